@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/config"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
+)
+
+// ignoreRules holds the ignore rules of the loaded config file.
+var ignoreRules ignore.Rules
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the " + config.FileName + " config file",
+	// Skip loading the config so validate can report all problems itself.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) { initLogger() },
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for unknown keys and invalid values",
+	Args:  cobra.NoArgs,
+	RunE:  runConfigValidate,
+	// Problems are listed above the error, usage would bury them.
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
+
+// readConfig loads the file given by --config, or the one found in the
+// terraform directory. It returns nil if there is no config file.
+func readConfig() (*config.Config, error) {
+	path := configPath
+	if path == "" {
+		found, err := config.Find(terraformDir)
+		if err != nil {
+			return nil, err
+		}
+		if found == "" {
+			return nil, nil
+		}
+		path = found
+	}
+	return config.Load(path)
+}
+
+// loadConfig applies the config file settings to the flags of cmd that were
+// not set on the command line and stores its ignore rules.
+func loadConfig(cmd *cobra.Command) error {
+	cfg, err := readConfig()
+	if err != nil || cfg == nil {
+		return err
+	}
+
+	for _, s := range cfg.Settings {
+		f := cmd.Flags().Lookup(s.Key)
+		if f == nil || !configurable(f) {
+			if lookupFlag(cmd.Root(), s.Key) == nil {
+				slog.Warn("unknown config key, ignoring", "file", cfg.Path, "line", s.Line, "key", s.Key)
+			}
+			continue
+		}
+		if f.Changed {
+			continue
+		}
+		if err := setFlag(f, s); err != nil {
+			return fmt.Errorf("%s:%d: %w", cfg.Path, s.Line, err)
+		}
+	}
+	ignoreRules = cfg.Ignore
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	cfg, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if cfg == nil {
+		return fmt.Errorf("no %s found in %s", config.FileName, terraformDir)
+	}
+
+	var problems []string
+	for _, s := range cfg.Settings {
+		f := lookupFlag(cmd.Root(), s.Key)
+		if f == nil {
+			problems = append(problems, fmt.Sprintf("%s:%d: unknown key %q", cfg.Path, s.Line, s.Key))
+			continue
+		}
+		if err := setFlag(f, s); err != nil {
+			problems = append(problems, fmt.Sprintf("%s:%d: %v", cfg.Path, s.Line, err))
+		}
+	}
+	for _, p := range problems {
+		if _, err := fmt.Fprintln(cmd.OutOrStdout(), p); err != nil {
+			return fmt.Errorf("printing problems: %w", err)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s has %d problem(s)", cfg.Path, len(problems))
+	}
+
+	slog.Info("config file is valid", "file", cfg.Path, "settings", len(cfg.Settings), "ignore_rules", len(cfg.Ignore))
+	return nil
+}
+
+// lookupFlag finds a configurable flag of root or any of its subcommands.
+func lookupFlag(root *cobra.Command, name string) *pflag.Flag {
+	var found *pflag.Flag
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		if found != nil {
+			return
+		}
+		for _, fs := range []*pflag.FlagSet{c.PersistentFlags(), c.LocalFlags()} {
+			if f := fs.Lookup(name); f != nil && configurable(f) {
+				found = f
+				return
+			}
+		}
+		for _, child := range c.Commands() {
+			walk(child)
+		}
+	}
+	walk(root)
+	return found
+}
+
+// configurable reports whether a flag may be set in the config file.
+func configurable(f *pflag.Flag) bool {
+	switch f.Name {
+	case "config", "help", "version":
+		return false
+	}
+	return true
+}
+
+func setFlag(f *pflag.Flag, s config.Setting) error {
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		if err := sv.Replace(s.Values); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", s.Key, err)
+		}
+		f.Changed = true
+		return nil
+	}
+	if s.List {
+		return fmt.Errorf("%s takes a single value, not a list", s.Key)
+	}
+	if err := f.Value.Set(s.Values[0]); err != nil {
+		return fmt.Errorf("invalid value %q for %s: %w", s.Values[0], s.Key, err)
+	}
+	f.Changed = true
+	return nil
+}
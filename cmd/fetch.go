@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
+)
+
+var (
+	snapshotOutput string
+	redactSnapshot bool
+)
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "Fetch GitLab resources into a snapshot file for offline scans",
+	Long: `Fetch all GitLab resources of the group and write them to a JSON snapshot.
+Run "scan --from-snapshot" on the snapshot to generate code, detect drift and
+print import commands without API access.`,
+	Args: cobra.NoArgs,
+	RunE: runFetch,
+}
+
+func init() {
+	rootCmd.AddCommand(fetchCmd)
+	addFetchFlags(fetchCmd.Flags())
+	fetchCmd.Flags().StringVarP(&snapshotOutput, "output", "o", "gitlab-snapshot.json", "Snapshot file to write (- for stdout)")
+	fetchCmd.Flags().BoolVar(&redactSnapshot, "redact", false, "Replace variable values, runner tokens and webhook header values, e.g. to attach the snapshot to a bug report")
+}
+
+// addFetchFlags adds the flags that control what is fetched from GitLab and
+// how, shared by scan and fetch.
+func addFetchFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVar(&skipResources, "skip", nil, "Resource types to skip (comma-separated). Use 'premium' to skip all Premium-tier resources")
+	fs.StringSliceVar(&includePaths, "include", nil, "Only scan groups and projects whose full path matches these globs (comma-separated)")
+	fs.StringSliceVar(&excludePaths, "exclude", nil, "Do not scan groups and projects whose full path matches these globs (comma-separated)")
+	fs.IntVar(&concurrency, "concurrency", gitlab.DefaultConcurrency, "Number of groups or projects fetched in parallel")
+	fs.IntVar(&maxRetries, "max-retries", gitlab.DefaultMaxRetries, "Retries for throttled (429), 5xx and failed API requests")
+	fs.Float64Var(&rateLimit, "rate-limit", 0, "Maximum API requests per second (0 for no limit)")
+}
+
+func runFetch(cmd *cobra.Command, args []string) error {
+	token, err := apiToken()
+	if err != nil {
+		return err
+	}
+	if err := requireGroup(); err != nil {
+		return err
+	}
+	skipSet := parseSkip()
+
+	client, err := newClient(token)
+	if err != nil {
+		return err
+	}
+	resources, err := fetchResources(cmd.Context(), client, skipSet)
+	if err != nil {
+		return err
+	}
+
+	snap := &gitlab.Snapshot{
+		CreatedAt: time.Now().UTC(),
+		GitLabURL: gitlabURL,
+		Group:     gitlabGroup,
+		Skipped:   slices.Sorted(maps.Keys(skipSet)),
+		Include:   includePaths,
+		Exclude:   excludePaths,
+		Resources: resources,
+	}
+	if redactSnapshot {
+		snap.Redact()
+	}
+
+	var buf bytes.Buffer
+	if err := gitlab.WriteSnapshot(&buf, snap); err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	if snapshotOutput == "-" {
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("writing snapshot: %w", err)
+		}
+		return nil
+	}
+	// Snapshots contain CI/CD variable values unless redacted.
+	if err := os.WriteFile(snapshotOutput, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	slog.Info("wrote snapshot", "file", snapshotOutput, "redacted", redactSnapshot)
+	return nil
+}
+
+// apiToken returns the token from --gitlab-token or GITLAB_TOKEN.
+func apiToken() (string, error) {
+	token := gitlabToken
+	if token == "" {
+		token = os.Getenv("GITLAB_TOKEN")
+	}
+	if token == "" {
+		return "", fmt.Errorf("GitLab token required: use --gitlab-token flag or set GITLAB_TOKEN environment variable")
+	}
+	return token, nil
+}
+
+func requireGroup() error {
+	if gitlabGroup == "" && gitlabURL == defaultGitLabURL {
+		return fmt.Errorf("--group is required when using gitlab.com, specify your top-level group")
+	}
+	return nil
+}
+
+// parseSkip resolves --skip and logs unknown and skipped resource types.
+func parseSkip() skip.Set {
+	skipSet, skipWarnings := skip.Parse(skipResources)
+	for _, w := range skipWarnings {
+		slog.Warn("unknown skip value, ignoring", "name", w)
+	}
+	if len(skipSet) > 0 {
+		slog.Info("skipping resource types", "skipped", slices.Sorted(maps.Keys(skipSet)))
+	}
+	return skipSet
+}
+
+// newClient creates a GitLab client configured by the fetch flags.
+func newClient(token string) (*gitlab.Client, error) {
+	client, err := gitlab.NewClient(token, gitlabURL, gitlabGroup, gitlab.RetryOptions{
+		MaxRetries:        maxRetries,
+		RequestsPerSecond: rateLimit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating client: %w", err)
+	}
+	client.SetPathFilter(includePaths, excludePaths)
+	client.SetConcurrency(concurrency)
+	return client, nil
+}
+
+// fetchResources fetches all resource types not in skipSet.
+func fetchResources(ctx context.Context, client *gitlab.Client, skipSet skip.Set) (*gitlab.Resources, error) {
+	slog.Debug("fetching resources from GitLab API")
+
+	resources, err := client.FetchAll(ctx, skipSet)
+	if err != nil {
+		return nil, fmt.Errorf("fetching resources: %w", err)
+	}
+
+	counts := fetchedCounts(resources)
+	slog.Info("fetched resources",
+		"groups", counts["groups"],
+		"projects", counts["projects"],
+		"group_members", counts["group_members"],
+		"project_share_groups", counts["project_share_groups"],
+	)
+	return resources, nil
+}
+
+// fetchedCounts summarizes resources for logs and reports.
+func fetchedCounts(resources *gitlab.Resources) map[string]int {
+	groupMemberCount := 0
+	for _, members := range resources.GroupMembers {
+		groupMemberCount += len(members)
+	}
+
+	projectShareGroupCount := 0
+	for _, p := range resources.Projects {
+		projectShareGroupCount += len(p.SharedWithGroups)
+	}
+
+	return map[string]int{
+		"groups":               len(resources.Groups),
+		"projects":             len(resources.Projects),
+		"group_members":        groupMemberCount,
+		"project_share_groups": projectShareGroupCount,
+	}
+}
+
+// loadSnapshot reads a snapshot written by fetch from path, or from stdin if
+// path is "-".
+func loadSnapshot(path string) (*gitlab.Snapshot, error) {
+	if path == "-" {
+		snap, err := gitlab.ReadSnapshot(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading snapshot from stdin: %w", err)
+		}
+		return snap, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening snapshot: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	snap, err := gitlab.ReadSnapshot(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot %s: %w", path, err)
+	}
+	return snap, nil
+}
+
+// applySnapshot takes the GitLab URL, group, skipped resource types and path
+// filters of the snapshot over into the scan settings, so resources the
+// snapshot lacks are not reported as deleted. It returns the resulting skip
+// set.
+func applySnapshot(snap *gitlab.Snapshot, skipSet skip.Set) (skip.Set, error) {
+	switch {
+	case snap.GitLabURL == "" || strings.TrimSuffix(gitlabURL, "/") == strings.TrimSuffix(snap.GitLabURL, "/"):
+	case gitlabURL == defaultGitLabURL:
+		gitlabURL = snap.GitLabURL
+	default:
+		return nil, fmt.Errorf("--gitlab-url %q does not match GitLab URL %q of the snapshot", gitlabURL, snap.GitLabURL)
+	}
+
+	if gitlabGroup == "" {
+		gitlabGroup = snap.Group
+	} else if gitlabGroup != snap.Group {
+		return nil, fmt.Errorf("--group %q does not match group %q of the snapshot", gitlabGroup, snap.Group)
+	}
+
+	if len(snap.Skipped) > 0 {
+		skipSet = maps.Clone(skipSet)
+		if skipSet == nil {
+			skipSet = make(skip.Set)
+		}
+		for _, name := range snap.Skipped {
+			skipSet[name] = true
+		}
+	}
+
+	// The snapshot only holds what matched its filters; --include can only
+	// narrow that down further.
+	if len(includePaths) == 0 {
+		includePaths = snap.Include
+	}
+	excludePaths = slices.Concat(snap.Exclude, excludePaths)
+
+	slog.Info("loaded snapshot", "created_at", snap.CreatedAt, "gitlab_url", snap.GitLabURL, "group", snap.Group, "skipped", snap.Skipped)
+	if snap.Redacted {
+		slog.Warn("snapshot is redacted, drift of variable values and webhook headers is not meaningful")
+	}
+	return skipSet, nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/config"
 )
 
 const defaultGitLabURL = "https://gitlab.com"
@@ -18,12 +19,18 @@ var (
 	gitlabGroup  string
 	verbose      bool
 	jsonOutput   bool
+	configPath   string
 )
 
 var rootCmd = &cobra.Command{
 	Use:     "terraform-gitlab-drift",
 	Short:   "Detect GitLab resources not managed by Terraform",
 	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		err := loadConfig(cmd)
+		initLogger()
+		return err
+	},
 }
 
 func Execute() {
@@ -34,14 +41,13 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initLogger)
-
 	rootCmd.PersistentFlags().StringVar(&terraformDir, "terraform-dir", ".", "Path to Terraform directory")
 	rootCmd.PersistentFlags().StringVar(&gitlabToken, "gitlab-token", "", "GitLab API token (or set GITLAB_TOKEN env var)")
 	rootCmd.PersistentFlags().StringVar(&gitlabURL, "gitlab-url", defaultGitLabURL, "GitLab instance URL")
 	rootCmd.PersistentFlags().StringVar(&gitlabGroup, "group", "", "GitLab top-level group to scan (required for gitlab.com)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose (debug) logging")
 	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "Output logs in JSON format (useful for CI)")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default: "+config.FileName+" in --terraform-dir, if present)")
 }
 
 func initLogger() {
@@ -5,26 +5,53 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
-	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/report"
 	"github.com/xMoelletschi/terraform-gitlab-drift/internal/terraform"
+	gl "gitlab.com/gitlab-org/api/client-go"
 )
 
 var (
-	createMR      bool
-	overwrite     bool
-	showDiff      bool
-	skipResources []string
-	targetRepo    string
-	mrDestPath    string
-	mrBranch      string
+	createMR       bool
+	overwrite      bool
+	showDiff       bool
+	skipResources  []string
+	targetRepo     string
+	mrDestPath     string
+	mrBranch       string
+	removedBlocks  bool
+	statePath      string
+	importBlocks   bool
+	reportFormats  []string
+	reportDir      string
+	includePaths   []string
+	excludePaths   []string
+	ignoreAttrs    []string
+	concurrency    int
+	maxRetries     int
+	rateLimit      float64
+	snapshotPath   string
+	mrTemplate     string
+	mrTitle        string
+	mrCommitMsg    string
+	mrTargetBranch string
+	mrLabels       []string
+	mrAssignees    []string
+	mrReviewers    []string
+	mrDraft        bool
+	mrSquash       bool
+	mrSplit        string
 )
 
 var scanCmd = &cobra.Command{
@@ -37,25 +64,51 @@ func init() {
 	rootCmd.AddCommand(scanCmd)
 	scanCmd.Flags().BoolVar(&createMR, "create-mr", false, "Create a merge request with generated Terraform code")
 	scanCmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite files in terraform directory (default: write to tmp/ subdirectory)")
-	scanCmd.Flags().BoolVar(&showDiff, "show-diff", true, "Show diff between generated and existing files")
-	scanCmd.Flags().StringSliceVar(&skipResources, "skip", nil, "Resource types to skip (comma-separated). Use 'premium' to skip all Premium-tier resources")
+	scanCmd.Flags().BoolVar(&showDiff, "show-diff", true, "Show drift between generated and existing files")
 	scanCmd.Flags().StringVar(&targetRepo, "target-repo", "", "GitLab project path or ID for the MR (default: detected from git remote in --terraform-dir)")
 	scanCmd.Flags().StringVar(&mrDestPath, "mr-dest-path", "", "Path within target repo where .tf files go (default: root)")
 	scanCmd.Flags().StringVar(&mrBranch, "mr-branch", "drift/backtrack", "Branch name for the drift MR")
+	scanCmd.Flags().StringVar(&mrTemplate, "mr-template", "", "Go text/template file the MR description is rendered from (default: built-in summary)")
+	scanCmd.Flags().StringVar(&mrTitle, "mr-title", gitlab.DefaultDriftMRTitle, "MR title, as Go text/template with the fields of the MR description")
+	scanCmd.Flags().StringVar(&mrCommitMsg, "mr-commit-message", gitlab.DefaultDriftMRTitle, "Commit message of the drift commit, as Go text/template like --mr-title")
+	scanCmd.Flags().StringVar(&mrTargetBranch, "mr-target-branch", "", "Branch the MR targets (default: the default branch of the target repo)")
+	scanCmd.Flags().StringSliceVar(&mrLabels, "mr-labels", nil, "Labels to add to the MR (comma-separated)")
+	scanCmd.Flags().StringSliceVar(&mrAssignees, "mr-assignees", nil, "Usernames to assign a new MR to (comma-separated)")
+	scanCmd.Flags().StringSliceVar(&mrReviewers, "mr-reviewers", nil, "Usernames to request a review of a new MR from (comma-separated)")
+	scanCmd.Flags().BoolVar(&mrDraft, "mr-draft", false, "Create the MR as draft")
+	scanCmd.Flags().BoolVar(&mrSquash, "mr-squash", false, "Squash the drift commits when the MR is merged")
+	scanCmd.Flags().StringVar(&mrSplit, "mr-split", "", "Open one MR per top-level subgroup (namespace) or per generated file (file) instead of a single MR")
+	scanCmd.Flags().StringVar(&statePath, "state", "", "Terraform state file (or `terraform show -json` output, - for stdin) used to decide what is managed")
+	scanCmd.Flags().BoolVar(&importBlocks, "import-blocks", false, "Write import {} blocks to imports.tf instead of printing terraform import commands")
+	scanCmd.Flags().StringSliceVar(&reportFormats, "report", nil, "Write drift reports in the given formats (comma-separated): json, codequality, sarif, junit")
+	scanCmd.Flags().StringVar(&reportDir, "report-dir", ".", "Directory reports are written to")
+	scanCmd.Flags().StringSliceVar(&ignoreAttrs, "ignore-attributes", nil, "Attributes to keep as declared and leave out of drift, as attribute or resource_type.attribute (comma-separated)")
+	scanCmd.Flags().BoolVar(&removedBlocks, "removed-blocks", false, "Write removed {} blocks for resources deleted in GitLab to removed.tf")
+	scanCmd.Flags().StringVar(&snapshotPath, "from-snapshot", "", "Scan a snapshot written by fetch (- for stdin) instead of the GitLab API")
+	addFetchFlags(scanCmd.Flags())
 }
 
 func runScan(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
-	token := gitlabToken
-	if token == "" {
-		token = os.Getenv("GITLAB_TOKEN")
-	}
-	if token == "" {
-		return fmt.Errorf("GitLab token required: use --gitlab-token flag or set GITLAB_TOKEN environment variable")
+	scannedAt := time.Now()
+	var token string
+	if snapshotPath != "" {
+		if createMR {
+			return fmt.Errorf("--create-mr needs API access and cannot be used with --from-snapshot")
+		}
+	} else {
+		var err error
+		token, err = apiToken()
+		if err != nil {
+			return err
+		}
+		if err := requireGroup(); err != nil {
+			return err
+		}
 	}
 
-	if gitlabGroup == "" && gitlabURL == defaultGitLabURL {
-		return fmt.Errorf("--group is required when using gitlab.com, specify your top-level group")
+	if mrSplit != "" && mrSplit != "namespace" && mrSplit != "file" {
+		return fmt.Errorf("unknown --mr-split %q, expected namespace or file", mrSplit)
 	}
 
 	if createMR && targetRepo == "" {
@@ -67,16 +120,25 @@ func runScan(cmd *cobra.Command, args []string) error {
 		slog.Info("detected target repo from git remote", "target_repo", targetRepo)
 	}
 
-	skipSet, skipWarnings := skip.Parse(skipResources)
-	for _, w := range skipWarnings {
-		slog.Warn("unknown skip value, ignoring", "name", w)
+	for _, format := range reportFormats {
+		if _, ok := report.Formats[format]; !ok {
+			return fmt.Errorf("unknown report format %q", format)
+		}
 	}
-	if len(skipSet) > 0 {
-		skipped := make([]string, 0, len(skipSet))
-		for k := range skipSet {
-			skipped = append(skipped, k)
+
+	skipSet := parseSkip()
+
+	var snap *gitlab.Snapshot
+	if snapshotPath != "" {
+		var err error
+		snap, err = loadSnapshot(snapshotPath)
+		if err != nil {
+			return err
+		}
+		skipSet, err = applySnapshot(snap, skipSet)
+		if err != nil {
+			return err
 		}
-		slog.Info("skipping resource types", "skipped", skipped)
 	}
 
 	slog.Info("scanning for unmanaged GitLab resources",
@@ -86,36 +148,34 @@ func runScan(cmd *cobra.Command, args []string) error {
 		"create_mr", createMR,
 	)
 
-	client, err := gitlab.NewClient(token, gitlabURL, gitlabGroup)
-	if err != nil {
-		return fmt.Errorf("creating client: %w", err)
-	}
-
-	slog.Debug("fetching resources from GitLab API")
-
-	// Fetch resources from GitLab API
-	resources, err := client.FetchAll(ctx, skipSet)
-	if err != nil {
-		return fmt.Errorf("fetching resources: %w", err)
+	filter := ignore.Filter{
+		Rules:   slices.Concat(ignoreRules, ignore.ParseAttributes(ignoreAttrs)),
+		Include: includePaths,
+		Exclude: excludePaths,
 	}
 
-	groupMemberCount := 0
-	for _, members := range resources.GroupMembers {
-		groupMemberCount += len(members)
+	if !filter.Empty() {
+		slog.Info("filtering scan", "include", includePaths, "exclude", excludePaths, "ignore_rules", len(filter.Rules))
 	}
 
-	projectShareGroupCount := 0
-	for _, p := range resources.Projects {
-		projectShareGroupCount += len(p.SharedWithGroups)
+	var client *gitlab.Client
+	var resources *gitlab.Resources
+	var err error
+	if snap != nil {
+		resources = snap.Resources
+		resources.SelectPaths(includePaths, excludePaths)
+	} else {
+		client, err = newClient(token)
+		if err != nil {
+			return err
+		}
+		// Fetch resources from GitLab API
+		resources, err = fetchResources(ctx, client, skipSet)
+		if err != nil {
+			return err
+		}
 	}
 
-	slog.Info("fetched resources",
-		"groups", len(resources.Groups),
-		"projects", len(resources.Projects),
-		"group_members", groupMemberCount,
-		"project_share_groups", projectShareGroupCount,
-	)
-
 	outputDir := filepath.Join(terraformDir, "tmp")
 	if err := os.RemoveAll(outputDir); err != nil {
 		return fmt.Errorf("cleaning tmp directory: %w", err)
@@ -128,87 +188,175 @@ func runScan(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("writing terraform files: %w", err)
 	}
 
+	// Resolve the GitLab paths resources belong to for the ignore rules
+	var resourcePaths map[string]string
+	if !filter.Empty() {
+		resourcePaths, err = terraform.ResourcePaths(resources, outputDir, terraformDir)
+		if err != nil {
+			return fmt.Errorf("resolving resource paths: %w", err)
+		}
+	}
+
 	slog.Info("wrote terraform files", "dir", outputDir)
 
-	// Compare generated .tf files with existing ones
-	driftFound := false
-	files, err := filepath.Glob(filepath.Join(outputDir, "*.tf"))
+	// Recognize existing resources declared under a different name or file
+	matches, err := terraform.MatchExistingResources(resources, terraformDir, outputDir)
 	if err != nil {
-		return fmt.Errorf("listing generated files: %w", err)
+		return fmt.Errorf("matching existing resources: %w", err)
+	}
+	if len(matches) > 0 {
+		slog.Info("matched existing resources by identity", "count", len(matches))
+	}
+	if err := terraform.RenameMatchedResources(outputDir, matches); err != nil {
+		return fmt.Errorf("renaming matched resources: %w", err)
 	}
 
-	for _, genFile := range files {
-		base := filepath.Base(genFile)
-		existingFile := filepath.Join(terraformDir, base)
+	// Keep ignored attributes and namespaces outside --include and --exclude
+	// as they are declared
+	if err := terraform.KeepIgnored(outputDir, terraformDir, resources, filter, resourcePaths); err != nil {
+		return fmt.Errorf("keeping ignored declarations: %w", err)
+	}
 
-		if _, err := os.Stat(existingFile); os.IsNotExist(err) {
-			slog.Warn("new unmanaged resource detected", "file", base)
-			if showDiff {
-				diffCmd := exec.Command("diff", "-u", "--color=auto", "/dev/null", genFile)
-				diffCmd.Stdout = os.Stdout
-				diffCmd.Stderr = os.Stderr
-				_ = diffCmd.Run()
+	// Detect resources that are declared in terraform but gone from GitLab
+	orphans, err := terraform.FindOrphans(resources, terraformDir, outputDir, matches, skipSet)
+	if err != nil {
+		return fmt.Errorf("detecting orphaned resources: %w", err)
+	}
+	orphans = terraform.IgnoreOrphans(orphans, filter, resourcePaths)
+	if removedBlocks && len(orphans) > 0 {
+		var buf bytes.Buffer
+		if err := terraform.WriteRemovedBlocks(orphans, &buf); err != nil {
+			return fmt.Errorf("generating removed blocks: %w", err)
+		}
+		if buf.Len() > 0 {
+			if err := os.WriteFile(filepath.Join(outputDir, "removed.tf"), buf.Bytes(), 0644); err != nil {
+				return fmt.Errorf("writing removed.tf: %w", err)
 			}
-			driftFound = true
-			continue
 		}
+	}
 
+	// Compare generated .tf files with existing ones
+	driftFound := false
+	changes, err := terraform.CompareDirs(terraformDir, outputDir, matches)
+	if err != nil {
+		return fmt.Errorf("comparing terraform files: %w", err)
+	}
+	changes = terraform.IgnoreChanges(changes, filter, resourcePaths)
+	if len(changes) > 0 {
+		driftFound = true
+		slog.Warn("drift between existing and generated files", "changes", len(changes))
 		if showDiff {
-			diffCmd := exec.Command("diff", "-u", "--color=auto", existingFile, genFile)
-			diffCmd.Stdout = os.Stdout
-			diffCmd.Stderr = os.Stderr
-			if err := diffCmd.Run(); err != nil {
-				if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-					driftFound = true
-					continue
-				}
-				return fmt.Errorf("diff command failed for %s: %w", base, err)
+			if err := terraform.PrintDrift(os.Stdout, changes); err != nil {
+				return fmt.Errorf("printing drift: %w", err)
 			}
-		} else {
-			genData, err := os.ReadFile(genFile)
-			if err != nil {
-				return fmt.Errorf("reading generated file %s: %w", base, err)
+		}
+	}
+
+	if len(orphans) > 0 {
+		driftFound = true
+		slog.Warn("resources deleted in GitLab but still declared in terraform", "count", len(orphans))
+		if _, err := fmt.Fprintln(os.Stdout, "\nResources deleted in GitLab but still declared in Terraform:"); err != nil {
+			return fmt.Errorf("printing orphaned resources: %w", err)
+		}
+		if err := terraform.PrintOrphans(os.Stdout, orphans); err != nil {
+			return fmt.Errorf("printing orphaned resources: %w", err)
+		}
+	}
+
+	// Generate import commands for new resources
+	allCmds := terraform.GenerateImportCommands(resources, map[string]bool{}, gitlabGroup, skipSet)
+	allCmds = terraform.IgnoreImportCommands(allCmds, filter, resourcePaths)
+	var importCmds []terraform.ImportCommand
+	if statePath != "" {
+		// State is the source of truth: everything not recorded there needs
+		// an import, even if it is already declared in code.
+		state, err := loadState(statePath)
+		if err != nil {
+			return err
+		}
+		slog.Info("loaded terraform state", "path", statePath, "gitlab_resources", state.Len())
+		importCmds = state.FilterImportCommands(allCmds, resources)
+		importCmds = terraform.RenameImportCommands(importCmds, matches)
+	} else {
+		identities, err := terraform.ParseExistingResources(terraformDir, resources)
+		if err != nil {
+			return fmt.Errorf("parsing existing terraform files: %w", err)
+		}
+		existingResources := make(map[string]bool, len(identities)+len(matches))
+		for addr := range identities {
+			existingResources[addr] = true
+		}
+		for generated := range matches {
+			existingResources[generated] = true
+		}
+		importCmds = terraform.GenerateImportCommands(resources, existingResources, gitlabGroup, skipSet)
+		importCmds = terraform.SkipDeclaredMembers(importCmds, identities, resources)
+		importCmds = terraform.IgnoreImportCommands(importCmds, filter, resourcePaths)
+	}
+	if len(importCmds) > 0 {
+		driftFound = true
+		if importBlocks {
+			// Written to the output dir so it is part of the MR and --overwrite.
+			var buf bytes.Buffer
+			if err := terraform.WriteImportBlocks(&buf, importCmds); err != nil {
+				return fmt.Errorf("generating import blocks: %w", err)
 			}
-			existingData, err := os.ReadFile(existingFile)
-			if err != nil {
-				return fmt.Errorf("reading existing file %s: %w", base, err)
+			importsFile := filepath.Join(outputDir, "imports.tf")
+			if err := os.WriteFile(importsFile, buf.Bytes(), 0644); err != nil {
+				return fmt.Errorf("writing imports.tf: %w", err)
 			}
-			if !bytes.Equal(genData, existingData) {
-				driftFound = true
+			slog.Info("wrote import blocks for new resources", "file", importsFile, "count", len(importCmds))
+		} else {
+			if _, err := fmt.Fprintln(os.Stdout, "\nImport commands for new resources:"); err != nil {
+				return fmt.Errorf("printing import commands: %w", err)
+			}
+			if err := terraform.PrintImportCommands(os.Stdout, importCmds); err != nil {
+				return fmt.Errorf("printing import commands: %w", err)
 			}
 		}
 	}
 
-	// Generate import commands for new resources
-	existingResources, err := terraform.ParseExistingResources(terraformDir)
+	// Delete imports.tf and removed.tf of earlier scans once there is
+	// nothing left to import or remove
+	leftover, err := leftoverBlockFiles(outputDir)
 	if err != nil {
-		return fmt.Errorf("parsing existing terraform files: %w", err)
+		return err
 	}
-	importCmds := terraform.GenerateImportCommands(resources, existingResources, gitlabGroup, skipSet)
-	if len(importCmds) > 0 {
+	if len(leftover) > 0 {
 		driftFound = true
-		if _, err := fmt.Fprintln(os.Stdout, "\nImport commands for new resources:"); err != nil {
-			return fmt.Errorf("printing import commands: %w", err)
-		}
-		if err := terraform.PrintImportCommands(os.Stdout, importCmds); err != nil {
-			return fmt.Errorf("printing import commands: %w", err)
-		}
+		slog.Warn("block files no longer generated", "files", leftover)
 	}
 
-	// Create or update a merge request if drift was found
+	var r *report.Report
+	if createMR || len(reportFormats) > 0 {
+		locations, err := terraform.ParseResourceLocations(outputDir)
+		if err != nil {
+			return fmt.Errorf("parsing generated files: %w", err)
+		}
+		existing, err := terraform.ParseBlockLocations(terraformDir)
+		if err != nil {
+			return fmt.Errorf("parsing existing files: %w", err)
+		}
+		r = report.Build(report.Input{
+			Group:        gitlabGroup,
+			TerraformDir: repoTerraformDir(),
+			DriftFound:   driftFound,
+			Fetched:      fetchedCounts(resources),
+			All:          allCmds,
+			Unmanaged:    importCmds,
+			Matches:      matches,
+			Locations:    locations,
+			Existing:     existing,
+			Changes:      changes,
+			Orphans:      orphans,
+		})
+	}
+
+	// Create or update merge requests for the drift and close those of
+	// drift that is gone
 	if createMR {
-		if !driftFound {
-			slog.Info("no drift detected, skipping MR creation")
-		} else {
-			result, err := createDriftMR(ctx, client, targetRepo, outputDir, mrDestPath)
-			if err != nil {
-				return fmt.Errorf("creating drift MR: %w", err)
-			}
-			if result.Created {
-				slog.Info("created merge request", "url", result.WebURL)
-			} else {
-				slog.Info("updated existing merge request", "url", result.WebURL)
-			}
+		if err := syncDriftMRs(ctx, client, targetRepo, outputDir, resources, filter, r, scannedAt); err != nil {
+			return err
 		}
 	}
 
@@ -228,9 +376,20 @@ func runScan(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf("writing file %s: %w", dst, err)
 			}
 		}
+		for _, name := range leftover {
+			if err := os.Remove(filepath.Join(terraformDir, name)); err != nil {
+				return fmt.Errorf("deleting %s: %w", name, err)
+			}
+		}
 		slog.Info("overwrote terraform files", "dir", terraformDir)
 	}
 
+	if len(reportFormats) > 0 {
+		if err := writeReports(r); err != nil {
+			return err
+		}
+	}
+
 	if driftFound {
 		slog.Warn("drift detected")
 		os.Exit(1)
@@ -241,43 +400,406 @@ func runScan(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func createDriftMR(ctx context.Context, client *gitlab.Client, project, outputDir, destPath string) (*gitlab.DriftMRResult, error) {
-	defaultBranch, err := client.GetDefaultBranch(ctx, project)
+// writeReports writes the report in every requested format to reportDir.
+func writeReports(r *report.Report) error {
+	if err := os.MkdirAll(reportDir, 0755); err != nil {
+		return fmt.Errorf("creating report directory: %w", err)
+	}
+	for _, format := range reportFormats {
+		path := filepath.Join(reportDir, report.Formats[format])
+		var buf bytes.Buffer
+		if err := report.Write(&buf, format, r); err != nil {
+			return fmt.Errorf("generating %s report: %w", format, err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("writing %s report: %w", format, err)
+		}
+		slog.Info("wrote drift report", "format", format, "file", path)
+	}
+	return nil
+}
+
+// repoTerraformDir returns where the terraform files are within their
+// repository, so report paths resolve in merge requests and pipelines: the
+// --mr-dest-path for merge requests, else the path of --terraform-dir below
+// the root of its git checkout.
+func repoTerraformDir() string {
+	if createMR {
+		return mrDestPath
+	}
+	out, err := exec.Command("git", "-C", terraformDir, "rev-parse", "--show-prefix").Output()
 	if err != nil {
-		return nil, err
+		slog.Debug("terraform directory is not in a git checkout", "error", err)
+		return terraformDir
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// loadState reads terraform state from path, or from stdin if path is "-".
+func loadState(path string) (*terraform.State, error) {
+	if path == "-" {
+		state, err := terraform.LoadState(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading state from stdin: %w", err)
+		}
+		return state, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening state file: %w", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	state, err := terraform.LoadState(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading state file %s: %w", path, err)
 	}
+	return state, nil
+}
 
-	existingMR, err := client.FindExistingDriftMR(ctx, project)
+// driftMROptions renders the title, description and commit message of the
+// drift MR from d.
+func driftMROptions(d *report.MRDescription) (gitlab.DriftMROptions, string, error) {
+	var tmpl string
+	if mrTemplate != "" {
+		data, err := os.ReadFile(mrTemplate)
+		if err != nil {
+			return gitlab.DriftMROptions{}, "", fmt.Errorf("reading MR template: %w", err)
+		}
+		tmpl = string(data)
+	}
+	var description bytes.Buffer
+	if err := report.WriteMRDescription(&description, tmpl, d); err != nil {
+		return gitlab.DriftMROptions{}, "", err
+	}
+
+	titleTmpl := mrTitle
+	if d.Scope != "" && titleTmpl == gitlab.DefaultDriftMRTitle {
+		titleTmpl += " ({{.Scope}})"
+	}
+	title, err := d.Render(titleTmpl)
+	if err != nil {
+		return gitlab.DriftMROptions{}, "", fmt.Errorf("--mr-title: %w", err)
+	}
+	message, err := d.Render(mrCommitMsg)
+	if err != nil {
+		return gitlab.DriftMROptions{}, "", fmt.Errorf("--mr-commit-message: %w", err)
+	}
+
+	return gitlab.DriftMROptions{
+		Title:       title,
+		Description: description.String(),
+		Labels:      mrLabels,
+		Draft:       mrDraft,
+		Squash:      mrSquash,
+	}, message, nil
+}
+
+// driftUnit is a set of generated files that goes into its own drift MR.
+type driftUnit struct {
+	// scope is the namespace or file the MR is limited to, empty for the
+	// single MR without --mr-split.
+	scope  string
+	branch string
+	files  []string
+	// stale are the files of removed or transferred namespaces the MR
+	// deletes or moves.
+	stale []terraform.StaleFile
+	// rest marks the unit that also takes the drift outside of the
+	// generated files of all other units, e.g. in hand-written files.
+	rest bool
+}
+
+// holds tells whether the file with the given base name is generated,
+// deleted or moved by the unit.
+func (u driftUnit) holds(file string) bool {
+	return slices.ContainsFunc(u.files, func(f string) bool { return filepath.Base(f) == file }) ||
+		slices.ContainsFunc(u.stale, func(s terraform.StaleFile) bool { return s.Name == file })
+}
+
+// owns tells whether drift in the file with the given base name belongs to
+// the unit.
+func (u driftUnit) owns(file string, units []driftUnit) bool {
+	if u.holds(file) {
+		return true
+	}
+	if !u.rest {
+		return false
+	}
+	for _, other := range units {
+		if other.branch != u.branch && other.holds(file) {
+			return false
+		}
+	}
+	return true
+}
+
+// driftFiles returns the file changes of the unit's drift commit.
+func (u driftUnit) driftFiles() gitlab.DriftFiles {
+	files := gitlab.DriftFiles{Generated: u.files, Moves: make(map[string]string)}
+	for _, s := range u.stale {
+		if s.MovedTo != "" {
+			files.Moves[s.MovedTo] = s.Name
+		} else {
+			files.Delete = append(files.Delete, s.Name)
+		}
+	}
+	return files
+}
+
+// driftUnits splits the generated files in outputDir into drift MRs as set by
+// --mr-split. Branches are --mr-branch with the subgroup or file appended.
+func driftUnits(outputDir string, resources *gitlab.Resources, filter ignore.Filter) ([]driftUnit, error) {
+	files, err := filepath.Glob(filepath.Join(outputDir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("listing generated files: %w", err)
+	}
+
+	var units []driftUnit
+	switch mrSplit {
+	case "file":
+		for _, f := range files {
+			name := filepath.Base(f)
+			units = append(units, driftUnit{
+				scope:  name,
+				branch: mrBranch + "-" + strings.TrimSuffix(name, ".tf"),
+				files:  []string{f},
+			})
+		}
+
+	case "namespace":
+		// Files of a subgroup and its descendants go into the MR of the
+		// top-level subgroup. The main group and the files holding
+		// resources of all namespaces, e.g. group_membership.tf, share
+		// the MR on --mr-branch.
+		namespaces := terraform.NamespaceFiles(resources, gitlabGroup)
+		shared := driftUnit{scope: gitlabGroup, branch: mrBranch, rest: true}
+		subgroups := make(map[string]*driftUnit)
+		for _, f := range files {
+			ns, ok := namespaces[filepath.Base(f)]
+			rel, sub := strings.CutPrefix(ns, gitlabGroup+"/")
+			if !ok || ns == gitlabGroup || (!sub && gitlabGroup != "") {
+				shared.files = append(shared.files, f)
+				continue
+			}
+			top, _, _ := strings.Cut(rel, "/")
+			u, ok := subgroups[top]
+			if !ok {
+				scope := top
+				if gitlabGroup != "" {
+					scope = gitlabGroup + "/" + top
+				}
+				u = &driftUnit{scope: scope, branch: mrBranch + "-" + top}
+				subgroups[top] = u
+			}
+			u.files = append(u.files, f)
+		}
+		units = append(units, shared)
+		for _, top := range slices.Sorted(maps.Keys(subgroups)) {
+			units = append(units, *subgroups[top])
+		}
+
+	default:
+		units = append(units, driftUnit{branch: mrBranch, files: files, rest: true})
+	}
+
+	// Moved files go with the file that replaces them, deleted ones into
+	// the shared MR, or an MR of their own with --mr-split file.
+	stale, err := terraform.FindStaleFiles(resources, terraformDir, outputDir, filter)
+	if err != nil {
+		return nil, fmt.Errorf("finding files of removed namespaces: %w", err)
+	}
+	leftover, err := leftoverBlockFiles(outputDir)
 	if err != nil {
 		return nil, err
 	}
+	for _, name := range leftover {
+		stale = append(stale, terraform.StaleFile{Name: name})
+	}
+	for _, sf := range stale {
+		i := slices.IndexFunc(units, func(u driftUnit) bool { return sf.MovedTo != "" && u.holds(sf.MovedTo) })
+		if i < 0 {
+			i = slices.IndexFunc(units, func(u driftUnit) bool { return u.rest })
+		}
+		if i < 0 {
+			units = append(units, driftUnit{
+				scope:  sf.Name,
+				branch: mrBranch + "-" + strings.TrimSuffix(sf.Name, ".tf"),
+			})
+			i = len(units) - 1
+		}
+		units[i].stale = append(units[i].stale, sf)
+		slog.Info("generated file no longer matches a namespace", "file", sf.Name, "moved_to", sf.MovedTo, "branch", units[i].branch)
+	}
+	return units, nil
+}
 
-	branchName := mrBranch
-	if existingMR != nil {
-		branchName = existingMR.SourceBranch
+// leftoverBlockFiles returns imports.tf and removed.tf if --import-blocks
+// and --removed-blocks write them, they exist in --terraform-dir, and this
+// scan did not generate them because there is nothing to import or remove.
+func leftoverBlockFiles(outputDir string) ([]string, error) {
+	var leftover []string
+	for _, f := range []struct {
+		name    string
+		written bool
+	}{{"imports.tf", importBlocks}, {"removed.tf", removedBlocks}} {
+		if !f.written {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(outputDir, f.name)); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("checking generated %s: %w", f.name, err)
+		}
+		if _, err := os.Stat(filepath.Join(terraformDir, f.name)); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("checking existing %s: %w", f.name, err)
+		}
+		leftover = append(leftover, f.name)
+	}
+	return leftover, nil
+}
+
+// syncDriftMRs creates or updates the drift MRs of all units with drift and
+// closes the open drift MRs whose drift is gone.
+func syncDriftMRs(ctx context.Context, client *gitlab.Client, project, outputDir string, resources *gitlab.Resources, filter ignore.Filter, r *report.Report, scannedAt time.Time) error {
+	existing, err := client.FindDriftMRs(ctx, project, mrBranch)
+	if err != nil {
+		return fmt.Errorf("finding drift MRs: %w", err)
+	}
+
+	var updated map[string]bool
+	if r.DriftFound {
+		updated, err = createDriftMRs(ctx, client, project, outputDir, resources, filter, r, existing, scannedAt)
+		if err != nil {
+			return err
+		}
+	} else {
+		slog.Info("no drift detected, skipping MR creation")
 	}
 
-	if err := client.EnsureBranch(ctx, project, branchName, defaultBranch); err != nil {
+	comment := fmt.Sprintf("No drift left as of %s, closing. A new merge request is opened when drift is found again.",
+		scannedAt.Format("2006-01-02 15:04 MST"))
+	for _, branch := range slices.Sorted(maps.Keys(existing)) {
+		// Other branches sharing the prefix, e.g. of another --mr-branch,
+		// are not ours to close.
+		if updated[branch] || (branch != mrBranch && !strings.HasPrefix(branch, mrBranch+"-")) {
+			continue
+		}
+		mr := existing[branch]
+		if err := client.CloseDriftMR(ctx, project, mr.IID, comment); err != nil {
+			return fmt.Errorf("closing drift MR: %w", err)
+		}
+		slog.Info("closed merge request without drift", "url", mr.WebURL, "branch", branch)
+	}
+	return nil
+}
+
+// createDriftMRs creates or updates the drift MRs of all units with drift and
+// returns their branches.
+func createDriftMRs(ctx context.Context, client *gitlab.Client, project, outputDir string, resources *gitlab.Resources, filter ignore.Filter, r *report.Report, existing map[string]*gl.BasicMergeRequest, scannedAt time.Time) (map[string]bool, error) {
+	targetBranch := mrTargetBranch
+	if targetBranch == "" {
+		var err error
+		targetBranch, err = client.GetDefaultBranch(ctx, project)
+		if err != nil {
+			return nil, fmt.Errorf("creating drift MR: %w", err)
+		}
+	}
+
+	units, err := driftUnits(outputDir, resources, filter)
+	if err != nil {
 		return nil, err
 	}
 
-	committed, err := client.CommitDriftFiles(ctx, project, branchName, outputDir, destPath)
+	all, err := report.NewMRDescription(r, scannedAt, importBlocks)
+	if err != nil {
+		return nil, fmt.Errorf("summarizing drift: %w", err)
+	}
+
+	updated := make(map[string]bool)
+	for _, u := range units {
+		d := all
+		if mrSplit != "" {
+			ur := r.Select(func(file string) bool { return u.owns(file, units) })
+			if d, err = report.NewMRDescription(ur, scannedAt, importBlocks); err != nil {
+				return nil, fmt.Errorf("summarizing drift: %w", err)
+			}
+			d.Scope = u.scope
+
+			// imports.tf and removed.tf hold the blocks of all namespaces,
+			// so their MR lists all of them and the others none.
+			if importBlocks {
+				d.Imports, d.ImportsOmitted = "", 0
+				if u.holds("imports.tf") {
+					d.Imports, d.ImportsOmitted = all.Imports, all.ImportsOmitted
+				}
+			}
+			if removedBlocks && u.holds("removed.tf") {
+				d.Orphans = all.Orphans
+			}
+			if !ur.DriftFound && d.Imports == "" && len(d.Orphans) == 0 && len(u.stale) == 0 {
+				slog.Debug("no drift, skipping MR", "scope", u.scope)
+				continue
+			}
+		}
+
+		result, err := createDriftMR(ctx, client, project, targetBranch, u, existing[u.branch], d)
+		if err != nil {
+			return nil, fmt.Errorf("creating drift MR for %s: %w", u.branch, err)
+		}
+		updated[u.branch] = true
+		if result.Created {
+			slog.Info("created merge request", "url", result.WebURL, "branch", u.branch)
+		} else {
+			slog.Info("updated existing merge request", "url", result.WebURL, "branch", u.branch)
+		}
+	}
+	return updated, nil
+}
+
+func createDriftMR(ctx context.Context, client *gitlab.Client, project, targetBranch string, u driftUnit, existingMR *gl.BasicMergeRequest, d *report.MRDescription) (*gitlab.DriftMRResult, error) {
+	opts, message, err := driftMROptions(d)
 	if err != nil {
 		return nil, err
 	}
 
+	// The branch is rebuilt on the latest target branch on every run, so
+	// the MR diff always shows the current drift.
+	committed, err := client.CommitDriftFiles(ctx, project, u.branch, targetBranch, u.driftFiles(), mrDestPath, message)
+	if err != nil {
+		return nil, err
+	}
 	if !committed {
-		slog.Info("no file changes to commit, all files are identical")
+		slog.Info("no file changes to commit, all files match the target branch", "branch", u.branch)
+		if err := client.ResetBranch(ctx, project, u.branch, targetBranch); err != nil {
+			return nil, err
+		}
 	}
 
 	if existingMR != nil {
+		// Keep the draft state, someone may have marked the MR as ready.
+		opts.Draft = existingMR.Draft
+		if _, err := client.UpdateDriftMR(ctx, project, existingMR.IID, opts); err != nil {
+			return nil, err
+		}
 		return &gitlab.DriftMRResult{
 			WebURL:  existingMR.WebURL,
 			Created: false,
 		}, nil
 	}
 
-	mr, err := client.CreateDriftMR(ctx, project, branchName, defaultBranch)
+	// Assignees and reviewers are only set on new MRs.
+	if opts.AssigneeIDs, err = client.UserIDs(ctx, mrAssignees); err != nil {
+		return nil, fmt.Errorf("resolving --mr-assignees: %w", err)
+	}
+	if opts.ReviewerIDs, err = client.UserIDs(ctx, mrReviewers); err != nil {
+		return nil, fmt.Errorf("resolving --mr-reviewers: %w", err)
+	}
+
+	mr, err := client.CreateDriftMR(ctx, project, u.branch, targetBranch, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/snapshot"
+)
+
+var snapshotDiffFormat string
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Work with snapshots written by fetch",
+}
+
+var snapshotDiffCmd = &cobra.Command{
+	Use:   "diff OLD NEW",
+	Short: "Report which GitLab objects were added, removed or changed between two snapshots",
+	Long: `Compare two snapshots written by fetch and list the groups, projects, members,
+shares, labels, hooks and pipeline schedules that were added, removed or
+changed in between, independent of any Terraform configuration.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runSnapshotDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotDiffCmd)
+	snapshotDiffCmd.Flags().StringVar(&snapshotDiffFormat, "format", "text", "Output format: text or json")
+}
+
+func runSnapshotDiff(cmd *cobra.Command, args []string) error {
+	if snapshotDiffFormat != "text" && snapshotDiffFormat != "json" {
+		return fmt.Errorf("unknown format %q, expected text or json", snapshotDiffFormat)
+	}
+
+	before, err := loadSnapshot(args[0])
+	if err != nil {
+		return err
+	}
+	after, err := loadSnapshot(args[1])
+	if err != nil {
+		return err
+	}
+
+	if before.Group != after.Group {
+		return fmt.Errorf("snapshots are of different groups: %q and %q", before.Group, after.Group)
+	}
+	if before.CreatedAt.After(after.CreatedAt) {
+		slog.Warn("first snapshot is newer than the second, changes are reversed", "before", before.CreatedAt, "after", after.CreatedAt)
+	}
+	if !slices.Equal(before.Include, after.Include) || !slices.Equal(before.Exclude, after.Exclude) {
+		slog.Warn("snapshots were fetched with different --include or --exclude, filtered namespaces show up as added or removed")
+	}
+
+	// Types missing from either snapshot would show up as added or removed.
+	skipSet := make(skip.Set)
+	for _, name := range slices.Concat(before.Skipped, after.Skipped) {
+		skipSet[name] = true
+	}
+
+	changes, err := snapshot.Diff(before.Resources, after.Resources, skipSet)
+	if err != nil {
+		return err
+	}
+	slog.Info("compared snapshots", "before", before.CreatedAt, "after", after.CreatedAt, "changes", len(changes))
+
+	if snapshotDiffFormat == "json" {
+		err = snapshot.WriteJSON(cmd.OutOrStdout(), changes)
+	} else {
+		err = snapshot.Print(cmd.OutOrStdout(), changes)
+	}
+	if err != nil {
+		return fmt.Errorf("printing changes: %w", err)
+	}
+	return nil
+}
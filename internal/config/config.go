@@ -0,0 +1,168 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
+)
+
+// FileName is the name of the config file looked up in the terraform
+// directory.
+const FileName = ".terraform-gitlab-drift.yaml"
+
+// ignoreKey holds the ignore rules; every other top-level key sets the
+// command-line flag of the same name.
+const ignoreKey = "ignore"
+
+// Config is a parsed config file.
+type Config struct {
+	Path     string
+	Settings []Setting
+	Ignore   ignore.Rules
+}
+
+// Setting is the value of a top-level key, given as a scalar or a list of
+// scalars.
+type Setting struct {
+	Key    string
+	Values []string
+	List   bool
+	Line   int
+}
+
+// Find returns the path of the config file in dir, or "" if there is none.
+func Find(dir string) (string, error) {
+	path := filepath.Join(dir, FileName)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("checking config file: %w", err)
+	}
+	return path, nil
+}
+
+// Load reads and parses the config file at path. Environment variables
+// referenced as ${NAME} or ${NAME:-default} are substituted in all values.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	cfg.Path = path
+	return cfg, nil
+}
+
+// Parse parses config file contents.
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return cfg, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return cfg, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected a mapping of settings", root.Line)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if seen[key.Value] {
+			return nil, fmt.Errorf("line %d: duplicate key %q", key.Line, key.Value)
+		}
+		seen[key.Value] = true
+
+		if key.Value == ignoreKey {
+			rules, err := parseIgnoreRules(value)
+			if err != nil {
+				return nil, err
+			}
+			cfg.Ignore = rules
+			continue
+		}
+
+		setting := Setting{Key: key.Value, Line: key.Line}
+		switch value.Kind {
+		case yaml.ScalarNode:
+			setting.Values = []string{expandEnv(value.Value)}
+		case yaml.SequenceNode:
+			setting.List = true
+			setting.Values = []string{}
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode {
+					return nil, fmt.Errorf("line %d: %s: expected a list of values", item.Line, key.Value)
+				}
+				setting.Values = append(setting.Values, expandEnv(item.Value))
+			}
+		default:
+			return nil, fmt.Errorf("line %d: %s: expected a value or a list of values", value.Line, key.Value)
+		}
+		cfg.Settings = append(cfg.Settings, setting)
+	}
+	return cfg, nil
+}
+
+func parseIgnoreRules(node *yaml.Node) (ignore.Rules, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: %s: expected a list of rules", node.Line, ignoreKey)
+	}
+
+	var rules ignore.Rules
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: %s: expected a rule with path, type or attributes", item.Line, ignoreKey)
+		}
+		for i := 0; i < len(item.Content); i += 2 {
+			switch k := item.Content[i]; k.Value {
+			case "path", "type", "attributes":
+			default:
+				return nil, fmt.Errorf("line %d: %s: unknown key %q", k.Line, ignoreKey, k.Value)
+			}
+		}
+
+		var rule ignore.Rule
+		if err := item.Decode(&rule); err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", item.Line, ignoreKey, err)
+		}
+		rule.Path = expandEnv(rule.Path)
+		rule.Type = expandEnv(rule.Type)
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", item.Line, ignoreKey, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// expandEnv substitutes ${NAME} and ${NAME:-default} references. Unset
+// variables without default expand to the empty string.
+func expandEnv(s string) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envRef.FindStringSubmatch(ref)
+		if v, ok := os.LookupEnv(m[1]); ok && v != "" {
+			return v
+		}
+		return m[2]
+	})
+}
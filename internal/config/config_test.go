@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Setenv("DRIFT_GROUP", "my-group")
+	t.Setenv("DRIFT_EMPTY", "")
+
+	cfg, err := Parse([]byte(`
+group: ${DRIFT_GROUP}
+gitlab-url: ${DRIFT_URL:-https://gitlab.example.com}
+mr-branch: drift/${DRIFT_EMPTY:-nightly}
+create-mr: true
+skip: [premium, labels]
+report:
+  - json
+ignore:
+  - path: ${DRIFT_GROUP}/sandbox
+  - type: gitlab_project
+    attributes: [description]
+`))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	want := []Setting{
+		{Key: "group", Values: []string{"my-group"}, Line: 2},
+		{Key: "gitlab-url", Values: []string{"https://gitlab.example.com"}, Line: 3},
+		{Key: "mr-branch", Values: []string{"drift/nightly"}, Line: 4},
+		{Key: "create-mr", Values: []string{"true"}, Line: 5},
+		{Key: "skip", Values: []string{"premium", "labels"}, List: true, Line: 6},
+		{Key: "report", Values: []string{"json"}, List: true, Line: 7},
+	}
+	if len(cfg.Settings) != len(want) {
+		t.Fatalf("got %d settings, want %d: %+v", len(cfg.Settings), len(want), cfg.Settings)
+	}
+	for i, w := range want {
+		got := cfg.Settings[i]
+		if got.Key != w.Key || got.List != w.List || got.Line != w.Line || strings.Join(got.Values, ",") != strings.Join(w.Values, ",") {
+			t.Errorf("setting %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	if len(cfg.Ignore) != 2 {
+		t.Fatalf("got %d ignore rules, want 2", len(cfg.Ignore))
+	}
+	if cfg.Ignore[0].Path != "my-group/sandbox" {
+		t.Errorf("rule 0 path = %q, want my-group/sandbox", cfg.Ignore[0].Path)
+	}
+	if cfg.Ignore[1].Type != "gitlab_project" || len(cfg.Ignore[1].Attributes) != 1 {
+		t.Errorf("rule 1 = %+v, want gitlab_project description rule", cfg.Ignore[1])
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	cfg, err := Parse([]byte("# nothing yet\n"))
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if len(cfg.Settings) != 0 || len(cfg.Ignore) != 0 {
+		t.Errorf("expected empty config, got %+v", cfg)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"not a mapping":     "- group\n",
+		"duplicate key":     "group: a\ngroup: b\n",
+		"nested value":      "group:\n  name: a\n",
+		"nested list":       "skip:\n  - [a]\n",
+		"ignore not a list": "ignore: sandbox\n",
+		"unknown rule key":  "ignore:\n  - paths: sandbox\n",
+		"bad rule pattern":  "ignore:\n  - path: \"my-group/[\"\n",
+	}
+	for name, src := range tests {
+		if _, err := Parse([]byte(src)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFindAndLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := Find(dir)
+	if err != nil || path != "" {
+		t.Fatalf("Find in empty dir = %q, %v; want no config", path, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte("group: my-group\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path, err = Find(dir)
+	if err != nil || path != filepath.Join(dir, FileName) {
+		t.Fatalf("Find = %q, %v; want %s", path, err, filepath.Join(dir, FileName))
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if cfg.Path != path || len(cfg.Settings) != 1 {
+		t.Errorf("Load = %+v", cfg)
+	}
+}
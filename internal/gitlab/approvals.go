@@ -0,0 +1,88 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ProjectApprovalRules maps project IDs to their merge request approval rules.
+type ProjectApprovalRules = map[int64][]*gl.ProjectApprovalRule
+
+// ProjectMRApprovals maps project IDs to their project-level merge request
+// approval settings.
+type ProjectMRApprovals = map[int64]*gl.ProjectApprovals
+
+func (c *Client) ListProjectApprovalRules(ctx context.Context, projects []*gl.Project) (ProjectApprovalRules, error) {
+	rules, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) ([]*gl.ProjectApprovalRule, error) {
+		if p == nil {
+			return nil, nil
+		}
+		slog.Debug("fetching project approval rules", "project", p.PathWithNamespace)
+		opts := &gl.GetProjectApprovalRulesListsOptions{
+			ListOptions: gl.ListOptions{
+				Page:    1,
+				PerPage: 100,
+			},
+		}
+		var rules []*gl.ProjectApprovalRule
+		for {
+			page, resp, err := c.api.Projects.GetProjectApprovalRules(p.ID, opts, gl.WithContext(ctx))
+			if err != nil {
+				if isForbidden(err) {
+					slog.Warn("approval rules require Premium/Ultimate, skipping", "project", p.PathWithNamespace)
+					break
+				}
+				return nil, fmt.Errorf("listing approval rules for project %d: %w", p.ID, err)
+			}
+			rules = append(rules, page...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return rules, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ProjectApprovalRules, len(projects))
+	for i, p := range projects {
+		if len(rules[i]) > 0 {
+			result[p.ID] = rules[i]
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) ListProjectMRApprovals(ctx context.Context, projects []*gl.Project) (ProjectMRApprovals, error) {
+	approvals, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) (*gl.ProjectApprovals, error) {
+		if p == nil {
+			return nil, nil
+		}
+		slog.Debug("fetching project merge request approval settings", "project", p.PathWithNamespace)
+		approvals, _, err := c.api.Projects.GetApprovalConfiguration(p.ID, gl.WithContext(ctx))
+		if err != nil {
+			if isForbidden(err) {
+				slog.Warn("merge request approval settings require Premium/Ultimate, skipping", "project", p.PathWithNamespace)
+				return nil, nil
+			}
+			return nil, fmt.Errorf("getting approval configuration for project %d: %w", p.ID, err)
+		}
+		return approvals, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ProjectMRApprovals, len(projects))
+	for i, p := range projects {
+		if approvals[i] != nil {
+			result[p.ID] = approvals[i]
+		}
+	}
+	return result, nil
+}
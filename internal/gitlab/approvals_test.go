@@ -0,0 +1,102 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+	gitlabtesting "gitlab.com/gitlab-org/api/client-go/testing"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListProjectApprovalRules(t *testing.T) {
+	t.Run("returns rules per project", func(t *testing.T) {
+		tc := gitlabtesting.NewTestClient(t)
+		c := NewClientFromAPI(tc.Client, "mygroup")
+
+		tc.MockProjects.EXPECT().
+			GetProjectApprovalRules(int64(1), gomock.Any(), gomock.Any()).
+			Return([]*gl.ProjectApprovalRule{
+				{ID: 5, Name: "Security", ApprovalsRequired: 1},
+			}, &gl.Response{}, nil)
+
+		projects := []*gl.Project{{ID: 1, PathWithNamespace: "mygroup/proj"}}
+		result, err := c.ListProjectApprovalRules(context.Background(), projects)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result[1]) != 1 || result[1][0].Name != "Security" {
+			t.Errorf("unexpected rules: %v", result[1])
+		}
+	})
+
+	t.Run("skips projects without Premium", func(t *testing.T) {
+		tc := gitlabtesting.NewTestClient(t)
+		c := NewClientFromAPI(tc.Client, "mygroup")
+
+		tc.MockProjects.EXPECT().
+			GetProjectApprovalRules(int64(1), gomock.Any(), gomock.Any()).
+			Return(nil, nil, &gl.ErrorResponse{
+				Response: &http.Response{StatusCode: http.StatusForbidden},
+			})
+
+		projects := []*gl.Project{{ID: 1, PathWithNamespace: "mygroup/proj"}}
+		result, err := c.ListProjectApprovalRules(context.Background(), projects)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result) != 0 {
+			t.Errorf("expected no rules, got %v", result)
+		}
+	})
+}
+
+func TestListProjectMRApprovals(t *testing.T) {
+	t.Run("skips projects without Premium", func(t *testing.T) {
+		tc := gitlabtesting.NewTestClient(t)
+		c := NewClientFromAPI(tc.Client, "mygroup")
+
+		gomock.InOrder(
+			tc.MockProjects.EXPECT().
+				GetApprovalConfiguration(int64(1), gomock.Any()).
+				Return(nil, nil, &gl.ErrorResponse{
+					Response: &http.Response{StatusCode: http.StatusForbidden},
+				}),
+			tc.MockProjects.EXPECT().
+				GetApprovalConfiguration(int64(2), gomock.Any()).
+				Return(&gl.ProjectApprovals{MergeRequestsAuthorApproval: true}, &gl.Response{}, nil),
+		)
+
+		projects := []*gl.Project{
+			{ID: 1, PathWithNamespace: "mygroup/free"},
+			{ID: 2, PathWithNamespace: "mygroup/premium"},
+		}
+		result, err := c.ListProjectMRApprovals(context.Background(), projects)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := result[1]; ok {
+			t.Error("expected project 1 to be skipped")
+		}
+		if result[2] == nil || !result[2].MergeRequestsAuthorApproval {
+			t.Errorf("unexpected settings for project 2: %v", result[2])
+		}
+	})
+
+	t.Run("returns error on other failures", func(t *testing.T) {
+		tc := gitlabtesting.NewTestClient(t)
+		c := NewClientFromAPI(tc.Client, "mygroup")
+
+		tc.MockProjects.EXPECT().
+			GetApprovalConfiguration(int64(1), gomock.Any()).
+			Return(nil, nil, &gl.ErrorResponse{
+				Response: &http.Response{StatusCode: http.StatusInternalServerError},
+			})
+
+		projects := []*gl.Project{{ID: 1, PathWithNamespace: "mygroup/proj"}}
+		if _, err := c.ListProjectMRApprovals(context.Background(), projects); err == nil {
+			t.Fatal("expected error, got nil")
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ProtectedBranches maps project IDs to their protected branches.
+type ProtectedBranches = map[int64][]*gl.ProtectedBranch
+
+// ProtectedTags maps project IDs to their protected tags.
+type ProtectedTags = map[int64][]*gl.ProtectedTag
+
+func (c *Client) ListProtectedBranches(ctx context.Context, projects []*gl.Project) (ProtectedBranches, error) {
+	branches, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) ([]*gl.ProtectedBranch, error) {
+		if p == nil {
+			return nil, nil
+		}
+		slog.Debug("fetching protected branches", "project", p.PathWithNamespace)
+		opts := &gl.ListProtectedBranchesOptions{
+			ListOptions: gl.ListOptions{
+				Page:    1,
+				PerPage: 100,
+			},
+		}
+		var branches []*gl.ProtectedBranch
+		for {
+			page, resp, err := c.api.ProtectedBranches.ListProtectedBranches(p.ID, opts, gl.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("listing protected branches for project %d: %w", p.ID, err)
+			}
+			branches = append(branches, page...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return branches, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ProtectedBranches, len(projects))
+	for i, p := range projects {
+		if len(branches[i]) > 0 {
+			result[p.ID] = branches[i]
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) ListProtectedTags(ctx context.Context, projects []*gl.Project) (ProtectedTags, error) {
+	tags, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) ([]*gl.ProtectedTag, error) {
+		if p == nil {
+			return nil, nil
+		}
+		slog.Debug("fetching protected tags", "project", p.PathWithNamespace)
+		opts := &gl.ListProtectedTagsOptions{
+			ListOptions: gl.ListOptions{
+				Page:    1,
+				PerPage: 100,
+			},
+		}
+		var tags []*gl.ProtectedTag
+		for {
+			page, resp, err := c.api.ProtectedTags.ListProtectedTags(p.ID, opts, gl.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("listing protected tags for project %d: %w", p.ID, err)
+			}
+			tags = append(tags, page...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return tags, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ProtectedTags, len(projects))
+	for i, p := range projects {
+		if len(tags[i]) > 0 {
+			result[p.ID] = tags[i]
+		}
+	}
+	return result, nil
+}
@@ -2,39 +2,62 @@ package gitlab
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
 	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
 	gl "gitlab.com/gitlab-org/api/client-go"
 )
 
 type Client struct {
-	api   *gl.Client
-	group string
+	api         *gl.Client
+	group       string
+	paths       ignore.Filter
+	concurrency int
 }
 
+// Resources is everything fetched from GitLab. The JSON field names are
+// part of the snapshot format.
 type Resources struct {
-	Groups            []*gl.Group
-	Projects          []*gl.Project
-	GroupMembers      GroupMembers
-	GroupLabels       GroupLabels
-	ProjectLabels     ProjectLabels
-	PipelineSchedules PipelineSchedules
-	ProjectHooks      ProjectHooks
-	GroupHooks        GroupHooks
+	Groups            []*gl.Group          `json:"groups,omitempty"`
+	Projects          []*gl.Project        `json:"projects,omitempty"`
+	GroupMembers      GroupMembers         `json:"group_members,omitempty"`
+	ProjectMembers    ProjectMembers       `json:"project_members,omitempty"`
+	GroupShares       GroupShares          `json:"group_shares,omitempty"`
+	GroupLabels       GroupLabels          `json:"group_labels,omitempty"`
+	ProjectLabels     ProjectLabels        `json:"project_labels,omitempty"`
+	PipelineSchedules PipelineSchedules    `json:"pipeline_schedules,omitempty"`
+	ProjectHooks      ProjectHooks         `json:"project_hooks,omitempty"`
+	GroupHooks        GroupHooks           `json:"group_hooks,omitempty"`
+	GroupVariables    GroupVariables       `json:"group_variables,omitempty"`
+	ProjectVariables  ProjectVariables     `json:"project_variables,omitempty"`
+	ProtectedBranches ProtectedBranches    `json:"protected_branches,omitempty"`
+	ProtectedTags     ProtectedTags        `json:"protected_tags,omitempty"`
+	ApprovalRules     ProjectApprovalRules `json:"approval_rules,omitempty"`
+	MRApprovals       ProjectMRApprovals   `json:"mr_approvals,omitempty"`
+	ServiceAccounts   GroupServiceAccounts `json:"service_accounts,omitempty"`
 }
 
 func NewClientFromAPI(api *gl.Client, group string) *Client {
 	return &Client{api: api, group: group}
 }
 
-func NewClient(token, baseURL, group string) (*Client, error) {
-	client, err := gl.NewClient(token, gl.WithBaseURL(baseURL))
+func NewClient(token, baseURL, group string, retry RetryOptions) (*Client, error) {
+	// Retries are handled by our transport, which also honors Retry-After
+	// and backs off longer than the SDK does.
+	transport := newRetryTransport(http.DefaultTransport.(*http.Transport).Clone(), retry)
+	client, err := gl.NewClient(token,
+		gl.WithBaseURL(baseURL),
+		gl.WithHTTPClient(&http.Client{Transport: transport}),
+		gl.WithoutRetries(),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("creating GitLab client: %w", err)
 	}
-	return &Client{api: client, group: group}, nil
+	return &Client{api: client, group: group, concurrency: DefaultConcurrency}, nil
 }
 
 func (c *Client) FetchAll(ctx context.Context, skipSet skip.Set) (*Resources, error) {
@@ -51,12 +74,26 @@ func (c *Client) FetchAll(ctx context.Context, skipSet skip.Set) (*Resources, er
 	slog.Info("fetched projects", "count", len(projects))
 
 	var groupMembers GroupMembers
+	var projectMembers ProjectMembers
+	var groupShares GroupShares
 	if !skipSet.Has("memberships") {
 		groupMembers, err = c.ListGroupMembers(ctx, groups)
 		if err != nil {
 			return nil, fmt.Errorf("listing group members: %w", err)
 		}
 		slog.Info("fetched group members", "count", len(groupMembers))
+
+		projectMembers, err = c.ListProjectMembers(ctx, projects)
+		if err != nil {
+			return nil, fmt.Errorf("listing project members: %w", err)
+		}
+		slog.Info("fetched project members", "count", len(projectMembers))
+
+		groupShares, err = c.ListGroupShares(ctx, groups)
+		if err != nil {
+			return nil, fmt.Errorf("listing group shares: %w", err)
+		}
+		slog.Info("fetched group shares", "count", len(groupShares))
 	}
 
 	var groupLabels GroupLabels
@@ -100,14 +137,89 @@ func (c *Client) FetchAll(ctx context.Context, skipSet skip.Set) (*Resources, er
 		slog.Info("fetched group hooks", "count", len(groupHooks))
 	}
 
+	var groupVariables GroupVariables
+	var projectVariables ProjectVariables
+	if !skipSet.Has("variables") {
+		groupVariables, err = c.ListGroupVariables(ctx, groups)
+		if err != nil {
+			return nil, fmt.Errorf("listing group variables: %w", err)
+		}
+		slog.Info("fetched group variables", "count", len(groupVariables))
+
+		projectVariables, err = c.ListProjectVariables(ctx, projects)
+		if err != nil {
+			return nil, fmt.Errorf("listing project variables: %w", err)
+		}
+		slog.Info("fetched project variables", "count", len(projectVariables))
+	}
+
+	var protectedBranches ProtectedBranches
+	var protectedTags ProtectedTags
+	if !skipSet.Has("branch_protection") {
+		protectedBranches, err = c.ListProtectedBranches(ctx, projects)
+		if err != nil {
+			return nil, fmt.Errorf("listing protected branches: %w", err)
+		}
+		slog.Info("fetched protected branches", "count", len(protectedBranches))
+
+		protectedTags, err = c.ListProtectedTags(ctx, projects)
+		if err != nil {
+			return nil, fmt.Errorf("listing protected tags: %w", err)
+		}
+		slog.Info("fetched protected tags", "count", len(protectedTags))
+	}
+
+	var approvalRules ProjectApprovalRules
+	if !skipSet.Has("approval_rules") {
+		approvalRules, err = c.ListProjectApprovalRules(ctx, projects)
+		if err != nil {
+			return nil, fmt.Errorf("listing approval rules: %w", err)
+		}
+		slog.Info("fetched approval rules", "count", len(approvalRules))
+	}
+
+	var mrApprovals ProjectMRApprovals
+	if !skipSet.Has("mr_approvals") {
+		mrApprovals, err = c.ListProjectMRApprovals(ctx, projects)
+		if err != nil {
+			return nil, fmt.Errorf("listing merge request approval settings: %w", err)
+		}
+		slog.Info("fetched merge request approval settings", "count", len(mrApprovals))
+	}
+
+	var serviceAccounts GroupServiceAccounts
+	if !skipSet.Has("service_accounts") {
+		serviceAccounts, err = c.ListGroupServiceAccounts(ctx, groups)
+		if err != nil {
+			return nil, fmt.Errorf("listing group service accounts: %w", err)
+		}
+		slog.Info("fetched group service accounts", "count", len(serviceAccounts))
+	}
+
 	return &Resources{
 		Groups:            groups,
 		Projects:          projects,
 		GroupMembers:      groupMembers,
+		ProjectMembers:    projectMembers,
+		GroupShares:       groupShares,
 		GroupLabels:       groupLabels,
 		ProjectLabels:     projectLabels,
 		PipelineSchedules: pipelineSchedules,
 		ProjectHooks:      projectHooks,
 		GroupHooks:        groupHooks,
+		GroupVariables:    groupVariables,
+		ProjectVariables:  projectVariables,
+		ProtectedBranches: protectedBranches,
+		ProtectedTags:     protectedTags,
+		ApprovalRules:     approvalRules,
+		MRApprovals:       mrApprovals,
+		ServiceAccounts:   serviceAccounts,
 	}, nil
 }
+
+// isForbidden returns true if the error represents a 403 Forbidden response,
+// which GitLab returns for Premium-only endpoints on lower tiers.
+func isForbidden(err error) bool {
+	var errResp *gl.ErrorResponse
+	return errors.As(err, &errResp) && errResp.HasStatusCode(http.StatusForbidden)
+}
@@ -0,0 +1,47 @@
+package gitlab
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultConcurrency is the number of parallel API requests used by
+// NewClient.
+const DefaultConcurrency = 4
+
+// SetConcurrency sets how many groups or projects are fetched in parallel.
+// Values below 1 fetch sequentially.
+func (c *Client) SetConcurrency(n int) {
+	c.concurrency = n
+}
+
+// fetchEach calls fetch for every item on up to c.concurrency goroutines and
+// returns the results in the order of items, so the outcome does not depend
+// on scheduling. The first error cancels the remaining calls.
+func fetchEach[T, R any](ctx context.Context, c *Client, items []T, fetch func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(c.concurrency, 1))
+	for i, item := range items {
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			// Go may have waited for a slot while another fetch failed.
+			if err := gctx.Err(); err != nil {
+				return err
+			}
+			r, err := fetch(gctx, item)
+			if err != nil {
+				return err
+			}
+			results[i] = r
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, ctx.Err()
+}
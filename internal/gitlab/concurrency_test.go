@@ -0,0 +1,95 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+	gitlabtesting "gitlab.com/gitlab-org/api/client-go/testing"
+	"go.uber.org/mock/gomock"
+)
+
+func TestFetchEachKeepsOrder(t *testing.T) {
+	c := &Client{concurrency: 4}
+	items := []int{5, 1, 4, 2, 3}
+
+	var running, peak atomic.Int32
+	got, err := fetchEach(context.Background(), c, items, func(ctx context.Context, n int) (int, error) {
+		cur := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		// Finish in a different order than started.
+		time.Sleep(time.Duration(n) * time.Millisecond)
+		return n * 10, nil
+	})
+	if err != nil {
+		t.Fatalf("fetchEach error: %v", err)
+	}
+	for i, n := range items {
+		if got[i] != n*10 {
+			t.Errorf("got[%d] = %d, want %d", i, got[i], n*10)
+		}
+	}
+	if p := peak.Load(); p > 4 {
+		t.Errorf("ran %d fetches at once, want at most 4", p)
+	}
+}
+
+func TestFetchEachStopsOnError(t *testing.T) {
+	c := &Client{concurrency: 1}
+	boom := errors.New("boom")
+
+	var calls int
+	_, err := fetchEach(context.Background(), c, []int{1, 2, 3}, func(ctx context.Context, n int) (int, error) {
+		calls++
+		if n == 2 {
+			return 0, boom
+		}
+		return n, nil
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+	if calls != 2 {
+		t.Errorf("fetch called %d times, want 2", calls)
+	}
+}
+
+func TestListProjectHooksConcurrent(t *testing.T) {
+	tc := gitlabtesting.NewTestClient(t)
+	c := NewClientFromAPI(tc.Client, "mygroup")
+	c.SetConcurrency(8)
+
+	var projects []*gl.Project
+	for id := int64(1); id <= 20; id++ {
+		projects = append(projects, &gl.Project{ID: id})
+	}
+	tc.MockProjects.EXPECT().
+		ListProjectHooks(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(pid any, _ *gl.ListProjectHooksOptions, _ ...gl.RequestOptionFunc) ([]*gl.ProjectHook, *gl.Response, error) {
+			id := pid.(int64)
+			return []*gl.ProjectHook{{ID: id * 100, ProjectID: id}}, &gl.Response{}, nil
+		}).
+		Times(20)
+
+	result, err := c.ListProjectHooks(context.Background(), projects)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 20 {
+		t.Fatalf("got hooks for %d projects, want 20", len(result))
+	}
+	for id, hooks := range result {
+		if len(hooks) != 1 || hooks[0].ID != id*100 {
+			t.Errorf("project %d hooks = %v", id, hooks)
+		}
+	}
+}
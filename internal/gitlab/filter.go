@@ -0,0 +1,54 @@
+package gitlab
+
+import (
+	"log/slog"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// SetPathFilter limits ListGroups and ListProjects, and with them everything
+// fetched per group or project, to the paths matching include (all if empty)
+// and not matching exclude. A pattern for a group covers everything below it.
+func (c *Client) SetPathFilter(include, exclude []string) {
+	c.paths = ignore.Filter{Include: include, Exclude: exclude}
+}
+
+// SelectPaths drops the groups and projects of r that do not match include
+// or match exclude, like SetPathFilter does for fetching. Resources of
+// dropped groups and projects are left in place but no longer used.
+func (r *Resources) SelectPaths(include, exclude []string) {
+	c := &Client{paths: ignore.Filter{Include: include, Exclude: exclude}}
+	r.Groups = c.filterGroups(r.Groups)
+	r.Projects = c.filterProjects(r.Projects)
+}
+
+func (c *Client) filterGroups(groups []*gl.Group) []*gl.Group {
+	if c.paths.Empty() {
+		return groups
+	}
+	var result []*gl.Group
+	for _, g := range groups {
+		if g != nil && !c.paths.Selected(g.FullPath) {
+			slog.Debug("excluding group", "group", g.FullPath)
+			continue
+		}
+		result = append(result, g)
+	}
+	return result
+}
+
+func (c *Client) filterProjects(projects []*gl.Project) []*gl.Project {
+	if c.paths.Empty() {
+		return projects
+	}
+	var result []*gl.Project
+	for _, p := range projects {
+		if p != nil && !c.paths.Selected(p.PathWithNamespace) {
+			slog.Debug("excluding project", "project", p.PathWithNamespace)
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
@@ -0,0 +1,50 @@
+package gitlab
+
+import (
+	"context"
+	"testing"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+	gitlabtesting "gitlab.com/gitlab-org/api/client-go/testing"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListGroupsAndProjectsWithPathFilter(t *testing.T) {
+	tc := gitlabtesting.NewTestClient(t)
+	c := NewClientFromAPI(tc.Client, "mygroup")
+	c.SetPathFilter([]string{"mygroup/team-*"}, []string{"mygroup/team-a/sandbox"})
+
+	tc.MockGroups.EXPECT().
+		GetGroup("mygroup", gomock.Any(), gomock.Any()).
+		Return(&gl.Group{ID: 1, FullPath: "mygroup"}, &gl.Response{}, nil)
+	tc.MockGroups.EXPECT().
+		ListDescendantGroups("mygroup", gomock.Any(), gomock.Any()).
+		Return([]*gl.Group{
+			{ID: 2, FullPath: "mygroup/team-a"},
+			{ID: 3, FullPath: "mygroup/team-a/sandbox"},
+			{ID: 4, FullPath: "mygroup/ops"},
+		}, &gl.Response{}, nil)
+	tc.MockGroups.EXPECT().
+		ListGroupProjects("mygroup", gomock.Any(), gomock.Any()).
+		Return([]*gl.Project{
+			{ID: 10, PathWithNamespace: "mygroup/team-a/api"},
+			{ID: 11, PathWithNamespace: "mygroup/team-a/sandbox/test"},
+			{ID: 12, PathWithNamespace: "mygroup/ops/infra"},
+		}, &gl.Response{}, nil)
+
+	groups, err := c.ListGroups(context.Background())
+	if err != nil {
+		t.Fatalf("ListGroups error: %v", err)
+	}
+	if len(groups) != 1 || groups[0].ID != 2 {
+		t.Errorf("groups = %v, want only mygroup/team-a", groups)
+	}
+
+	projects, err := c.ListProjects(context.Background())
+	if err != nil {
+		t.Fatalf("ListProjects error: %v", err)
+	}
+	if len(projects) != 1 || projects[0].ID != 10 {
+		t.Errorf("projects = %v, want only mygroup/team-a/api", projects)
+	}
+}
@@ -12,11 +12,9 @@ import (
 type GroupMembers = map[int64][]*gl.GroupMember
 
 func (c *Client) ListGroupMembers(ctx context.Context, groups []*gl.Group) (GroupMembers, error) {
-	result := make(GroupMembers, len(groups))
-
-	for _, g := range groups {
+	members, err := fetchEach(ctx, c, groups, func(ctx context.Context, g *gl.Group) ([]*gl.GroupMember, error) {
 		if g == nil {
-			continue
+			return nil, nil
 		}
 		slog.Debug("fetching group members", "group", g.FullPath)
 		opts := &gl.ListGroupMembersOptions{
@@ -37,10 +35,17 @@ func (c *Client) ListGroupMembers(ctx context.Context, groups []*gl.Group) (Grou
 			}
 			opts.Page = resp.NextPage
 		}
-		if len(members) > 0 {
-			result[g.ID] = members
-		}
+		return members, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	result := make(GroupMembers, len(groups))
+	for i, g := range groups {
+		if len(members[i]) > 0 {
+			result[g.ID] = members[i]
+		}
+	}
 	return result, nil
 }
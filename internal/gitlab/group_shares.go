@@ -0,0 +1,42 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GroupShares maps group IDs to the groups they are shared with.
+type GroupShares = map[int64][]gl.SharedWithGroup
+
+// ListGroupShares fetches the groups each group is shared with. The group
+// list endpoints do not return shared_with_groups, so every group is fetched
+// individually.
+func (c *Client) ListGroupShares(ctx context.Context, groups []*gl.Group) (GroupShares, error) {
+	shares, err := fetchEach(ctx, c, groups, func(ctx context.Context, g *gl.Group) ([]gl.SharedWithGroup, error) {
+		if g == nil {
+			return nil, nil
+		}
+		slog.Debug("fetching group shares", "group", g.FullPath)
+		detail, _, err := c.api.Groups.GetGroup(g.ID, &gl.GetGroupOptions{
+			WithProjects: gl.Ptr(false),
+		}, gl.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("getting group %d: %w", g.ID, err)
+		}
+		return detail.SharedWithGroups, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(GroupShares, len(groups))
+	for i, g := range groups {
+		if len(shares[i]) > 0 {
+			result[g.ID] = shares[i]
+		}
+	}
+	return result, nil
+}
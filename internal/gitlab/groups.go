@@ -38,7 +38,7 @@ func (c *Client) ListGroups(ctx context.Context) ([]*gl.Group, error) {
 			}
 			opts.Page = resp.NextPage
 		}
-		return allGroups, nil
+		return c.filterGroups(allGroups), nil
 	}
 
 	opts := &gl.ListGroupsOptions{
@@ -58,5 +58,5 @@ func (c *Client) ListGroups(ctx context.Context) ([]*gl.Group, error) {
 		}
 		opts.Page = resp.NextPage
 	}
-	return allGroups, nil
+	return c.filterGroups(allGroups), nil
 }
@@ -2,10 +2,8 @@ package gitlab
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log/slog"
-	"net/http"
 
 	gl "gitlab.com/gitlab-org/api/client-go"
 )
@@ -17,11 +15,9 @@ type ProjectHooks = map[int64][]*gl.ProjectHook
 type GroupHooks = map[int64][]*gl.GroupHook
 
 func (c *Client) ListProjectHooks(ctx context.Context, projects []*gl.Project) (ProjectHooks, error) {
-	result := make(ProjectHooks, len(projects))
-
-	for _, p := range projects {
+	hooks, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) ([]*gl.ProjectHook, error) {
 		if p == nil {
-			continue
+			return nil, nil
 		}
 		slog.Debug("fetching project hooks", "project", p.PathWithNamespace)
 		opts := &gl.ListProjectHooksOptions{
@@ -42,19 +38,25 @@ func (c *Client) ListProjectHooks(ctx context.Context, projects []*gl.Project) (
 			}
 			opts.Page = resp.NextPage
 		}
-		if len(hooks) > 0 {
-			result[p.ID] = hooks
+		return hooks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ProjectHooks, len(projects))
+	for i, p := range projects {
+		if len(hooks[i]) > 0 {
+			result[p.ID] = hooks[i]
 		}
 	}
 	return result, nil
 }
 
 func (c *Client) ListGroupHooks(ctx context.Context, groups []*gl.Group) (GroupHooks, error) {
-	result := make(GroupHooks, len(groups))
-
-	for _, g := range groups {
+	hooks, err := fetchEach(ctx, c, groups, func(ctx context.Context, g *gl.Group) ([]*gl.GroupHook, error) {
 		if g == nil {
-			continue
+			return nil, nil
 		}
 		slog.Debug("fetching group hooks", "group", g.FullPath)
 		opts := &gl.ListGroupHooksOptions{
@@ -67,8 +69,7 @@ func (c *Client) ListGroupHooks(ctx context.Context, groups []*gl.Group) (GroupH
 		for {
 			page, resp, err := c.api.Groups.ListGroupHooks(g.ID, opts, gl.WithContext(ctx))
 			if err != nil {
-				var errResp *gl.ErrorResponse
-				if errors.As(err, &errResp) && errResp.HasStatusCode(http.StatusForbidden) {
+				if isForbidden(err) {
 					slog.Warn("group hooks require Premium/Ultimate, skipping", "group", g.FullPath)
 					break
 				}
@@ -80,8 +81,16 @@ func (c *Client) ListGroupHooks(ctx context.Context, groups []*gl.Group) (GroupH
 			}
 			opts.Page = resp.NextPage
 		}
-		if len(hooks) > 0 {
-			result[g.ID] = hooks
+		return hooks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(GroupHooks, len(groups))
+	for i, g := range groups {
+		if len(hooks[i]) > 0 {
+			result[g.ID] = hooks[i]
 		}
 	}
 	return result, nil
@@ -15,12 +15,9 @@ type GroupLabels = map[int64][]*gl.GroupLabel
 type ProjectLabels = map[int64][]*gl.Label
 
 func (c *Client) ListGroupLabels(ctx context.Context, groups []*gl.Group) (GroupLabels, error) {
-	result := make(GroupLabels, len(groups))
-	seen := make(map[int64]bool) // track label IDs already attributed to a group
-
-	for _, g := range groups {
+	labels, err := fetchEach(ctx, c, groups, func(ctx context.Context, g *gl.Group) ([]*gl.GroupLabel, error) {
 		if g == nil {
-			continue
+			return nil, nil
 		}
 		slog.Debug("fetching group labels", "group", g.FullPath)
 		opts := &gl.ListGroupLabelsOptions{
@@ -42,8 +39,20 @@ func (c *Client) ListGroupLabels(ctx context.Context, groups []*gl.Group) (Group
 			}
 			opts.Page = resp.NextPage
 		}
+		return labels, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Attribute each label to the first group it is seen in, so inherited
+	// labels stay with their parent group. Done after fetching to keep the
+	// result independent of request order.
+	result := make(GroupLabels, len(groups))
+	seen := make(map[int64]bool)
+	for i, g := range groups {
 		var owned []*gl.GroupLabel
-		for _, l := range labels {
+		for _, l := range labels[i] {
 			if !seen[l.ID] {
 				seen[l.ID] = true
 				owned = append(owned, l)
@@ -53,16 +62,13 @@ func (c *Client) ListGroupLabels(ctx context.Context, groups []*gl.Group) (Group
 			result[g.ID] = owned
 		}
 	}
-
 	return result, nil
 }
 
 func (c *Client) ListProjectLabels(ctx context.Context, projects []*gl.Project) (ProjectLabels, error) {
-	result := make(ProjectLabels, len(projects))
-
-	for _, p := range projects {
+	labels, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) ([]*gl.Label, error) {
 		if p == nil {
-			continue
+			return nil, nil
 		}
 		slog.Debug("fetching project labels", "project", p.PathWithNamespace)
 		opts := &gl.ListLabelsOptions{
@@ -87,10 +93,17 @@ func (c *Client) ListProjectLabels(ctx context.Context, projects []*gl.Project)
 			}
 			opts.Page = resp.NextPage
 		}
-		if len(labels) > 0 {
-			result[p.ID] = labels
-		}
+		return labels, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	result := make(ProjectLabels, len(projects))
+	for i, p := range projects {
+		if len(labels[i]) > 0 {
+			result[p.ID] = labels[i]
+		}
+	}
 	return result, nil
 }
@@ -42,22 +42,23 @@ func (c *Client) GetDefaultBranch(ctx context.Context, project string) (string,
 	return p.DefaultBranch, nil
 }
 
-// FindExistingDriftMR searches for an open MR with a "drift/" source branch prefix.
-// Returns nil, nil if no matching MR is found.
-func (c *Client) FindExistingDriftMR(ctx context.Context, project string) (*gl.BasicMergeRequest, error) {
+// FindDriftMRs returns the open MRs whose source branch starts with
+// branchPrefix, keyed by source branch.
+func (c *Client) FindDriftMRs(ctx context.Context, project, branchPrefix string) (map[string]*gl.BasicMergeRequest, error) {
 	opts := &gl.ListProjectMergeRequestsOptions{
 		State:       gl.Ptr("opened"),
 		ListOptions: gl.ListOptions{PerPage: 100},
 	}
 
+	found := make(map[string]*gl.BasicMergeRequest)
 	for {
 		mrs, resp, err := c.api.MergeRequests.ListProjectMergeRequests(project, opts, gl.WithContext(ctx))
 		if err != nil {
 			return nil, fmt.Errorf("listing merge requests for %s: %w", project, err)
 		}
 		for _, mr := range mrs {
-			if strings.HasPrefix(mr.SourceBranch, "drift/") {
-				return mr, nil
+			if strings.HasPrefix(mr.SourceBranch, branchPrefix) {
+				found[mr.SourceBranch] = mr
 			}
 		}
 		if resp.NextPage == 0 {
@@ -66,23 +67,21 @@ func (c *Client) FindExistingDriftMR(ctx context.Context, project string) (*gl.B
 		opts.Page = resp.NextPage
 	}
 
-	return nil, nil
+	return found, nil
 }
 
-// EnsureBranch creates the branch if it does not already exist.
-func (c *Client) EnsureBranch(ctx context.Context, project, branchName, defaultBranch string) error {
-	_, _, err := c.api.Branches.GetBranch(project, branchName, gl.WithContext(ctx))
-	if err == nil {
-		return nil
-	}
-
-	if !isNotFound(err) {
-		return fmt.Errorf("checking branch %s: %w", branchName, err)
+// ResetBranch points the branch at ref, creating it if it does not exist.
+// GitLab has no API to move a branch, so it is deleted and recreated; an
+// open merge request of the branch stays open and picks the new branch up.
+func (c *Client) ResetBranch(ctx context.Context, project, branchName, ref string) error {
+	_, err := c.api.Branches.DeleteBranch(project, branchName, gl.WithContext(ctx))
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("deleting branch %s: %w", branchName, err)
 	}
 
 	_, _, err = c.api.Branches.CreateBranch(project, &gl.CreateBranchOptions{
 		Branch: gl.Ptr(branchName),
-		Ref:    gl.Ptr(defaultBranch),
+		Ref:    gl.Ptr(ref),
 	}, gl.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("creating branch %s: %w", branchName, err)
@@ -91,62 +90,99 @@ func (c *Client) EnsureBranch(ctx context.Context, project, branchName, defaultB
 	return nil
 }
 
-// CommitDriftFiles commits generated .tf files to the given branch.
-// Returns true if a commit was created, false if all files are identical.
-func (c *Client) CommitDriftFiles(ctx context.Context, project, branchName, generatedDir, repoPath string) (bool, error) {
-	files, err := filepath.Glob(filepath.Join(generatedDir, "*.tf"))
-	if err != nil {
-		return false, fmt.Errorf("listing generated files: %w", err)
-	}
-	if len(files) == 0 {
+// DriftFiles are the file changes of a drift commit.
+type DriftFiles struct {
+	// Generated are the generated .tf files to create or update.
+	Generated []string
+	// Delete lists the base names of files to delete, e.g. of namespaces
+	// that no longer exist. Files missing from the branch are skipped.
+	Delete []string
+	// Moves maps base names of generated files that are new to the branch
+	// to the file they replace, e.g. after a namespace was transferred.
+	Moves map[string]string
+}
+
+// CommitDriftFiles commits files under repoPath with the given commit
+// message. The commit is based on the latest baseBranch and replaces
+// whatever branchName held before, so the branch never lags behind
+// baseBranch or keeps outdated drift.
+// Returns true if a commit was created, false if nothing differs from
+// baseBranch; branchName is left untouched then.
+func (c *Client) CommitDriftFiles(ctx context.Context, project, branchName, baseBranch string, files DriftFiles, repoPath, message string) (bool, error) {
+	if len(files.Generated) == 0 && len(files.Delete) == 0 {
 		return false, nil
 	}
 
+	remotePath := func(name string) string {
+		if repoPath != "" {
+			return path.Join(repoPath, name)
+		}
+		return name
+	}
+
 	var actions []*gl.CommitActionOptions
 
-	for _, f := range files {
+	for _, f := range files.Generated {
 		localContent, err := os.ReadFile(f)
 		if err != nil {
 			return false, fmt.Errorf("reading file %s: %w", f, err)
 		}
 
-		remotePath := filepath.Base(f)
-		if repoPath != "" {
-			remotePath = path.Join(repoPath, remotePath)
-		}
-
-		existing, _, err := c.api.RepositoryFiles.GetRawFile(project, remotePath, &gl.GetRawFileOptions{
-			Ref: gl.Ptr(branchName),
-		}, gl.WithContext(ctx))
-
+		name := filepath.Base(f)
+		existing, found, err := c.remoteFile(ctx, project, baseBranch, remotePath(name))
 		if err != nil {
-			if isNotFound(err) {
-				actions = append(actions, &gl.CommitActionOptions{
-					Action:   gl.Ptr(gl.FileCreate),
-					FilePath: gl.Ptr(remotePath),
-					Content:  gl.Ptr(string(localContent)),
-				})
-				continue
-			}
-			return false, fmt.Errorf("checking remote file %s: %w", remotePath, err)
+			return false, err
 		}
 
-		if !bytes.Equal(existing, localContent) {
+		switch {
+		case !found:
+			action := &gl.CommitActionOptions{
+				Action:   gl.Ptr(gl.FileCreate),
+				FilePath: gl.Ptr(remotePath(name)),
+				Content:  gl.Ptr(string(localContent)),
+			}
+			if previous, ok := files.Moves[name]; ok {
+				_, found, err := c.remoteFile(ctx, project, baseBranch, remotePath(previous))
+				if err != nil {
+					return false, err
+				}
+				if found {
+					action.Action = gl.Ptr(gl.FileMove)
+					action.PreviousPath = gl.Ptr(remotePath(previous))
+				}
+			}
+			actions = append(actions, action)
+		case !bytes.Equal(existing, localContent):
 			actions = append(actions, &gl.CommitActionOptions{
 				Action:   gl.Ptr(gl.FileUpdate),
-				FilePath: gl.Ptr(remotePath),
+				FilePath: gl.Ptr(remotePath(name)),
 				Content:  gl.Ptr(string(localContent)),
 			})
 		}
 	}
 
+	for _, name := range files.Delete {
+		_, found, err := c.remoteFile(ctx, project, baseBranch, remotePath(name))
+		if err != nil {
+			return false, err
+		}
+		if found {
+			actions = append(actions, &gl.CommitActionOptions{
+				Action:   gl.Ptr(gl.FileDelete),
+				FilePath: gl.Ptr(remotePath(name)),
+			})
+		}
+	}
+
 	if len(actions) == 0 {
 		return false, nil
 	}
 
-	_, _, err = c.api.Commits.CreateCommit(project, &gl.CreateCommitOptions{
+	_, _, err := c.api.Commits.CreateCommit(project, &gl.CreateCommitOptions{
 		Branch:        gl.Ptr(branchName),
-		CommitMessage: gl.Ptr("chore: update Terraform resources from GitLab drift scan"),
+		StartBranch:   gl.Ptr(baseBranch),
+		Force:         gl.Ptr(true),
+		CommitMessage: gl.Ptr(message),
 		Actions:       actions,
 	}, gl.WithContext(ctx))
 	if err != nil {
@@ -156,17 +192,130 @@ func (c *Client) CommitDriftFiles(ctx context.Context, project, branchName, gene
 	return true, nil
 }
 
+// remoteFile returns the content of a file on the given ref, and false if
+// it does not exist.
+func (c *Client) remoteFile(ctx context.Context, project, ref, filePath string) ([]byte, bool, error) {
+	content, _, err := c.api.RepositoryFiles.GetRawFile(project, filePath, &gl.GetRawFileOptions{
+		Ref: gl.Ptr(ref),
+	}, gl.WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("checking remote file %s: %w", filePath, err)
+	}
+	return content, true, nil
+}
+
+// DefaultDriftMRTitle is the title and commit message of drift merge
+// requests unless configured otherwise.
+const DefaultDriftMRTitle = "chore: update Terraform resources from GitLab drift scan"
+
+// DriftMROptions configures the drift merge request.
+type DriftMROptions struct {
+	Title       string
+	Description string
+	Labels      []string
+	AssigneeIDs []int64
+	ReviewerIDs []int64
+	// Draft prefixes the title with "Draft: ", so the MR cannot be merged
+	// until someone marks it as ready.
+	Draft bool
+	// Squash squashes the drift commits on merge.
+	Squash bool
+}
+
+func (o DriftMROptions) title() string {
+	title := o.Title
+	if title == "" {
+		title = DefaultDriftMRTitle
+	}
+	if o.Draft {
+		title = "Draft: " + title
+	}
+	return title
+}
+
 // CreateDriftMR creates a new merge request for the drift branch.
-func (c *Client) CreateDriftMR(ctx context.Context, project, branchName, defaultBranch string) (*gl.MergeRequest, error) {
-	mr, _, err := c.api.MergeRequests.CreateMergeRequest(project, &gl.CreateMergeRequestOptions{
-		Title:              gl.Ptr("chore: update Terraform resources from GitLab drift scan"),
+func (c *Client) CreateDriftMR(ctx context.Context, project, branchName, targetBranch string, opts DriftMROptions) (*gl.MergeRequest, error) {
+	create := &gl.CreateMergeRequestOptions{
+		Title:              gl.Ptr(opts.title()),
 		SourceBranch:       gl.Ptr(branchName),
-		TargetBranch:       gl.Ptr(defaultBranch),
+		TargetBranch:       gl.Ptr(targetBranch),
 		RemoveSourceBranch: gl.Ptr(true),
-		Description:        gl.Ptr("Auto-generated by [terraform-gitlab-drift](https://github.com/xMoelletschi/terraform-gitlab-drift)."),
-	}, gl.WithContext(ctx))
+		Description:        gl.Ptr(opts.Description),
+	}
+	if len(opts.Labels) > 0 {
+		create.Labels = gl.Ptr(gl.LabelOptions(opts.Labels))
+	}
+	if len(opts.AssigneeIDs) > 0 {
+		create.AssigneeIDs = gl.Ptr(opts.AssigneeIDs)
+	}
+	if len(opts.ReviewerIDs) > 0 {
+		create.ReviewerIDs = gl.Ptr(opts.ReviewerIDs)
+	}
+	if opts.Squash {
+		create.Squash = gl.Ptr(true)
+	}
+
+	mr, _, err := c.api.MergeRequests.CreateMergeRequest(project, create, gl.WithContext(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("creating merge request: %w", err)
 	}
 	return mr, nil
 }
+
+// UpdateDriftMR rewrites the title and description of an existing drift
+// merge request, so it reflects the latest scan, and adds missing labels.
+// Assignees, reviewers and labels set by people are kept; opts.Draft should
+// be the current draft state so an MR marked as ready stays ready.
+func (c *Client) UpdateDriftMR(ctx context.Context, project string, iid int64, opts DriftMROptions) (*gl.MergeRequest, error) {
+	update := &gl.UpdateMergeRequestOptions{
+		Title:       gl.Ptr(opts.title()),
+		Description: gl.Ptr(opts.Description),
+	}
+	if len(opts.Labels) > 0 {
+		update.AddLabels = gl.Ptr(gl.LabelOptions(opts.Labels))
+	}
+
+	mr, _, err := c.api.MergeRequests.UpdateMergeRequest(project, iid, update, gl.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("updating merge request !%d: %w", iid, err)
+	}
+	return mr, nil
+}
+
+// CloseDriftMR comments on a drift merge request and closes it.
+func (c *Client) CloseDriftMR(ctx context.Context, project string, iid int64, comment string) error {
+	_, _, err := c.api.Notes.CreateMergeRequestNote(project, iid, &gl.CreateMergeRequestNoteOptions{
+		Body: gl.Ptr(comment),
+	}, gl.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("commenting on merge request !%d: %w", iid, err)
+	}
+
+	_, _, err = c.api.MergeRequests.UpdateMergeRequest(project, iid, &gl.UpdateMergeRequestOptions{
+		StateEvent: gl.Ptr("close"),
+	}, gl.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("closing merge request !%d: %w", iid, err)
+	}
+	return nil
+}
+
+// UserIDs resolves usernames to user IDs, e.g. for MR assignees.
+func (c *Client) UserIDs(ctx context.Context, usernames []string) ([]int64, error) {
+	ids := make([]int64, 0, len(usernames))
+	for _, username := range usernames {
+		username = strings.TrimPrefix(username, "@")
+		users, _, err := c.api.Users.ListUsers(&gl.ListUsersOptions{Username: gl.Ptr(username)}, gl.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("looking up user %s: %w", username, err)
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("user %s not found", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	gl "gitlab.com/gitlab-org/api/client-go"
@@ -48,8 +49,8 @@ func TestGetDefaultBranch(t *testing.T) {
 	})
 }
 
-func TestFindExistingDriftMR(t *testing.T) {
-	t.Run("finds drift MR", func(t *testing.T) {
+func TestFindDriftMRs(t *testing.T) {
+	t.Run("finds drift MRs by branch", func(t *testing.T) {
 		tc := gitlabtesting.NewTestClient(t)
 		c := NewClientFromAPI(tc.Client, "mygroup")
 
@@ -57,22 +58,27 @@ func TestFindExistingDriftMR(t *testing.T) {
 			ListProjectMergeRequests("mygroup/myproject", gomock.Any(), gomock.Any()).
 			Return([]*gl.BasicMergeRequest{
 				{IID: 1, SourceBranch: "feature/something"},
-				{IID: 2, SourceBranch: "drift/update-2025-01-01", WebURL: "https://gitlab.com/mr/2"},
+				{IID: 2, SourceBranch: "drift/backtrack", WebURL: "https://gitlab.com/mr/2"},
+				{IID: 3, SourceBranch: "drift/backtrack-team-a", WebURL: "https://gitlab.com/mr/3"},
+				{IID: 4, SourceBranch: "drift/other"},
 			}, &gl.Response{}, nil)
 
-		mr, err := c.FindExistingDriftMR(context.Background(), "mygroup/myproject")
+		mrs, err := c.FindDriftMRs(context.Background(), "mygroup/myproject", "drift/backtrack")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if mr == nil {
-			t.Fatal("expected MR, got nil")
+		if len(mrs) != 2 {
+			t.Fatalf("got %d MRs, want 2: %v", len(mrs), mrs)
 		}
-		if mr.IID != 2 {
-			t.Errorf("got IID %d, want 2", mr.IID)
+		if mrs["drift/backtrack"].IID != 2 {
+			t.Errorf("got IID %d for drift/backtrack, want 2", mrs["drift/backtrack"].IID)
+		}
+		if mrs["drift/backtrack-team-a"].IID != 3 {
+			t.Errorf("got IID %d for drift/backtrack-team-a, want 3", mrs["drift/backtrack-team-a"].IID)
 		}
 	})
 
-	t.Run("returns nil when none found", func(t *testing.T) {
+	t.Run("returns empty map when none found", func(t *testing.T) {
 		tc := gitlabtesting.NewTestClient(t)
 		c := NewClientFromAPI(tc.Client, "mygroup")
 
@@ -82,68 +88,79 @@ func TestFindExistingDriftMR(t *testing.T) {
 				{IID: 1, SourceBranch: "feature/something"},
 			}, &gl.Response{}, nil)
 
-		mr, err := c.FindExistingDriftMR(context.Background(), "mygroup/myproject")
+		mrs, err := c.FindDriftMRs(context.Background(), "mygroup/myproject", "drift/")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if mr != nil {
-			t.Errorf("expected nil, got MR with IID %d", mr.IID)
+		if len(mrs) != 0 {
+			t.Errorf("expected no MRs, got %v", mrs)
 		}
 	})
 
-	t.Run("paginates to find drift MR", func(t *testing.T) {
+	t.Run("paginates", func(t *testing.T) {
 		tc := gitlabtesting.NewTestClient(t)
 		c := NewClientFromAPI(tc.Client, "mygroup")
 
-		// First page: no drift MR, has next page
+		// First page: one drift MR, has next page
 		tc.MockMergeRequests.EXPECT().
 			ListProjectMergeRequests("mygroup/myproject", gomock.Any(), gomock.Any()).
 			Return([]*gl.BasicMergeRequest{
 				{IID: 1, SourceBranch: "feature/a"},
+				{IID: 2, SourceBranch: "drift/update-2025-01-01"},
 			}, &gl.Response{NextPage: 2}, nil)
 
-		// Second page: has drift MR
+		// Second page: another drift MR
 		tc.MockMergeRequests.EXPECT().
 			ListProjectMergeRequests("mygroup/myproject", gomock.Any(), gomock.Any()).
 			Return([]*gl.BasicMergeRequest{
 				{IID: 3, SourceBranch: "drift/update-2025-02-01", WebURL: "https://gitlab.com/mr/3"},
 			}, &gl.Response{}, nil)
 
-		mr, err := c.FindExistingDriftMR(context.Background(), "mygroup/myproject")
+		mrs, err := c.FindDriftMRs(context.Background(), "mygroup/myproject", "drift/")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if mr == nil {
-			t.Fatal("expected MR, got nil")
+		if len(mrs) != 2 {
+			t.Fatalf("got %d MRs, want 2", len(mrs))
 		}
-		if mr.IID != 3 {
-			t.Errorf("got IID %d, want 3", mr.IID)
+		if mrs["drift/update-2025-02-01"].IID != 3 {
+			t.Errorf("got IID %d, want 3", mrs["drift/update-2025-02-01"].IID)
 		}
 	})
 }
 
-func TestEnsureBranch(t *testing.T) {
-	t.Run("branch already exists", func(t *testing.T) {
+func TestResetBranch(t *testing.T) {
+	t.Run("recreates existing branch", func(t *testing.T) {
 		tc := gitlabtesting.NewTestClient(t)
 		c := NewClientFromAPI(tc.Client, "mygroup")
 
-		tc.MockBranches.EXPECT().
-			GetBranch("mygroup/myproject", "drift/update-2025-01-01", gomock.Any()).
-			Return(&gl.Branch{Name: "drift/update-2025-01-01"}, nil, nil)
-
-		err := c.EnsureBranch(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main")
+		gomock.InOrder(
+			tc.MockBranches.EXPECT().
+				DeleteBranch("mygroup/myproject", "drift/update-2025-01-01", gomock.Any()).
+				Return(nil, nil),
+			tc.MockBranches.EXPECT().
+				CreateBranch("mygroup/myproject", gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ any, opt *gl.CreateBranchOptions, _ ...gl.RequestOptionFunc) (*gl.Branch, *gl.Response, error) {
+					if *opt.Ref != "main" {
+						t.Errorf("got ref %q, want main", *opt.Ref)
+					}
+					return &gl.Branch{Name: *opt.Branch}, nil, nil
+				}),
+		)
+
+		err := c.ResetBranch(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
 	})
 
-	t.Run("creates branch on 404", func(t *testing.T) {
+	t.Run("creates missing branch", func(t *testing.T) {
 		tc := gitlabtesting.NewTestClient(t)
 		c := NewClientFromAPI(tc.Client, "mygroup")
 
 		tc.MockBranches.EXPECT().
-			GetBranch("mygroup/myproject", "drift/update-2025-01-01", gomock.Any()).
-			Return(nil, nil, &gl.ErrorResponse{
+			DeleteBranch("mygroup/myproject", "drift/update-2025-01-01", gomock.Any()).
+			Return(nil, &gl.ErrorResponse{
 				Response: &http.Response{StatusCode: http.StatusNotFound},
 			})
 
@@ -151,25 +168,7 @@ func TestEnsureBranch(t *testing.T) {
 			CreateBranch("mygroup/myproject", gomock.Any(), gomock.Any()).
 			Return(&gl.Branch{Name: "drift/update-2025-01-01"}, nil, nil)
 
-		err := c.EnsureBranch(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main")
-		if err != nil {
-			t.Fatalf("unexpected error: %v", err)
-		}
-	})
-
-	t.Run("creates branch on ErrNotFound", func(t *testing.T) {
-		tc := gitlabtesting.NewTestClient(t)
-		c := NewClientFromAPI(tc.Client, "mygroup")
-
-		tc.MockBranches.EXPECT().
-			GetBranch("mygroup/myproject", "drift/update-2025-01-01", gomock.Any()).
-			Return(nil, nil, gl.ErrNotFound)
-
-		tc.MockBranches.EXPECT().
-			CreateBranch("mygroup/myproject", gomock.Any(), gomock.Any()).
-			Return(&gl.Branch{Name: "drift/update-2025-01-01"}, nil, nil)
-
-		err := c.EnsureBranch(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main")
+		err := c.ResetBranch(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main")
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -180,12 +179,12 @@ func TestEnsureBranch(t *testing.T) {
 		c := NewClientFromAPI(tc.Client, "mygroup")
 
 		tc.MockBranches.EXPECT().
-			GetBranch("mygroup/myproject", "drift/update-2025-01-01", gomock.Any()).
-			Return(nil, nil, &gl.ErrorResponse{
+			DeleteBranch("mygroup/myproject", "drift/update-2025-01-01", gomock.Any()).
+			Return(nil, &gl.ErrorResponse{
 				Response: &http.Response{StatusCode: http.StatusForbidden},
 			})
 
-		err := c.EnsureBranch(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main")
+		err := c.ResetBranch(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main")
 		if err == nil {
 			t.Fatal("expected error, got nil")
 		}
@@ -212,7 +211,7 @@ func TestCommitDriftFiles(t *testing.T) {
 			CreateCommit("mygroup/myproject", gomock.Any(), gomock.Any()).
 			Return(&gl.Commit{ID: "abc123"}, nil, nil)
 
-		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", dir, "")
+		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", "main", DriftFiles{Generated: []string{filepath.Join(dir, "groups.tf")}}, "", DefaultDriftMRTitle)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -238,7 +237,7 @@ func TestCommitDriftFiles(t *testing.T) {
 			CreateCommit("mygroup/myproject", gomock.Any(), gomock.Any()).
 			Return(&gl.Commit{ID: "abc123"}, nil, nil)
 
-		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", dir, "")
+		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", "main", DriftFiles{Generated: []string{filepath.Join(dir, "groups.tf")}}, "", DefaultDriftMRTitle)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -258,13 +257,26 @@ func TestCommitDriftFiles(t *testing.T) {
 
 		tc.MockRepositoryFiles.EXPECT().
 			GetRawFile("mygroup/myproject", "groups.tf", gomock.Any(), gomock.Any()).
-			Return([]byte("resource { old }"), nil, nil)
+			DoAndReturn(func(_ any, _ string, opt *gl.GetRawFileOptions, _ ...gl.RequestOptionFunc) ([]byte, *gl.Response, error) {
+				if *opt.Ref != "main" {
+					t.Errorf("compared with ref %q, want main", *opt.Ref)
+				}
+				return []byte("resource { old }"), nil, nil
+			})
 
 		tc.MockCommits.EXPECT().
 			CreateCommit("mygroup/myproject", gomock.Any(), gomock.Any()).
-			Return(&gl.Commit{ID: "def456"}, nil, nil)
+			DoAndReturn(func(_ any, opt *gl.CreateCommitOptions, _ ...gl.RequestOptionFunc) (*gl.Commit, *gl.Response, error) {
+				if *opt.Branch != "drift/update" || *opt.StartBranch != "main" || !*opt.Force {
+					t.Errorf("got branch %q from %q (force %v), want drift/update rebuilt from main", *opt.Branch, *opt.StartBranch, *opt.Force)
+				}
+				if len(opt.Actions) != 1 || *opt.Actions[0].Action != gl.FileUpdate {
+					t.Errorf("got actions %+v, want one update", opt.Actions)
+				}
+				return &gl.Commit{ID: "def456"}, nil, nil
+			})
 
-		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", dir, "")
+		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", "main", DriftFiles{Generated: []string{filepath.Join(dir, "groups.tf")}}, "", DefaultDriftMRTitle)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -287,7 +299,7 @@ func TestCommitDriftFiles(t *testing.T) {
 			GetRawFile("mygroup/myproject", "groups.tf", gomock.Any(), gomock.Any()).
 			Return(content, nil, nil)
 
-		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", dir, "")
+		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", "main", DriftFiles{Generated: []string{filepath.Join(dir, "groups.tf")}}, "", DefaultDriftMRTitle)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -315,7 +327,7 @@ func TestCommitDriftFiles(t *testing.T) {
 			CreateCommit("mygroup/myproject", gomock.Any(), gomock.Any()).
 			Return(&gl.Commit{ID: "ghi789"}, nil, nil)
 
-		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", dir, "terraform")
+		committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", "main", DriftFiles{Generated: []string{filepath.Join(dir, "groups.tf")}}, "terraform", DefaultDriftMRTitle)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -325,24 +337,221 @@ func TestCommitDriftFiles(t *testing.T) {
 	})
 }
 
+func TestCloseDriftMR(t *testing.T) {
+	tc := gitlabtesting.NewTestClient(t)
+	c := NewClientFromAPI(tc.Client, "mygroup")
+
+	gomock.InOrder(
+		tc.MockNotes.EXPECT().
+			CreateMergeRequestNote("mygroup/myproject", int64(7), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, _ int64, opt *gl.CreateMergeRequestNoteOptions, _ ...gl.RequestOptionFunc) (*gl.Note, *gl.Response, error) {
+				if *opt.Body != "no drift left" {
+					t.Errorf("got comment %q", *opt.Body)
+				}
+				return &gl.Note{}, nil, nil
+			}),
+		tc.MockMergeRequests.EXPECT().
+			UpdateMergeRequest("mygroup/myproject", int64(7), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, _ int64, opt *gl.UpdateMergeRequestOptions, _ ...gl.RequestOptionFunc) (*gl.MergeRequest, *gl.Response, error) {
+				if opt.StateEvent == nil || *opt.StateEvent != "close" {
+					t.Errorf("got state event %v, want close", opt.StateEvent)
+				}
+				return &gl.MergeRequest{}, nil, nil
+			}),
+	)
+
+	if err := c.CloseDriftMR(context.Background(), "mygroup/myproject", 7, "no drift left"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommitDriftFilesDeletesAndMoves(t *testing.T) {
+	tc := gitlabtesting.NewTestClient(t)
+	c := NewClientFromAPI(tc.Client, "mygroup")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "team_b_backend.tf"), []byte("resource {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	notFound := &gl.ErrorResponse{Response: &http.Response{StatusCode: http.StatusNotFound}}
+	tc.MockRepositoryFiles.EXPECT().
+		GetRawFile("mygroup/myproject", "terraform/team_b_backend.tf", gomock.Any(), gomock.Any()).
+		Return(nil, nil, notFound)
+	tc.MockRepositoryFiles.EXPECT().
+		GetRawFile("mygroup/myproject", "terraform/team_a_backend.tf", gomock.Any(), gomock.Any()).
+		Return([]byte("resource { old }"), nil, nil)
+	tc.MockRepositoryFiles.EXPECT().
+		GetRawFile("mygroup/myproject", "terraform/old_team.tf", gomock.Any(), gomock.Any()).
+		Return([]byte("resource {}"), nil, nil)
+	tc.MockRepositoryFiles.EXPECT().
+		GetRawFile("mygroup/myproject", "terraform/gone.tf", gomock.Any(), gomock.Any()).
+		Return(nil, nil, notFound)
+
+	tc.MockCommits.EXPECT().
+		CreateCommit("mygroup/myproject", gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, opt *gl.CreateCommitOptions, _ ...gl.RequestOptionFunc) (*gl.Commit, *gl.Response, error) {
+			if len(opt.Actions) != 2 {
+				t.Fatalf("got %d actions, want 2", len(opt.Actions))
+			}
+			move, del := opt.Actions[0], opt.Actions[1]
+			if *move.Action != gl.FileMove || *move.FilePath != "terraform/team_b_backend.tf" || *move.PreviousPath != "terraform/team_a_backend.tf" || *move.Content != "resource {}" {
+				t.Errorf("got %s %s from %v, want move of terraform/team_a_backend.tf", *move.Action, *move.FilePath, move.PreviousPath)
+			}
+			if *del.Action != gl.FileDelete || *del.FilePath != "terraform/old_team.tf" {
+				t.Errorf("got %s %s, want delete of terraform/old_team.tf", *del.Action, *del.FilePath)
+			}
+			return &gl.Commit{ID: "abc123"}, nil, nil
+		})
+
+	committed, err := c.CommitDriftFiles(context.Background(), "mygroup/myproject", "drift/update", "main", DriftFiles{
+		Generated: []string{filepath.Join(dir, "team_b_backend.tf")},
+		Delete:    []string{"old_team.tf", "gone.tf"},
+		Moves:     map[string]string{"team_b_backend.tf": "team_a_backend.tf"},
+	}, "terraform", DefaultDriftMRTitle)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !committed {
+		t.Error("expected commit to be created")
+	}
+}
+
 func TestCreateDriftMR(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		tc := gitlabtesting.NewTestClient(t)
+		c := NewClientFromAPI(tc.Client, "mygroup")
+
+		tc.MockMergeRequests.EXPECT().
+			CreateMergeRequest("mygroup/myproject", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, opt *gl.CreateMergeRequestOptions, _ ...gl.RequestOptionFunc) (*gl.MergeRequest, *gl.Response, error) {
+				if *opt.Title != DefaultDriftMRTitle {
+					t.Errorf("got title %q, want %q", *opt.Title, DefaultDriftMRTitle)
+				}
+				if *opt.Description != "## GitLab drift" {
+					t.Errorf("got description %q, want %q", *opt.Description, "## GitLab drift")
+				}
+				if opt.Labels != nil || opt.AssigneeIDs != nil || opt.ReviewerIDs != nil || opt.Squash != nil {
+					t.Errorf("unexpected options: %+v", opt)
+				}
+				return &gl.MergeRequest{
+					BasicMergeRequest: gl.BasicMergeRequest{
+						IID:    1,
+						WebURL: "https://gitlab.com/mygroup/myproject/-/merge_requests/1",
+					},
+				}, nil, nil
+			})
+
+		mr, err := c.CreateDriftMR(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main", DriftMROptions{
+			Description: "## GitLab drift",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mr.WebURL != "https://gitlab.com/mygroup/myproject/-/merge_requests/1" {
+			t.Errorf("got WebURL %q, want expected URL", mr.WebURL)
+		}
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		tc := gitlabtesting.NewTestClient(t)
+		c := NewClientFromAPI(tc.Client, "mygroup")
+
+		tc.MockMergeRequests.EXPECT().
+			CreateMergeRequest("mygroup/myproject", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, opt *gl.CreateMergeRequestOptions, _ ...gl.RequestOptionFunc) (*gl.MergeRequest, *gl.Response, error) {
+				if *opt.Title != "Draft: drift in mygroup" {
+					t.Errorf("got title %q, want draft title", *opt.Title)
+				}
+				if *opt.TargetBranch != "production" {
+					t.Errorf("got target branch %q, want production", *opt.TargetBranch)
+				}
+				if got := strings.Join(*opt.Labels, ","); got != "drift,terraform" {
+					t.Errorf("got labels %q, want drift,terraform", got)
+				}
+				if len(*opt.AssigneeIDs) != 1 || (*opt.AssigneeIDs)[0] != 5 {
+					t.Errorf("got assignees %v, want [5]", *opt.AssigneeIDs)
+				}
+				if len(*opt.ReviewerIDs) != 2 {
+					t.Errorf("got reviewers %v, want two", *opt.ReviewerIDs)
+				}
+				if opt.Squash == nil || !*opt.Squash {
+					t.Error("expected squash to be set")
+				}
+				return &gl.MergeRequest{}, nil, nil
+			})
+
+		_, err := c.CreateDriftMR(context.Background(), "mygroup/myproject", "drift/backtrack", "production", DriftMROptions{
+			Title:       "drift in mygroup",
+			Labels:      []string{"drift", "terraform"},
+			AssigneeIDs: []int64{5},
+			ReviewerIDs: []int64{6, 7},
+			Draft:       true,
+			Squash:      true,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestUpdateDriftMR(t *testing.T) {
 	tc := gitlabtesting.NewTestClient(t)
 	c := NewClientFromAPI(tc.Client, "mygroup")
 
 	tc.MockMergeRequests.EXPECT().
-		CreateMergeRequest("mygroup/myproject", gomock.Any(), gomock.Any()).
-		Return(&gl.MergeRequest{
-			BasicMergeRequest: gl.BasicMergeRequest{
-				IID:    1,
-				WebURL: "https://gitlab.com/mygroup/myproject/-/merge_requests/1",
-			},
-		}, nil, nil)
-
-	mr, err := c.CreateDriftMR(context.Background(), "mygroup/myproject", "drift/update-2025-01-01", "main")
+		UpdateMergeRequest("mygroup/myproject", int64(7), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, _ int64, opt *gl.UpdateMergeRequestOptions, _ ...gl.RequestOptionFunc) (*gl.MergeRequest, *gl.Response, error) {
+			if *opt.Description != "updated" {
+				t.Errorf("got description %q, want %q", *opt.Description, "updated")
+			}
+			if *opt.Title != DefaultDriftMRTitle {
+				t.Errorf("got title %q, want %q", *opt.Title, DefaultDriftMRTitle)
+			}
+			if got := strings.Join(*opt.AddLabels, ","); got != "drift" {
+				t.Errorf("got added labels %q, want drift", got)
+			}
+			if opt.Labels != nil || opt.AssigneeIDs != nil || opt.ReviewerIDs != nil || opt.TargetBranch != nil {
+				t.Error("labels, assignees, reviewers and target branch set by people must be kept")
+			}
+			return &gl.MergeRequest{BasicMergeRequest: gl.BasicMergeRequest{IID: 7}}, nil, nil
+		})
+
+	_, err := c.UpdateDriftMR(context.Background(), "mygroup/myproject", 7, DriftMROptions{
+		Description: "updated",
+		Labels:      []string{"drift"},
+		AssigneeIDs: []int64{5},
+	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if mr.WebURL != "https://gitlab.com/mygroup/myproject/-/merge_requests/1" {
-		t.Errorf("got WebURL %q, want expected URL", mr.WebURL)
+}
+
+func TestUserIDs(t *testing.T) {
+	tc := gitlabtesting.NewTestClient(t)
+	c := NewClientFromAPI(tc.Client, "mygroup")
+
+	tc.MockUsers.EXPECT().
+		ListUsers(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(opt *gl.ListUsersOptions, _ ...gl.RequestOptionFunc) ([]*gl.User, *gl.Response, error) {
+			switch *opt.Username {
+			case "alice":
+				return []*gl.User{{ID: 5, Username: "alice"}}, nil, nil
+			default:
+				return nil, nil, nil
+			}
+		}).
+		Times(2)
+
+	ids, err := c.UserIDs(context.Background(), []string{"@alice"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != 5 {
+		t.Errorf("got %v, want [5]", ids)
+	}
+
+	if _, err := c.UserIDs(context.Background(), []string{"nobody"}); err == nil {
+		t.Error("expected error for unknown user")
 	}
 }
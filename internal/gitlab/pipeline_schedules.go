@@ -10,12 +10,16 @@ import (
 
 type PipelineSchedules = map[int64][]*gl.PipelineSchedule
 
-func (c *Client) ListPipelineSchedules(ctx context.Context, projects []*gl.Project) (PipelineSchedules, error) {
-	result := make(PipelineSchedules, len(projects))
+// scheduleRef identifies a pipeline schedule whose details are fetched.
+type scheduleRef struct {
+	project *gl.Project
+	id      int64
+}
 
-	for _, p := range projects {
+func (c *Client) ListPipelineSchedules(ctx context.Context, projects []*gl.Project) (PipelineSchedules, error) {
+	schedules, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) ([]*gl.PipelineSchedule, error) {
 		if p == nil {
-			continue
+			return nil, nil
 		}
 		slog.Debug("fetching pipeline schedules", "project", p.PathWithNamespace)
 		opts := &gl.ListPipelineSchedulesOptions{
@@ -36,21 +40,36 @@ func (c *Client) ListPipelineSchedules(ctx context.Context, projects []*gl.Proje
 			}
 			opts.Page = resp.NextPage
 		}
+		return schedules, nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Fetch detail for each schedule to get variables.
-		var detailed []*gl.PipelineSchedule
-		for _, s := range schedules {
-			slog.Debug("fetching pipeline schedule detail", "project", p.PathWithNamespace, "schedule", s.ID)
-			d, _, err := c.api.PipelineSchedules.GetPipelineSchedule(p.ID, s.ID, gl.WithContext(ctx))
-			if err != nil {
-				return nil, fmt.Errorf("getting pipeline schedule %d for project %d: %w", s.ID, p.ID, err)
-			}
-			detailed = append(detailed, d)
+	// Fetch detail for each schedule to get variables. The schedules of all
+	// projects share one pool, so projects with many schedules do not
+	// serialize the scan.
+	var refs []scheduleRef
+	for i, p := range projects {
+		for _, s := range schedules[i] {
+			refs = append(refs, scheduleRef{project: p, id: s.ID})
 		}
-
-		if len(detailed) > 0 {
-			result[p.ID] = detailed
+	}
+	detailed, err := fetchEach(ctx, c, refs, func(ctx context.Context, ref scheduleRef) (*gl.PipelineSchedule, error) {
+		slog.Debug("fetching pipeline schedule detail", "project", ref.project.PathWithNamespace, "schedule", ref.id)
+		d, _, err := c.api.PipelineSchedules.GetPipelineSchedule(ref.project.ID, ref.id, gl.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("getting pipeline schedule %d for project %d: %w", ref.id, ref.project.ID, err)
 		}
+		return d, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(PipelineSchedules, len(projects))
+	for i, ref := range refs {
+		result[ref.project.ID] = append(result[ref.project.ID], detailed[i])
 	}
 	return result, nil
 }
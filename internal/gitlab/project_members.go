@@ -0,0 +1,52 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ProjectMembers maps project IDs to their direct members. Members inherited
+// from parent groups or added through group shares are not included.
+type ProjectMembers = map[int64][]*gl.ProjectMember
+
+func (c *Client) ListProjectMembers(ctx context.Context, projects []*gl.Project) (ProjectMembers, error) {
+	members, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) ([]*gl.ProjectMember, error) {
+		if p == nil {
+			return nil, nil
+		}
+		slog.Debug("fetching project members", "project", p.PathWithNamespace)
+		opts := &gl.ListProjectMembersOptions{
+			ListOptions: gl.ListOptions{
+				Page:    1,
+				PerPage: 100,
+			},
+		}
+		var members []*gl.ProjectMember
+		for {
+			page, resp, err := c.api.ProjectMembers.ListProjectMembers(p.ID, opts, gl.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("listing members for project %d: %w", p.ID, err)
+			}
+			members = append(members, page...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return members, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ProjectMembers, len(projects))
+	for i, p := range projects {
+		if len(members[i]) > 0 {
+			result[p.ID] = members[i]
+		}
+	}
+	return result, nil
+}
@@ -29,7 +29,7 @@ func (c *Client) ListProjects(ctx context.Context) ([]*gl.Project, error) {
 			}
 			opts.Page = resp.NextPage
 		}
-		return allProjects, nil
+		return c.filterProjects(allProjects), nil
 	}
 
 	opts := &gl.ListProjectsOptions{
@@ -49,5 +49,5 @@ func (c *Client) ListProjects(ctx context.Context) ([]*gl.Project, error) {
 		}
 		opts.Page = resp.NextPage
 	}
-	return allProjects, nil
+	return c.filterProjects(allProjects), nil
 }
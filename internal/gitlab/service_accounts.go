@@ -0,0 +1,58 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GroupServiceAccounts maps top-level group IDs to their service accounts.
+type GroupServiceAccounts = map[int64][]*gl.GroupServiceAccount
+
+// ListGroupServiceAccounts fetches service accounts for every top-level group.
+// Service accounts can only be created on top-level groups, so subgroups are
+// not queried.
+func (c *Client) ListGroupServiceAccounts(ctx context.Context, groups []*gl.Group) (GroupServiceAccounts, error) {
+	accounts, err := fetchEach(ctx, c, groups, func(ctx context.Context, g *gl.Group) ([]*gl.GroupServiceAccount, error) {
+		if g == nil || g.ParentID != 0 {
+			return nil, nil
+		}
+		slog.Debug("fetching group service accounts", "group", g.FullPath)
+		opts := &gl.ListServiceAccountsOptions{
+			ListOptions: gl.ListOptions{
+				Page:    1,
+				PerPage: 100,
+			},
+		}
+		var accounts []*gl.GroupServiceAccount
+		for {
+			page, resp, err := c.api.Groups.ListServiceAccounts(g.ID, opts, gl.WithContext(ctx))
+			if err != nil {
+				if isForbidden(err) {
+					slog.Warn("group service accounts require Premium/Ultimate, skipping", "group", g.FullPath)
+					break
+				}
+				return nil, fmt.Errorf("listing service accounts for group %d: %w", g.ID, err)
+			}
+			accounts = append(accounts, page...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return accounts, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(GroupServiceAccounts)
+	for i, g := range groups {
+		if len(accounts[i]) > 0 {
+			result[g.ID] = accounts[i]
+		}
+	}
+	return result, nil
+}
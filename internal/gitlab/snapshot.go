@@ -0,0 +1,105 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SnapshotVersion is the format version written by WriteSnapshot.
+// ReadSnapshot rejects other versions, as the meaning of fields may differ.
+const SnapshotVersion = 1
+
+// redacted replaces secret values in redacted snapshots.
+const redacted = "REDACTED"
+
+// Snapshot is the state of a GitLab group as fetched by FetchAll, stored so
+// scans can run without API access.
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	GitLabURL string    `json:"gitlab_url"`
+	Group     string    `json:"group"`
+	// Skipped lists the resource types that were not fetched.
+	Skipped []string `json:"skipped,omitempty"`
+	// Include and Exclude are the path filters the resources were fetched
+	// with.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// Redacted is set when secret values were removed.
+	Redacted  bool       `json:"redacted,omitempty"`
+	Resources *Resources `json:"resources"`
+}
+
+// WriteSnapshot writes s as indented JSON, setting its version.
+func WriteSnapshot(w io.Writer, s *Snapshot) error {
+	s.Version = SnapshotVersion
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ReadSnapshot parses a snapshot written by WriteSnapshot.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	if s.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected %d", s.Version, SnapshotVersion)
+	}
+	if s.Resources == nil {
+		return nil, fmt.Errorf("snapshot has no resources")
+	}
+	return &s, nil
+}
+
+// Redact replaces CI/CD variable values, pipeline schedule variable values,
+// runner registration tokens and webhook custom header values, so the
+// snapshot can be shared. Drift of these values is meaningless afterwards.
+func (s *Snapshot) Redact() {
+	s.Redacted = true
+	r := s.Resources
+	for _, g := range r.Groups {
+		if g.RunnersToken != "" {
+			g.RunnersToken = redacted
+		}
+	}
+	for _, p := range r.Projects {
+		if p.RunnersToken != "" {
+			p.RunnersToken = redacted
+		}
+	}
+	for _, vars := range r.GroupVariables {
+		for _, v := range vars {
+			v.Value = redacted
+		}
+	}
+	for _, vars := range r.ProjectVariables {
+		for _, v := range vars {
+			v.Value = redacted
+		}
+	}
+	for _, schedules := range r.PipelineSchedules {
+		for _, s := range schedules {
+			for _, v := range s.Variables {
+				v.Value = redacted
+			}
+		}
+	}
+	for _, hooks := range r.GroupHooks {
+		for _, h := range hooks {
+			for _, header := range h.CustomHeaders {
+				header.Value = redacted
+			}
+		}
+	}
+	for _, hooks := range r.ProjectHooks {
+		for _, h := range hooks {
+			for _, header := range h.CustomHeaders {
+				header.Value = redacted
+			}
+		}
+	}
+}
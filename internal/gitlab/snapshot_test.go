@@ -0,0 +1,136 @@
+package gitlab
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func snapshotResources() *Resources {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &Resources{
+		Groups: []*gl.Group{
+			{ID: 1, FullPath: "mygroup", Name: "mygroup", Visibility: gl.PrivateVisibility, RunnersToken: "group-token"},
+		},
+		Projects: []*gl.Project{
+			{ID: 10, PathWithNamespace: "mygroup/api", Name: "api", CreatedAt: &created, RunnersToken: "project-token",
+				SharedWithGroups: []gl.ProjectSharedWithGroup{{GroupID: 2, GroupFullPath: "other", GroupAccessLevel: 30}}},
+		},
+		GroupMembers: GroupMembers{
+			1: {{ID: 100, Username: "alice", AccessLevel: gl.MaintainerPermissions}},
+		},
+		ProjectLabels: ProjectLabels{
+			10: {{ID: 5, Name: "bug", Color: "#ff0000"}},
+		},
+		ProjectVariables: ProjectVariables{
+			10: {{Key: "SECRET", Value: "s3cret", Masked: true, EnvironmentScope: "*"}},
+		},
+		PipelineSchedules: PipelineSchedules{
+			10: {{ID: 7, Description: "nightly", Cron: "0 1 * * *", Ref: "main",
+				Variables: []*gl.PipelineVariable{{Key: "MODE", Value: "full"}}}},
+		},
+		ProjectHooks: ProjectHooks{
+			10: {{ID: 3, URL: "https://example.com/hook", PushEvents: true,
+				CustomHeaders: []*gl.HookCustomHeader{{Key: "X-Token", Value: "abc"}}}},
+		},
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	want := &Snapshot{
+		CreatedAt: time.Date(2024, 3, 2, 8, 30, 0, 0, time.UTC),
+		GitLabURL: "https://gitlab.example.com",
+		Group:     "mygroup",
+		Skipped:   []string{"hooks"},
+		Exclude:   []string{"mygroup/sandbox"},
+		Resources: snapshotResources(),
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, want); err != nil {
+		t.Fatalf("WriteSnapshot error: %v", err)
+	}
+	got, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("ReadSnapshot error: %v", err)
+	}
+
+	if got.Version != SnapshotVersion {
+		t.Errorf("Version = %d, want %d", got.Version, SnapshotVersion)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestReadSnapshotRejectsOtherVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "newer version", input: `{"version": 2, "resources": {}}`, wantErr: "unsupported snapshot version 2"},
+		{name: "no version", input: `{"resources": {}}`, wantErr: "unsupported snapshot version 0"},
+		{name: "no resources", input: `{"version": 1}`, wantErr: "no resources"},
+		{name: "not json", input: `groups: []`, wantErr: "decoding snapshot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadSnapshot(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadSnapshot error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnapshotRedact(t *testing.T) {
+	s := &Snapshot{Resources: snapshotResources()}
+	s.Redact()
+
+	r := s.Resources
+	if !s.Redacted {
+		t.Error("Redacted not set")
+	}
+	for _, got := range []string{
+		r.Groups[0].RunnersToken,
+		r.Projects[0].RunnersToken,
+		r.ProjectVariables[10][0].Value,
+		r.PipelineSchedules[10][0].Variables[0].Value,
+		r.ProjectHooks[10][0].CustomHeaders[0].Value,
+	} {
+		if got != redacted {
+			t.Errorf("value %q not redacted", got)
+		}
+	}
+	if r.ProjectVariables[10][0].Key != "SECRET" || r.ProjectHooks[10][0].CustomHeaders[0].Key != "X-Token" {
+		t.Error("keys must be kept")
+	}
+}
+
+func TestResourcesSelectPaths(t *testing.T) {
+	r := &Resources{
+		Groups: []*gl.Group{
+			{ID: 1, FullPath: "mygroup"},
+			{ID: 2, FullPath: "mygroup/team-a"},
+			{ID: 3, FullPath: "mygroup/team-a/sandbox"},
+		},
+		Projects: []*gl.Project{
+			{ID: 10, PathWithNamespace: "mygroup/team-a/api"},
+			{ID: 11, PathWithNamespace: "mygroup/team-a/sandbox/test"},
+			{ID: 12, PathWithNamespace: "mygroup/ops/infra"},
+		},
+	}
+	r.SelectPaths([]string{"mygroup/team-a"}, []string{"mygroup/team-a/sandbox"})
+
+	if len(r.Groups) != 1 || r.Groups[0].ID != 2 {
+		t.Errorf("groups = %v, want only mygroup/team-a", r.Groups)
+	}
+	if len(r.Projects) != 1 || r.Projects[0].ID != 10 {
+		t.Errorf("projects = %v, want only mygroup/team-a/api", r.Projects)
+	}
+}
@@ -0,0 +1,175 @@
+package gitlab
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultMaxRetries is the number of retries used by NewClient unless
+// configured otherwise.
+const DefaultMaxRetries = 5
+
+// RetryOptions configures retries and client-side rate limiting of API
+// requests.
+type RetryOptions struct {
+	// MaxRetries is how often a throttled or failed request is retried.
+	MaxRetries int
+	// RequestsPerSecond limits the request rate; 0 means no limit.
+	RequestsPerSecond float64
+}
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 30 * time.Second
+	// retryMaxWait caps the wait a Retry-After or RateLimit-Reset header
+	// asks for, so a skewed clock or a bogus header cannot stall a scan.
+	// GitLab's rate limits reset within a minute.
+	retryMaxWait = 2 * time.Minute
+)
+
+// retryTransport retries requests that were throttled (429), failed with a
+// transient 5xx or a network error. Throttled requests wait as long as the
+// Retry-After or RateLimit-Reset header asks, everything else backs off
+// exponentially with jitter. Network errors and 5xx responses are only
+// retried for idempotent methods, since the request may have been applied.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	limiter    *rate.Limiter
+	// sleep waits for d or until ctx is done; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRetryTransport(next http.RoundTripper, opts RetryOptions) *retryTransport {
+	t := &retryTransport{next: next, maxRetries: opts.MaxRetries, sleep: sleepContext}
+	if opts.RequestsPerSecond > 0 {
+		t.limiter = rate.NewLimiter(rate.Limit(opts.RequestsPerSecond), max(1, int(opts.RequestsPerSecond)))
+	}
+	return t
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.maxRetries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		delay := retryDelay(resp, attempt)
+		if err != nil {
+			slog.Debug("GitLab request failed, retrying", "method", req.Method, "url", req.URL.Redacted(), "error", err, "attempt", attempt+1, "wait", delay)
+		} else {
+			slog.Debug("GitLab request throttled, retrying", "method", req.Method, "url", req.URL.Redacted(), "status", resp.StatusCode, "attempt", attempt+1, "wait", delay)
+			// Drain so the connection can be reused.
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+
+		if req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait blocks until the client-side rate limit allows another request.
+func (t *retryTransport) wait(ctx context.Context) error {
+	if t.limiter == nil {
+		return nil
+	}
+	r := t.limiter.Reserve()
+	delay := r.Delay()
+	if delay == 0 {
+		return nil
+	}
+	slog.Debug("client-side rate limit reached, waiting", "wait", delay)
+	if err := t.sleep(ctx, delay); err != nil {
+		r.Cancel()
+		return err
+	}
+	return nil
+}
+
+// retryable reports whether a request should be tried again.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// retryDelay returns how long to wait before the next attempt. Retry-After
+// and RateLimit-Reset take precedence over exponential backoff and are
+// capped at retryMaxWait.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil {
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+				return min(time.Duration(secs)*time.Second, retryMaxWait)
+			}
+			if at, err := http.ParseTime(v); err == nil {
+				return min(max(0, time.Until(at)), retryMaxWait)
+			}
+		}
+		if v := resp.Header.Get("RateLimit-Reset"); v != "" {
+			if reset, err := strconv.ParseInt(v, 10, 64); err == nil && reset > 0 {
+				return min(max(0, time.Until(time.Unix(reset, 0))), retryMaxWait)
+			}
+		}
+	}
+
+	d := retryMaxDelay
+	if attempt < 16 {
+		d = min(retryBaseDelay<<attempt, retryMaxDelay)
+	}
+	// Jitter within the upper half keeps parallel workers from retrying in
+	// lockstep.
+	return d/2 + rand.N(d/2+1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,213 @@
+package gitlab
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestTransport returns a retryTransport that records its waits instead
+// of sleeping.
+func newTestTransport(opts RetryOptions) (*retryTransport, *[]time.Duration) {
+	var waits []time.Duration
+	t := newRetryTransport(http.DefaultTransport, opts)
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return t, &waits
+}
+
+func TestRetryTransportHonorsRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	tr, waits := newTestTransport(RetryOptions{MaxRetries: 3})
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+	if len(*waits) != 1 || (*waits)[0] != 7*time.Second {
+		t.Errorf("waits = %v, want [7s]", *waits)
+	}
+}
+
+func TestRetryTransportGivesUpAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	tr, waits := newTestTransport(RetryOptions{MaxRetries: 2})
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get error: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+	for i, w := range *waits {
+		if d := retryBaseDelay << i; w < d/2 || w > d {
+			t.Errorf("wait %d = %v, want between %v and %v", i, w, d/2, d)
+		}
+	}
+}
+
+func TestRetryTransportRetriesPostOnlyWhenThrottled(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+	}{
+		{name: "server error", status: http.StatusInternalServerError, wantCalls: 1},
+		{name: "throttled", status: http.StatusTooManyRequests, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			var bodies []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				bodies = append(bodies, string(b))
+				if calls.Add(1) == 1 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer srv.Close()
+
+			tr, _ := newTestTransport(RetryOptions{MaxRetries: 3})
+			resp, err := (&http.Client{Transport: tr}).Post(srv.URL, "text/plain", strings.NewReader("payload"))
+			if err != nil {
+				t.Fatalf("Post error: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls.Load(), tt.wantCalls)
+			}
+			for i, b := range bodies {
+				if b != "payload" {
+					t.Errorf("body of attempt %d = %q, want %q", i+1, b, "payload")
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportStopsOnCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := newRetryTransport(http.DefaultTransport, RetryOptions{MaxRetries: 5})
+	tr.sleep = func(context.Context, time.Duration) error {
+		cancel()
+		return context.Canceled
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err == nil {
+		_ = resp.Body.Close()
+		t.Fatal("expected error after cancel")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	header := func(kv ...string) *http.Response {
+		resp := &http.Response{Header: http.Header{}}
+		for i := 0; i+1 < len(kv); i += 2 {
+			resp.Header.Set(kv[i], kv[i+1])
+		}
+		return resp
+	}
+
+	if got := retryDelay(header("Retry-After", "3"), 0); got != 3*time.Second {
+		t.Errorf("Retry-After seconds: got %v, want 3s", got)
+	}
+
+	at := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if got := retryDelay(header("Retry-After", at), 0); got < 8*time.Second || got > 10*time.Second {
+		t.Errorf("Retry-After date: got %v, want about 10s", got)
+	}
+
+	reset := time.Now().Add(20 * time.Second).Unix()
+	if got := retryDelay(header("RateLimit-Reset", strconv.FormatInt(reset, 10)), 0); got < 18*time.Second || got > 20*time.Second {
+		t.Errorf("RateLimit-Reset: got %v, want about 20s", got)
+	}
+
+	if got := retryDelay(header("Retry-After", "86400"), 0); got != retryMaxWait {
+		t.Errorf("large Retry-After: got %v, want %v", got, retryMaxWait)
+	}
+
+	far := time.Now().Add(6 * time.Hour)
+	if got := retryDelay(header("Retry-After", far.UTC().Format(http.TimeFormat)), 0); got != retryMaxWait {
+		t.Errorf("far Retry-After date: got %v, want %v", got, retryMaxWait)
+	}
+	if got := retryDelay(header("RateLimit-Reset", strconv.FormatInt(far.Unix(), 10)), 0); got != retryMaxWait {
+		t.Errorf("far RateLimit-Reset: got %v, want %v", got, retryMaxWait)
+	}
+
+	if got := retryDelay(header("Retry-After", "soon"), 1); got < 500*time.Millisecond || got > time.Second {
+		t.Errorf("invalid Retry-After: got %v, want backoff between 500ms and 1s", got)
+	}
+
+	if got := retryDelay(nil, 100); got < retryMaxDelay/2 || got > retryMaxDelay {
+		t.Errorf("capped backoff: got %v, want between %v and %v", got, retryMaxDelay/2, retryMaxDelay)
+	}
+}
+
+func TestRetryTransportRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	tr, waits := newTestTransport(RetryOptions{RequestsPerSecond: 2})
+	client := &http.Client{Transport: tr}
+	for range 4 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Get error: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	// The burst of 2 passes, the next requests have to wait.
+	if len(*waits) < 1 {
+		t.Errorf("waits = %v, want the limiter to delay requests", *waits)
+	}
+}
@@ -0,0 +1,93 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GroupVariables maps group IDs to their CI/CD variables.
+type GroupVariables = map[int64][]*gl.GroupVariable
+
+// ProjectVariables maps project IDs to their CI/CD variables.
+type ProjectVariables = map[int64][]*gl.ProjectVariable
+
+func (c *Client) ListGroupVariables(ctx context.Context, groups []*gl.Group) (GroupVariables, error) {
+	variables, err := fetchEach(ctx, c, groups, func(ctx context.Context, g *gl.Group) ([]*gl.GroupVariable, error) {
+		if g == nil {
+			return nil, nil
+		}
+		slog.Debug("fetching group variables", "group", g.FullPath)
+		opts := &gl.ListGroupVariablesOptions{
+			ListOptions: gl.ListOptions{
+				Page:    1,
+				PerPage: 100,
+			},
+		}
+		var variables []*gl.GroupVariable
+		for {
+			page, resp, err := c.api.GroupVariables.ListVariables(g.ID, opts, gl.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("listing variables for group %d: %w", g.ID, err)
+			}
+			variables = append(variables, page...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return variables, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(GroupVariables, len(groups))
+	for i, g := range groups {
+		if len(variables[i]) > 0 {
+			result[g.ID] = variables[i]
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) ListProjectVariables(ctx context.Context, projects []*gl.Project) (ProjectVariables, error) {
+	variables, err := fetchEach(ctx, c, projects, func(ctx context.Context, p *gl.Project) ([]*gl.ProjectVariable, error) {
+		if p == nil {
+			return nil, nil
+		}
+		slog.Debug("fetching project variables", "project", p.PathWithNamespace)
+		opts := &gl.ListProjectVariablesOptions{
+			ListOptions: gl.ListOptions{
+				Page:    1,
+				PerPage: 100,
+			},
+		}
+		var variables []*gl.ProjectVariable
+		for {
+			page, resp, err := c.api.ProjectVariables.ListVariables(p.ID, opts, gl.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("listing variables for project %d: %w", p.ID, err)
+			}
+			variables = append(variables, page...)
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+		return variables, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ProjectVariables, len(projects))
+	for i, p := range projects {
+		if len(variables[i]) > 0 {
+			result[p.ID] = variables[i]
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,145 @@
+package ignore
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Rule leaves GitLab objects out of drift detection. Path is a glob on the
+// full path of the group or project an object belongs to and Type a glob on
+// its terraform resource type; empty fields match everything. Without
+// Attributes the whole object is ignored, otherwise only those attributes.
+type Rule struct {
+	Path       string   `yaml:"path"`
+	Type       string   `yaml:"type"`
+	Attributes []string `yaml:"attributes"`
+}
+
+// Rules is a list of ignore rules.
+type Rules []Rule
+
+// Validate reports malformed glob patterns.
+func (r Rule) Validate() error {
+	if _, err := path.Match(r.Path, ""); err != nil {
+		return fmt.Errorf("invalid path pattern %q: %w", r.Path, err)
+	}
+	if _, err := path.Match(r.Type, ""); err != nil {
+		return fmt.Errorf("invalid type pattern %q: %w", r.Type, err)
+	}
+	return nil
+}
+
+func (r Rule) matches(resourceType, fullPath string) bool {
+	if r.Type != "" {
+		if ok, _ := path.Match(r.Type, resourceType); !ok {
+			return false
+		}
+	}
+	return r.Path == "" || MatchPath(r.Path, fullPath)
+}
+
+// Ignored reports whether the object of the given type at fullPath is ignored
+// as a whole.
+func (rs Rules) Ignored(resourceType, fullPath string) bool {
+	for _, r := range rs {
+		if len(r.Attributes) == 0 && r.matches(resourceType, fullPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoredAttribute reports whether an attribute of the object of the given
+// type at fullPath is ignored.
+func (rs Rules) IgnoredAttribute(resourceType, fullPath, attribute string) bool {
+	for _, r := range rs {
+		if slices.Contains(r.Attributes, attribute) && r.matches(resourceType, fullPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPath reports whether fullPath or one of its parent namespaces matches
+// the glob pattern, so a pattern for a group covers everything inside it.
+func MatchPath(pattern, fullPath string) bool {
+	if fullPath == "" {
+		return false
+	}
+	for p := fullPath; ; {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			return false
+		}
+		p = p[:i]
+	}
+}
+
+// Filter combines ignore rules with --include and --exclude path patterns.
+type Filter struct {
+	Rules Rules
+	// Include limits the scan to these group or project path patterns.
+	Include []string
+	// Exclude leaves these group or project path patterns out of the scan.
+	Exclude []string
+}
+
+// Empty reports whether the filter ignores nothing.
+func (f Filter) Empty() bool {
+	return len(f.Rules) == 0 && len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Selected reports whether the group or project at fullPath passes the
+// include and exclude patterns. Exclude takes precedence. The parents of an
+// included path are not selected, only what is below it.
+func (f Filter) Selected(fullPath string) bool {
+	for _, pattern := range f.Exclude {
+		if MatchPath(pattern, fullPath) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if MatchPath(pattern, fullPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ignored reports whether the object of the given type at fullPath is ignored
+// by a rule or not selected. Objects of unknown path are only subject to the
+// rules.
+func (f Filter) Ignored(resourceType, fullPath string) bool {
+	if fullPath != "" && !f.Selected(fullPath) {
+		return true
+	}
+	return f.Rules.Ignored(resourceType, fullPath)
+}
+
+// IgnoredAttribute reports whether an attribute of the object of the given
+// type at fullPath is ignored.
+func (f Filter) IgnoredAttribute(resourceType, fullPath, attribute string) bool {
+	return f.Rules.IgnoredAttribute(resourceType, fullPath, attribute)
+}
+
+// ParseAttributes turns --ignore-attributes values, either `attribute` or
+// `resource_type.attribute`, into rules.
+func ParseAttributes(values []string) Rules {
+	var rules Rules
+	for _, v := range values {
+		if resourceType, attribute, ok := strings.Cut(v, "."); ok {
+			rules = append(rules, Rule{Type: resourceType, Attributes: []string{attribute}})
+		} else {
+			rules = append(rules, Rule{Attributes: []string{v}})
+		}
+	}
+	return rules
+}
@@ -0,0 +1,130 @@
+package ignore
+
+import "testing"
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"my-group/sandbox", "my-group/sandbox", true},
+		{"my-group/sandbox", "my-group/sandbox/project", true},
+		{"my-group/sandbox", "my-group/sandbox-2", false},
+		{"my-group/*", "my-group/api", true},
+		{"my-group/*", "my-group", false},
+		{"*/forks", "my-group/forks/a/b", true},
+		{"my-group", "", false},
+	}
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRulesIgnored(t *testing.T) {
+	rules := Rules{
+		{Path: "my-group/sandbox"},
+		{Type: "gitlab_project_hook", Path: "my-group/legacy"},
+		{Type: "gitlab_project", Attributes: []string{"description"}},
+	}
+
+	if !rules.Ignored("gitlab_project", "my-group/sandbox/test") {
+		t.Error("expected project in sandbox to be ignored")
+	}
+	if !rules.Ignored("gitlab_project_hook", "my-group/legacy/app") {
+		t.Error("expected legacy hook to be ignored")
+	}
+	if rules.Ignored("gitlab_project", "my-group/legacy/app") {
+		t.Error("legacy project should only have its hooks ignored")
+	}
+	if rules.Ignored("gitlab_project", "my-group/api") {
+		t.Error("attribute rule should not ignore the whole project")
+	}
+}
+
+func TestRulesIgnoredAttribute(t *testing.T) {
+	rules := Rules{
+		{Type: "gitlab_project", Attributes: []string{"description"}},
+		{Type: "gitlab_group*", Path: "my-group/team-*", Attributes: []string{"visibility_level"}},
+	}
+
+	if !rules.IgnoredAttribute("gitlab_project", "my-group/api", "description") {
+		t.Error("expected project description to be ignored")
+	}
+	if rules.IgnoredAttribute("gitlab_project", "my-group/api", "name") {
+		t.Error("project name should not be ignored")
+	}
+	if !rules.IgnoredAttribute("gitlab_group", "my-group/team-a", "visibility_level") {
+		t.Error("expected team group visibility to be ignored")
+	}
+	if rules.IgnoredAttribute("gitlab_group", "my-group/ops", "visibility_level") {
+		t.Error("ops group visibility should not be ignored")
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	if err := (Rule{Path: "my-group/*", Type: "gitlab_*"}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (Rule{Path: "my-group/["}).Validate(); err == nil {
+		t.Error("expected error for malformed path pattern")
+	}
+}
+
+func TestFilterSelected(t *testing.T) {
+	f := Filter{
+		Include: []string{"my-group/team-*"},
+		Exclude: []string{"my-group/team-a/sandbox"},
+	}
+
+	tests := map[string]bool{
+		"my-group":                      false,
+		"my-group/team-a":               true,
+		"my-group/team-a/api":           true,
+		"my-group/team-a/sandbox":       false,
+		"my-group/team-a/sandbox/test":  false,
+		"my-group/ops/infra":            false,
+		"my-group/team-b/sandbox/notes": true,
+	}
+	for p, want := range tests {
+		if got := f.Selected(p); got != want {
+			t.Errorf("Selected(%q) = %v, want %v", p, got, want)
+		}
+	}
+
+	if !(Filter{}).Selected("anything") {
+		t.Error("empty filter should select everything")
+	}
+}
+
+func TestFilterIgnored(t *testing.T) {
+	f := Filter{
+		Rules:   Rules{{Type: "gitlab_project_hook"}},
+		Exclude: []string{"my-group/sandbox"},
+	}
+
+	if !f.Ignored("gitlab_project", "my-group/sandbox/test") {
+		t.Error("expected excluded project to be ignored")
+	}
+	if !f.Ignored("gitlab_project_hook", "") {
+		t.Error("expected hook rule to apply to unknown paths")
+	}
+	if f.Ignored("gitlab_project", "") {
+		t.Error("unknown paths should not be excluded")
+	}
+}
+
+func TestParseAttributes(t *testing.T) {
+	rules := ParseAttributes([]string{"description", "gitlab_project.topics"})
+
+	if !rules.IgnoredAttribute("gitlab_group", "my-group", "description") {
+		t.Error("expected description to be ignored for all types")
+	}
+	if !rules.IgnoredAttribute("gitlab_project", "my-group/api", "topics") {
+		t.Error("expected project topics to be ignored")
+	}
+	if rules.IgnoredAttribute("gitlab_group", "my-group", "topics") {
+		t.Error("topics should only be ignored for projects")
+	}
+}
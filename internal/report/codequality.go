@@ -0,0 +1,54 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// codeQualityIssue is an entry of a GitLab Code Quality report.
+// See https://docs.gitlab.com/ci/testing/code_quality/#code-quality-report-format
+type codeQualityIssue struct {
+	Description string              `json:"description"`
+	CheckName   string              `json:"check_name"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeQualityLocation `json:"location"`
+}
+
+type codeQualityLocation struct {
+	Path  string           `json:"path"`
+	Lines codeQualityLines `json:"lines"`
+}
+
+type codeQualityLines struct {
+	Begin int `json:"begin"`
+}
+
+// codeQualitySeverity ranks orphans highest since they break the next apply.
+var codeQualitySeverity = map[string]string{
+	ruleUnmanaged: "major",
+	ruleOrphaned:  "critical",
+	ruleChanged:   "minor",
+}
+
+// WriteCodeQuality writes the findings of a report in the GitLab Code
+// Quality format, shown in the merge request widget.
+func WriteCodeQuality(w io.Writer, r *Report) error {
+	issues := []codeQualityIssue{}
+	for _, f := range findings(r) {
+		issues = append(issues, codeQualityIssue{
+			Description: f.message,
+			CheckName:   f.rule,
+			Fingerprint: f.fingerprint,
+			Severity:    codeQualitySeverity[f.rule],
+			Location: codeQualityLocation{
+				Path:  f.path,
+				Lines: codeQualityLines{Begin: f.line},
+			},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
@@ -0,0 +1,92 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/terraform"
+)
+
+func TestWriteCodeQuality(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "codequality", Build(testInput())); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	var issues []codeQualityIssue
+	if err := json.Unmarshal(buf.Bytes(), &issues); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	want := []struct {
+		checkName string
+		severity  string
+		path      string
+		line      int
+	}{
+		{ruleUnmanaged, "major", "terraform/my_group.tf", 1},
+		{ruleOrphaned, "critical", "terraform/my_group.tf", 21},
+		{ruleChanged, "minor", "terraform/my_group.tf", 1},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("got %d issues, want %d:\n%s", len(issues), len(want), buf.String())
+	}
+	for i, w := range want {
+		got := issues[i]
+		if got.CheckName != w.checkName || got.Severity != w.severity ||
+			got.Location.Path != w.path || got.Location.Lines.Begin != w.line {
+			t.Errorf("issue %d = %+v, want %s/%s at %s:%d", i, got, w.checkName, w.severity, w.path, w.line)
+		}
+		if got.Fingerprint == "" || got.Description == "" {
+			t.Errorf("issue %d has empty fingerprint or description: %+v", i, got)
+		}
+	}
+}
+
+func TestFindingsSkipAddedBlocksOfUnmanagedResources(t *testing.T) {
+	in := testInput()
+	in.Changes = append(in.Changes,
+		terraform.BlockChange{Address: "gitlab_project.my_group_web", File: "my_group.tf", Line: 11, Kind: terraform.ChangeAdded},
+		terraform.BlockChange{Address: "import.gitlab_project.my_group_web", File: "imports.tf", Line: 1, Kind: terraform.ChangeAdded},
+	)
+
+	var rules []string
+	for _, f := range findings(Build(in)) {
+		rules = append(rules, f.rule+" "+f.address)
+	}
+	want := []string{
+		ruleUnmanaged + " gitlab_project.my_group_web",
+		ruleOrphaned + " gitlab_project.deleted",
+		ruleChanged + " gitlab_group.my_group",
+	}
+	if !slices.Equal(rules, want) {
+		t.Errorf("findings = %v, want %v", rules, want)
+	}
+}
+
+func TestWriteCodeQualityEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCodeQuality(&buf, Build(Input{})); err != nil {
+		t.Fatalf("WriteCodeQuality error: %v", err)
+	}
+	if got := bytes.TrimSpace(buf.Bytes()); string(got) != "[]" {
+		t.Errorf("got %s, want []", got)
+	}
+}
+
+func TestFingerprintStable(t *testing.T) {
+	first := findings(Build(testInput()))
+
+	in := testInput()
+	in.Locations["gitlab_project.my_group_web"] = testInput().Locations["gitlab_group.my_group"]
+	second := findings(Build(in))
+
+	if first[0].fingerprint != second[0].fingerprint {
+		t.Error("fingerprint changed when only the line moved")
+	}
+	if first[0].fingerprint == first[1].fingerprint {
+		t.Error("different findings share a fingerprint")
+	}
+}
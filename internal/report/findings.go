@@ -0,0 +1,99 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Rule IDs shared by the Code Quality and SARIF writers.
+const (
+	ruleUnmanaged = "unmanaged-resource"
+	ruleOrphaned  = "orphaned-resource"
+	ruleChanged   = "configuration-drift"
+)
+
+// ruleDescriptions are the short descriptions of the rules above.
+var ruleDescriptions = map[string]string{
+	ruleUnmanaged: "GitLab object is not managed by Terraform",
+	ruleOrphaned:  "Terraform declares a GitLab object that no longer exists",
+	ruleChanged:   "Terraform configuration differs from GitLab",
+}
+
+// finding is a single drift finding pointing at a .tf file and line.
+type finding struct {
+	rule        string
+	address     string
+	path        string
+	line        int
+	message     string
+	fingerprint string
+}
+
+// findings flattens the unmanaged resources, orphans and changed blocks of
+// a report into findings, in that order. Blocks added only for unmanaged
+// resources, and their import blocks, are left to the unmanaged findings.
+func findings(r *Report) []finding {
+	var result []finding
+	unmanaged := make(map[string]bool)
+	for _, res := range r.Resources {
+		if res.Managed {
+			continue
+		}
+		unmanaged[resourceAddress(res.Address)] = true
+		result = append(result, newFinding(r, ruleUnmanaged, res.Address, res.File, res.Line,
+			fmt.Sprintf("%s exists in GitLab but is not managed by Terraform (import ID %s)", res.Address, res.ImportID)))
+	}
+	for _, o := range r.Orphans {
+		result = append(result, newFinding(r, ruleOrphaned, o.Address, o.File, o.Line,
+			fmt.Sprintf("%s is declared in Terraform but no longer exists in GitLab", o.Address)))
+	}
+	for _, c := range r.Changes {
+		if c.Kind == "added" && unmanaged[resourceAddress(strings.TrimPrefix(c.Address, "import."))] {
+			continue
+		}
+		var message string
+		switch c.Kind {
+		case "added":
+			message = fmt.Sprintf("%s is missing from the Terraform configuration", c.Address)
+		case "removed":
+			message = fmt.Sprintf("%s is not generated from GitLab anymore", c.Address)
+		default:
+			message = fmt.Sprintf("%s differs from GitLab in %d attribute(s)", c.Address, len(c.Attributes))
+		}
+		result = append(result, newFinding(r, ruleChanged, c.Address, c.File, c.Line, message))
+	}
+	return result
+}
+
+func newFinding(r *Report, rule, address, file string, line int, message string) finding {
+	if line < 1 {
+		line = 1
+	}
+	return finding{
+		rule:        rule,
+		address:     address,
+		path:        findingPath(r.TerraformDir, file),
+		line:        line,
+		message:     message,
+		fingerprint: fingerprint(rule, address),
+	}
+}
+
+// findingPath returns the slash-separated path of file within dir.
+func findingPath(dir, file string) string {
+	if dir == "" {
+		dir = "."
+	}
+	return path.Clean(filepath.ToSlash(filepath.Join(dir, file)))
+}
+
+// fingerprint derives a stable ID from the rule and resource address, so a
+// finding keeps its identity across scans while the resource is drifted.
+func fingerprint(rule, address string) string {
+	sum := sha256.Sum256([]byte(rule + ":" + address))
+	return hex.EncodeToString(sum[:])
+}
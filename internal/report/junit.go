@@ -0,0 +1,171 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML with one test suite per resource
+// type and one test case per GitLab object. A case fails when its object is
+// unmanaged or drifted; orphaned declarations and variable map entries that
+// differ without belonging to a generated object are added as failing cases
+// too.
+func WriteJUnit(w io.Writer, r *Report) error {
+	suites := make(map[string]*junitTestSuite)
+	add := func(typ string, tc junitTestCase) {
+		s, ok := suites[typ]
+		if !ok {
+			s = &junitTestSuite{Name: typ}
+			suites[typ] = s
+		}
+		s.Tests++
+		if tc.Failure != nil {
+			s.Failures++
+		}
+		s.Cases = append(s.Cases, tc)
+	}
+
+	attached := make(map[string]bool)
+	for _, res := range r.Resources {
+		for _, a := range res.Changes {
+			attached[a.Path] = true
+		}
+		tc := junitTestCase{Name: res.Address, ClassName: res.Type}
+		if res.File != "" {
+			tc.File = findingPath(r.TerraformDir, res.File)
+		}
+		switch {
+		case !res.Managed:
+			tc.Failure = &junitFailure{
+				Message: "not managed by Terraform",
+				Type:    ruleUnmanaged,
+				Text:    fmt.Sprintf("terraform import '%s' '%s'", res.Address, res.ImportID),
+			}
+		case len(res.Changes) > 0:
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d attribute(s) differ from GitLab", len(res.Changes)),
+				Type:    ruleChanged,
+				Text:    attributeDiff(res.Changes),
+			}
+		}
+		add(res.Type, tc)
+	}
+
+	for _, c := range r.Changes {
+		if !strings.HasPrefix(c.Address, "var.") {
+			continue
+		}
+		var changes []AttributeChange
+		for _, a := range c.Attributes {
+			if !attached[c.Address+"."+a.Path] {
+				changes = append(changes, a)
+			}
+		}
+		var message string
+		switch c.Kind {
+		case "added":
+			message = "missing from the Terraform configuration"
+		case "removed":
+			message = "not generated from GitLab anymore"
+		default:
+			if len(changes) == 0 {
+				continue
+			}
+			message = fmt.Sprintf("%d entries differ from GitLab", len(changes))
+		}
+		add(c.Address, junitTestCase{
+			Name:      c.Address,
+			ClassName: c.Address,
+			File:      findingPath(r.TerraformDir, c.File),
+			Failure: &junitFailure{
+				Message: message,
+				Type:    ruleChanged,
+				Text:    attributeDiff(changes),
+			},
+		})
+	}
+
+	for _, o := range r.Orphans {
+		typ := resourceType(o.Address)
+		add(typ, junitTestCase{
+			Name:      o.Address,
+			ClassName: typ,
+			File:      findingPath(r.TerraformDir, o.File),
+			Failure: &junitFailure{
+				Message: "declared in Terraform but no longer exists in GitLab",
+				Type:    ruleOrphaned,
+			},
+		})
+	}
+
+	doc := junitTestSuites{Name: "terraform-gitlab-drift", Suites: []junitTestSuite{}}
+	names := make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		s := suites[name]
+		doc.Tests += s.Tests
+		doc.Failures += s.Failures
+		doc.Suites = append(doc.Suites, *s)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// attributeDiff renders attribute changes the way PrintDrift does.
+func attributeDiff(changes []AttributeChange) string {
+	var b strings.Builder
+	for _, a := range changes {
+		switch a.Kind {
+		case "added":
+			fmt.Fprintf(&b, "+ %s = %s\n", a.Path, a.New)
+		case "removed":
+			fmt.Fprintf(&b, "- %s = %s\n", a.Path, a.Old)
+		default:
+			fmt.Fprintf(&b, "~ %s: %s => %s\n", a.Path, a.Old, a.New)
+		}
+	}
+	return b.String()
+}
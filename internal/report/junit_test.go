@@ -0,0 +1,111 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/terraform"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "junit", Build(testInput())); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 5 || doc.Failures != 3 {
+		t.Errorf("got %d tests with %d failures, want 5 with 3", doc.Tests, doc.Failures)
+	}
+
+	var names []string
+	suites := make(map[string]junitTestSuite)
+	for _, s := range doc.Suites {
+		names = append(names, s.Name)
+		suites[s.Name] = s
+	}
+	if got := strings.Join(names, ","); got != "gitlab_group,gitlab_group_membership,gitlab_project" {
+		t.Errorf("suites = %s, want sorted resource types", got)
+	}
+
+	group := suites["gitlab_group"].Cases[0]
+	if group.Failure == nil || !strings.Contains(group.Failure.Text, `~ description: "old" => "new"`) {
+		t.Errorf("group case = %+v, want failure with attribute diff", group)
+	}
+	if member := suites["gitlab_group_membership"].Cases[0]; member.Failure != nil {
+		t.Errorf("membership case = %+v, want passing", member)
+	}
+
+	projects := suites["gitlab_project"]
+	if projects.Tests != 3 || projects.Failures != 2 {
+		t.Errorf("gitlab_project suite has %d tests with %d failures, want 3 with 2", projects.Tests, projects.Failures)
+	}
+	if api := projects.Cases[0]; api.Failure != nil || api.Name != "gitlab_project.api" {
+		t.Errorf("api case = %+v, want passing under its existing address", api)
+	}
+	if web := projects.Cases[1]; web.Failure == nil || web.Failure.Type != ruleUnmanaged {
+		t.Errorf("web case = %+v, want unmanaged failure", web)
+	}
+	if orphan := projects.Cases[2]; orphan.Failure == nil || orphan.Failure.Type != ruleOrphaned {
+		t.Errorf("orphan case = %+v, want orphaned failure", orphan)
+	}
+}
+
+func TestWriteJUnitVariableChanges(t *testing.T) {
+	in := testInput()
+	in.Locations["gitlab_group_membership.my_group"] = terraform.Location{
+		File:    "my_group.tf",
+		Line:    16,
+		ForEach: `var.gitlab_group_membership["my-group"]`,
+	}
+	in.Changes = append(in.Changes, terraform.BlockChange{
+		Address: "var.gitlab_group_membership",
+		File:    "group_membership.tf",
+		Line:    1,
+		Kind:    terraform.ChangeChanged,
+		Attributes: []terraform.AttributeChange{
+			{Path: `default["my-group"]["alice"]`, Kind: terraform.ChangeChanged, Old: `"developer"`, New: `"maintainer"`},
+			{Path: `default["my-group/new"]`, Kind: terraform.ChangeAdded, New: `{}`},
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, Build(in)); err != nil {
+		t.Fatalf("WriteJUnit error: %v", err)
+	}
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	suites := make(map[string]junitTestSuite)
+	for _, s := range doc.Suites {
+		suites[s.Name] = s
+	}
+
+	member := suites["gitlab_group_membership"].Cases[0]
+	if member.Failure == nil || member.Failure.Type != ruleChanged {
+		t.Errorf("membership case = %+v, want changed failure", member)
+	}
+	vars := suites["var.gitlab_group_membership"]
+	if len(vars.Cases) != 1 || vars.Failures != 1 {
+		t.Fatalf("variable suite = %+v, want one failing case", vars)
+	}
+	if text := vars.Cases[0].Failure.Text; text != "+ default[\"my-group/new\"] = {}\n" {
+		t.Errorf("variable failure text = %q, want only the entry without object", text)
+	}
+}
+
+func TestWriteJUnitQuotesImportID(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, Build(testInput())); err != nil {
+		t.Fatalf("WriteJUnit error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "terraform import &#39;gitlab_project.my_group_web&#39; &#39;43&#39;") {
+		t.Errorf("expected quoted import command, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/terraform"
+)
+
+// DefaultMRTemplate is the text/template the drift merge request
+// description is rendered from unless a custom one is given.
+//
+//go:embed mr_description.md.tmpl
+var DefaultMRTemplate string
+
+// maxMRImports limits the imports listed in the description, as GitLab
+// rejects descriptions over 1,000,000 characters.
+const maxMRImports = 500
+
+// MRDescription is the data the merge request description template is
+// rendered with.
+type MRDescription struct {
+	Group string
+	// Scope is the namespace or file the merge request is limited to when
+	// drift is split into several merge requests, empty otherwise.
+	Scope     string
+	ScannedAt time.Time
+	// Types summarizes the drift per resource type, sorted by type.
+	Types []TypeSummary
+	// Imports holds the terraform import commands, or the import {} blocks
+	// if ImportBlocks is set. ImportsOmitted counts those left out.
+	Imports        string
+	ImportBlocks   bool
+	ImportsOmitted int
+	Changes        []Change
+	Orphans        []Orphan
+}
+
+// TypeSummary counts the drift of one resource type.
+type TypeSummary struct {
+	Type      string
+	Unmanaged int
+	Changed   int
+	Orphaned  int
+}
+
+// NewMRDescription summarizes a report for the merge request description.
+// importBlocks tells whether imports are part of the merge request as
+// import {} blocks rather than commands to run.
+func NewMRDescription(r *Report, scannedAt time.Time, importBlocks bool) (*MRDescription, error) {
+	d := &MRDescription{
+		Group:        r.Group,
+		ScannedAt:    scannedAt,
+		ImportBlocks: importBlocks,
+		Changes:      r.Changes,
+		Orphans:      r.Orphans,
+	}
+
+	types := make(map[string]*TypeSummary)
+	summary := func(typ string) *TypeSummary {
+		s, ok := types[typ]
+		if !ok {
+			s = &TypeSummary{Type: typ}
+			types[typ] = s
+		}
+		return s
+	}
+
+	var imports []terraform.ImportCommand
+	for _, res := range r.Resources {
+		if res.Managed {
+			continue
+		}
+		summary(res.Type).Unmanaged++
+		imports = append(imports, terraform.ImportCommand{Address: res.Address, ID: res.ImportID, Key: res.Key})
+	}
+	for _, c := range r.Changes {
+		summary(blockType(c.Address)).Changed++
+	}
+	for _, o := range r.Orphans {
+		summary(blockType(o.Address)).Orphaned++
+	}
+	for _, typ := range slices.Sorted(maps.Keys(types)) {
+		d.Types = append(d.Types, *types[typ])
+	}
+
+	if len(imports) > maxMRImports {
+		d.ImportsOmitted = len(imports) - maxMRImports
+		imports = imports[:maxMRImports]
+	}
+	if len(imports) > 0 {
+		var buf bytes.Buffer
+		var err error
+		if importBlocks {
+			err = terraform.WriteImportBlocks(&buf, imports)
+		} else {
+			err = terraform.PrintImportCommands(&buf, imports)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rendering imports: %w", err)
+		}
+		d.Imports = buf.String()
+	}
+	return d, nil
+}
+
+// WriteMRDescription renders d with the given text/template, or with
+// DefaultMRTemplate if tmpl is empty.
+func WriteMRDescription(w io.Writer, tmpl string, d *MRDescription) error {
+	if tmpl == "" {
+		tmpl = DefaultMRTemplate
+	}
+	return d.execute(w, tmpl)
+}
+
+// Render renders a one-line text/template such as the merge request title
+// or commit message with d.
+func (d *MRDescription) Render(tmpl string) (string, error) {
+	var b strings.Builder
+	if err := d.execute(&b, tmpl); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+func (d *MRDescription) execute(w io.Writer, tmpl string) error {
+	t, err := template.New("mr").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parsing merge request template: %w", err)
+	}
+	if err := t.Execute(w, d); err != nil {
+		return fmt.Errorf("rendering merge request template: %w", err)
+	}
+	return nil
+}
+
+// blockType returns the resource type of a block address, or the variable
+// name for variable maps such as var.gitlab_project_variable.
+func blockType(addr string) string {
+	for _, prefix := range []string{"var.", "import.", "removed."} {
+		if rest, ok := strings.CutPrefix(addr, prefix); ok {
+			addr = rest
+			break
+		}
+	}
+	addr, _, _ = strings.Cut(addr, "[")
+	return resourceType(addr)
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteMRDescription(t *testing.T) {
+	scannedAt := time.Date(2025, 1, 2, 3, 4, 0, 0, time.UTC)
+	d, err := NewMRDescription(Build(testInput()), scannedAt, false)
+	if err != nil {
+		t.Fatalf("NewMRDescription error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMRDescription(&buf, "", d); err != nil {
+		t.Fatalf("WriteMRDescription error: %v", err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		"## GitLab drift in `my-group`",
+		"Scanned 2025-01-02 03:04 UTC",
+		"| `gitlab_group` | 0 | 1 | 0 |",
+		"| `gitlab_project` | 1 | 0 | 1 |",
+		"```shell\nterraform import 'gitlab_project.my_group_web' '43'\n```",
+		"- `gitlab_group.my_group` (changed, 1 attribute(s))",
+		"- `gitlab_project.deleted` in `my_group.tf`",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("description does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "gitlab_group_membership") {
+		t.Errorf("types without drift should not be listed:\n%s", got)
+	}
+}
+
+func TestWriteMRDescriptionImportBlocks(t *testing.T) {
+	d, err := NewMRDescription(Build(testInput()), time.Now(), true)
+	if err != nil {
+		t.Fatalf("NewMRDescription error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMRDescription(&buf, "", d); err != nil {
+		t.Fatalf("WriteMRDescription error: %v", err)
+	}
+	if want := "import {\n  to = gitlab_project.my_group_web\n  id = \"43\"\n}"; !strings.Contains(buf.String(), want) {
+		t.Errorf("description does not contain import block %q:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteMRDescriptionCustomTemplate(t *testing.T) {
+	d, err := NewMRDescription(Build(testInput()), time.Now(), false)
+	if err != nil {
+		t.Fatalf("NewMRDescription error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	tmpl := "{{.Group}}:{{range .Types}} {{.Type}}={{.Unmanaged}}{{end}}"
+	if err := WriteMRDescription(&buf, tmpl, d); err != nil {
+		t.Fatalf("WriteMRDescription error: %v", err)
+	}
+	if want := "my-group: gitlab_group=0 gitlab_project=1"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	if err := WriteMRDescription(&buf, "{{.Missing}}", d); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
+
+func TestMRDescriptionRender(t *testing.T) {
+	d, err := NewMRDescription(Build(testInput()), time.Now(), false)
+	if err != nil {
+		t.Fatalf("NewMRDescription error: %v", err)
+	}
+
+	got, err := d.Render("chore: drift in {{.Group}} ({{len .Types}} types)\n")
+	if err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+	if want := "chore: drift in my-group (2 types)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,276 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/terraform"
+)
+
+// Version is the version of the JSON report format. It is increased on
+// incompatible changes.
+const Version = 1
+
+// Report is the machine-readable result of a drift scan.
+type Report struct {
+	Version      int                   `json:"version"`
+	Group        string                `json:"group"`
+	TerraformDir string                `json:"terraform_dir"`
+	DriftFound   bool                  `json:"drift_found"`
+	Fetched      map[string]int        `json:"fetched"`
+	Totals       map[string]TypeTotals `json:"totals"`
+	Resources    []Resource            `json:"resources"`
+	Changes      []Change              `json:"changes"`
+	Orphans      []Orphan              `json:"orphans"`
+}
+
+// TypeTotals counts the GitLab objects of one terraform resource type.
+type TypeTotals struct {
+	Total     int `json:"total"`
+	Managed   int `json:"managed"`
+	Unmanaged int `json:"unmanaged"`
+}
+
+// Resource is a single GitLab object and how it maps to terraform.
+type Resource struct {
+	Type     string            `json:"type"`
+	Address  string            `json:"address"`
+	Key      string            `json:"key,omitempty"`
+	ImportID string            `json:"import_id"`
+	File     string            `json:"file,omitempty"`
+	Line     int               `json:"line,omitempty"`
+	Managed  bool              `json:"managed"`
+	Changes  []AttributeChange `json:"changes,omitempty"`
+}
+
+// Change is a block that differs between existing and generated files.
+type Change struct {
+	Address    string            `json:"address"`
+	File       string            `json:"file"`
+	Line       int               `json:"line,omitempty"`
+	Kind       string            `json:"kind"`
+	Attributes []AttributeChange `json:"attributes,omitempty"`
+}
+
+// AttributeChange is a single attribute difference of a block.
+type AttributeChange struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// Orphan is a declaration whose GitLab object no longer exists.
+type Orphan struct {
+	Address string `json:"address"`
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// Input collects the scan results a report is built from.
+type Input struct {
+	Group string
+	// TerraformDir is the directory file names are relative to, as a path
+	// within the repository.
+	TerraformDir string
+	DriftFound   bool
+	// Fetched holds the counts logged after fetching, e.g. "groups".
+	Fetched map[string]int
+	// All lists every GitLab object with its generated address.
+	All []terraform.ImportCommand
+	// Unmanaged lists the objects that still need to be imported.
+	Unmanaged []terraform.ImportCommand
+	// Matches maps generated addresses to existing ones declared under a
+	// different name.
+	Matches map[string]string
+	// Locations maps "type.name" addresses to where they are declared in
+	// the generated files, after renaming matched resources.
+	Locations map[string]terraform.Location
+	// Existing maps block addresses to where they are declared in
+	// TerraformDir. Lines are only reported from there, as the generated
+	// files are not part of the repository.
+	Existing map[string]terraform.Location
+	Changes  []terraform.BlockChange
+	Orphans  []terraform.Orphan
+}
+
+// Build assembles a report from the scan results.
+func Build(in Input) *Report {
+	r := &Report{
+		Version:      Version,
+		Group:        in.Group,
+		TerraformDir: in.TerraformDir,
+		DriftFound:   in.DriftFound,
+		Fetched:      in.Fetched,
+		Totals:       make(map[string]TypeTotals),
+		Resources:    []Resource{},
+		Changes:      []Change{},
+		Orphans:      []Orphan{},
+	}
+
+	unmanaged := make(map[string]bool, len(in.Unmanaged))
+	for _, cmd := range in.Unmanaged {
+		unmanaged[resourceType(cmd.Address)+"/"+cmd.ID] = true
+	}
+
+	blockChanges := make(map[string][]AttributeChange)
+	for _, c := range in.Changes {
+		change := Change{Address: c.Address, File: c.File, Line: in.existingLine(c.Address, c.File), Kind: string(c.Kind)}
+		for _, a := range c.Attributes {
+			change.Attributes = append(change.Attributes, AttributeChange{
+				Path: a.Path,
+				Kind: string(a.Kind),
+				Old:  a.Old,
+				New:  a.New,
+			})
+		}
+		r.Changes = append(r.Changes, change)
+		blockChanges[c.Address] = change.Attributes
+	}
+
+	// Objects of for_each resources are changed in the variable map the
+	// resource iterates.
+	instanceChanges := make(map[string][]AttributeChange)
+	for addr, attrs := range terraform.InstanceChanges(in.Changes, in.Locations) {
+		for _, a := range attrs {
+			instanceChanges[addr] = append(instanceChanges[addr], AttributeChange{
+				Path: a.Path,
+				Kind: string(a.Kind),
+				Old:  a.Old,
+				New:  a.New,
+			})
+		}
+	}
+
+	renamed := terraform.RenameImportCommands(in.All, in.Matches)
+	for i, cmd := range in.All {
+		typ := resourceType(cmd.Address)
+		block := resourceAddress(renamed[i].Address)
+		changes := slices.Concat(blockChanges[block], instanceChanges[renamed[i].Address])
+		res := Resource{
+			Type:     typ,
+			Address:  renamed[i].Address,
+			Key:      cmd.Key,
+			ImportID: cmd.ID,
+			File:     in.Locations[block].File,
+			Line:     in.existingLine(block, in.Locations[block].File),
+			Managed:  !unmanaged[typ+"/"+cmd.ID],
+			Changes:  changes,
+		}
+		r.Resources = append(r.Resources, res)
+
+		totals := r.Totals[typ]
+		totals.Total++
+		if res.Managed {
+			totals.Managed++
+		} else {
+			totals.Unmanaged++
+		}
+		r.Totals[typ] = totals
+	}
+
+	for _, o := range in.Orphans {
+		r.Orphans = append(r.Orphans, Orphan{Address: o.Address, File: o.File, Line: o.Line})
+	}
+
+	return r
+}
+
+// existingLine returns the line addr is declared at in file of TerraformDir,
+// or 0 if the file does not declare it.
+func (in Input) existingLine(addr, file string) int {
+	if loc, ok := in.Existing[addr]; ok && loc.File == file {
+		return loc.Line
+	}
+	return 0
+}
+
+// Select returns the part of the report about the files keep accepts, by
+// base name, e.g. for a merge request limited to those files. Totals and
+// DriftFound are recomputed from what is left.
+func (r *Report) Select(keep func(file string) bool) *Report {
+	sel := &Report{
+		Version:      r.Version,
+		Group:        r.Group,
+		TerraformDir: r.TerraformDir,
+		Fetched:      r.Fetched,
+		Totals:       make(map[string]TypeTotals),
+		Resources:    []Resource{},
+		Changes:      []Change{},
+		Orphans:      []Orphan{},
+	}
+	for _, res := range r.Resources {
+		if !keep(filepath.Base(res.File)) {
+			continue
+		}
+		sel.Resources = append(sel.Resources, res)
+		totals := sel.Totals[res.Type]
+		totals.Total++
+		if res.Managed {
+			totals.Managed++
+		} else {
+			totals.Unmanaged++
+			sel.DriftFound = true
+		}
+		sel.Totals[res.Type] = totals
+	}
+	for _, c := range r.Changes {
+		if keep(filepath.Base(c.File)) {
+			sel.Changes = append(sel.Changes, c)
+			sel.DriftFound = true
+		}
+	}
+	for _, o := range r.Orphans {
+		if keep(filepath.Base(o.File)) {
+			sel.Orphans = append(sel.Orphans, o)
+			sel.DriftFound = true
+		}
+	}
+	return sel
+}
+
+// Formats maps the supported report formats to their default file names.
+var Formats = map[string]string{
+	"json":        "drift-report.json",
+	"codequality": "gl-code-quality-report.json",
+	"sarif":       "drift.sarif",
+	"junit":       "drift-junit.xml",
+}
+
+// Write writes the report in the given format.
+func Write(w io.Writer, format string, r *Report) error {
+	switch format {
+	case "json":
+		return WriteJSON(w, r)
+	case "codequality":
+		return WriteCodeQuality(w, r)
+	case "sarif":
+		return WriteSARIF(w, r)
+	case "junit":
+		return WriteJUnit(w, r)
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// WriteJSON writes the report as indented JSON.
+func WriteJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// resourceAddress strips the for_each key from an address.
+func resourceAddress(addr string) string {
+	block, _, _ := strings.Cut(addr, "[")
+	return block
+}
+
+func resourceType(addr string) string {
+	typ, _, _ := strings.Cut(addr, ".")
+	return typ
+}
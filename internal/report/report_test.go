@@ -0,0 +1,186 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/terraform"
+)
+
+func testInput() Input {
+	return Input{
+		Group:        "my-group",
+		TerraformDir: "terraform",
+		DriftFound:   true,
+		Fetched:      map[string]int{"groups": 1, "projects": 2},
+		All: []terraform.ImportCommand{
+			{Address: "gitlab_group.my_group", ID: "1"},
+			{Address: "gitlab_project.my_group_api", ID: "42"},
+			{Address: "gitlab_project.my_group_web", ID: "43"},
+			{Address: `gitlab_group_membership.my_group["alice"]`, ID: "1:100", Key: "alice"},
+		},
+		Unmanaged: []terraform.ImportCommand{
+			{Address: "gitlab_project.my_group_web", ID: "43"},
+		},
+		Matches: map[string]string{
+			"gitlab_project.my_group_api": "gitlab_project.api",
+		},
+		Locations: map[string]terraform.Location{
+			"gitlab_group.my_group":            {File: "my_group.tf", Line: 1},
+			"gitlab_project.api":               {File: "my_group.tf", Line: 6},
+			"gitlab_project.my_group_web":      {File: "my_group.tf", Line: 11},
+			"gitlab_group_membership.my_group": {File: "my_group.tf", Line: 16},
+		},
+		Existing: map[string]terraform.Location{
+			"gitlab_group.my_group":            {File: "my_group.tf", Line: 1},
+			"gitlab_project.api":               {File: "my_group.tf", Line: 6},
+			"gitlab_group_membership.my_group": {File: "my_group.tf", Line: 11},
+			"gitlab_project.deleted":           {File: "my_group.tf", Line: 21},
+		},
+		Changes: []terraform.BlockChange{
+			{
+				Address: "gitlab_group.my_group",
+				File:    "my_group.tf",
+				Line:    1,
+				Kind:    terraform.ChangeChanged,
+				Attributes: []terraform.AttributeChange{
+					{Path: "description", Kind: terraform.ChangeChanged, Old: `"old"`, New: `"new"`},
+				},
+			},
+		},
+		Orphans: []terraform.Orphan{
+			{Address: "gitlab_project.deleted", File: "my_group.tf", Line: 21},
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	r := Build(testInput())
+
+	if r.Version != Version {
+		t.Errorf("Version = %d, want %d", r.Version, Version)
+	}
+	if len(r.Resources) != 4 {
+		t.Fatalf("got %d resources, want 4", len(r.Resources))
+	}
+
+	group := r.Resources[0]
+	if !group.Managed || group.File != "my_group.tf" || len(group.Changes) != 1 {
+		t.Errorf("group resource = %+v, want managed with one change in my_group.tf", group)
+	}
+	if api := r.Resources[1]; api.Address != "gitlab_project.api" || !api.Managed {
+		t.Errorf("api resource = %+v, want managed under its existing address", api)
+	}
+	if web := r.Resources[2]; web.Managed || web.Line != 0 {
+		t.Errorf("web resource = %+v, want unmanaged without line", web)
+	}
+	if member := r.Resources[3]; member.Type != "gitlab_group_membership" || member.File != "my_group.tf" || member.Line != 11 {
+		t.Errorf("membership resource = %+v, want gitlab_group_membership in my_group.tf at its existing line", member)
+	}
+
+	want := TypeTotals{Total: 2, Managed: 1, Unmanaged: 1}
+	if got := r.Totals["gitlab_project"]; got != want {
+		t.Errorf("Totals[gitlab_project] = %+v, want %+v", got, want)
+	}
+	if len(r.Changes) != 1 || len(r.Orphans) != 1 {
+		t.Errorf("got %d changes and %d orphans, want 1 each", len(r.Changes), len(r.Orphans))
+	}
+}
+
+func TestBuildAttachesVariableChanges(t *testing.T) {
+	in := testInput()
+	in.All = append(in.All, terraform.ImportCommand{Address: `gitlab_group_membership.my_group["bob"]`, ID: "1:101", Key: "bob"})
+	in.Locations["gitlab_group_membership.my_group"] = terraform.Location{
+		File:    "my_group.tf",
+		Line:    16,
+		ForEach: `var.gitlab_group_membership["my-group"]`,
+	}
+	in.Changes = append(in.Changes, terraform.BlockChange{
+		Address: "var.gitlab_group_membership",
+		File:    "group_membership.tf",
+		Line:    1,
+		Kind:    terraform.ChangeChanged,
+		Attributes: []terraform.AttributeChange{
+			{Path: `default["my-group"]["alice"]`, Kind: terraform.ChangeChanged, Old: `"developer"`, New: `"maintainer"`},
+		},
+	})
+	r := Build(in)
+
+	alice, bob := r.Resources[3], r.Resources[4]
+	want := []AttributeChange{{
+		Path: `var.gitlab_group_membership.default["my-group"]["alice"]`,
+		Kind: "changed",
+		Old:  `"developer"`,
+		New:  `"maintainer"`,
+	}}
+	if !reflect.DeepEqual(alice.Changes, want) {
+		t.Errorf("alice changes = %+v, want %+v", alice.Changes, want)
+	}
+	if len(bob.Changes) != 0 {
+		t.Errorf("bob changes = %+v, want none", bob.Changes)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "json", Build(testInput())); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, key := range []string{"version", "group", "drift_found", "fetched", "totals", "resources", "changes", "orphans"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("missing key %q", key)
+		}
+	}
+}
+
+func TestBuildEmptyListsEncodeAsArrays(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, Build(Input{})); err != nil {
+		t.Fatalf("WriteJSON error: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"resources": []`)) {
+		t.Errorf("expected empty resources array, got:\n%s", buf.String())
+	}
+}
+
+func TestReportSelect(t *testing.T) {
+	in := testInput()
+	in.Locations["gitlab_group_membership.my_group"] = terraform.Location{File: "group_membership.tf", Line: 1}
+	r := Build(in)
+
+	sel := r.Select(func(file string) bool { return file == "group_membership.tf" })
+	if len(sel.Resources) != 1 || sel.Resources[0].Type != "gitlab_group_membership" {
+		t.Errorf("Resources = %+v, want only the membership", sel.Resources)
+	}
+	if len(sel.Changes) != 0 || len(sel.Orphans) != 0 {
+		t.Errorf("expected no changes and orphans, got %+v and %+v", sel.Changes, sel.Orphans)
+	}
+	if sel.DriftFound {
+		t.Error("DriftFound = true for a managed, unchanged resource")
+	}
+	if got := sel.Totals["gitlab_group_membership"]; got != (TypeTotals{Total: 1, Managed: 1}) {
+		t.Errorf("Totals = %+v", got)
+	}
+
+	sel = r.Select(func(file string) bool { return file == "my_group.tf" })
+	if len(sel.Resources) != 3 || len(sel.Changes) != 1 || len(sel.Orphans) != 1 {
+		t.Errorf("got %d resources, %d changes, %d orphans, want 3, 1, 1", len(sel.Resources), len(sel.Changes), len(sel.Orphans))
+	}
+	if !sel.DriftFound {
+		t.Error("DriftFound = false, want true")
+	}
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "xml", Build(Input{})); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
@@ -0,0 +1,42 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, "sarif", Build(testInput())); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if log.Version != sarifVersion || len(log.Runs) != 1 {
+		t.Fatalf("got version %q with %d runs, want %s with 1 run", log.Version, len(log.Runs), sarifVersion)
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(ruleDescriptions) {
+		t.Errorf("got %d rules, want %d", len(run.Tool.Driver.Rules), len(ruleDescriptions))
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(run.Results))
+	}
+
+	orphan := run.Results[1]
+	if orphan.RuleID != ruleOrphaned || orphan.Level != "error" {
+		t.Errorf("result 1 = %s/%s, want %s/error", orphan.RuleID, orphan.Level, ruleOrphaned)
+	}
+	loc := orphan.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "terraform/my_group.tf" || loc.Region.StartLine != 21 {
+		t.Errorf("result 1 location = %+v, want terraform/my_group.tf:21", loc)
+	}
+	if orphan.PartialFingerprints["resourceAddress/v1"] != fingerprint(ruleOrphaned, "gitlab_project.deleted") {
+		t.Errorf("unexpected fingerprint %v", orphan.PartialFingerprints)
+	}
+}
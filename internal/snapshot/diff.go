@@ -0,0 +1,395 @@
+// Package snapshot compares GitLab snapshots written by the fetch command,
+// independent of any Terraform configuration.
+package snapshot
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
+)
+
+// Kind describes how an object differs between two snapshots.
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// Change is a GitLab object that was added, removed or changed between two
+// snapshots. Path is the full path of the group or project the object
+// belongs to, Name identifies it within, e.g. a username or hook URL.
+type Change struct {
+	Type       string            `json:"type"`
+	Path       string            `json:"path"`
+	Name       string            `json:"name,omitempty"`
+	Kind       Kind              `json:"kind"`
+	Attributes []AttributeChange `json:"attributes,omitempty"`
+}
+
+// AttributeChange is a field that differs. For added and removed objects
+// only the fields that matter for access are listed, e.g. the access level
+// of a member.
+type AttributeChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// sensitive is shown instead of values that may hold secrets.
+const sensitive = "(sensitive)"
+
+// objectType describes how objects of a type are compared.
+type objectType struct {
+	name string
+	// ignore lists fields that change without anyone editing the object,
+	// like counters and timestamps. A field covers its nested fields.
+	ignore []string
+	// sensitive lists fields whose values are not shown.
+	sensitive []string
+	// summary lists fields shown for added and removed objects.
+	summary []string
+}
+
+var (
+	groupType = objectType{
+		name:   "group",
+		ignore: []string{"statistics", "runners_token", "shared_with_groups", "projects", "shared_projects", "marked_for_deletion_on"},
+	}
+	projectType = objectType{
+		name: "project",
+		ignore: []string{
+			"last_activity_at", "updated_at", "open_issues_count", "star_count", "forks_count", "statistics",
+			"runners_token", "_links", "empty_repo", "permissions", "shared_with_groups", "import_status",
+			"container_expiration_policy.next_run_at", "repository_storage",
+		},
+		summary: []string{"visibility"},
+	}
+	groupMemberType = objectType{
+		name:    "group_member",
+		ignore:  []string{"avatar_url", "web_url", "is_using_seat"},
+		summary: []string{"access_level", "expires_at"},
+	}
+	projectMemberType = objectType{
+		name:    "project_member",
+		ignore:  []string{"avatar_url", "web_url", "is_using_seat"},
+		summary: []string{"access_level", "expires_at"},
+	}
+	groupShareType = objectType{
+		name:    "group_share",
+		summary: []string{"group_access_level", "expires_at"},
+	}
+	projectShareType = objectType{
+		name:    "project_share",
+		summary: []string{"group_access_level", "expires_at"},
+	}
+	groupLabelType = objectType{
+		name:   "group_label",
+		ignore: []string{"open_issues_count", "closed_issues_count", "open_merge_requests_count", "subscribed"},
+	}
+	projectLabelType = objectType{
+		name:   "project_label",
+		ignore: []string{"open_issues_count", "closed_issues_count", "open_merge_requests_count", "subscribed"},
+	}
+	groupHookType = objectType{
+		name:      "group_hook",
+		sensitive: []string{"custom_headers"},
+	}
+	projectHookType = objectType{
+		name:      "project_hook",
+		sensitive: []string{"custom_headers"},
+	}
+	pipelineScheduleType = objectType{
+		name:      "pipeline_schedule",
+		ignore:    []string{"next_run_at", "updated_at", "last_pipeline"},
+		sensitive: []string{"variables"},
+		summary:   []string{"cron", "ref", "owner.username"},
+	}
+)
+
+// object is a GitLab object keyed for comparison.
+type object struct {
+	typ  *objectType
+	path string
+	name string
+	// value is the object itself, flattened to its JSON fields.
+	value map[string]string
+}
+
+// Diff returns the groups, projects, members, shares, labels, hooks and
+// pipeline schedules that differ between before and after, ordered by type, path
+// and name. Resource types in skipSet are not compared; pass the types
+// skipped by either snapshot so they do not show up as added or removed.
+func Diff(before, after *gitlab.Resources, skipSet skip.Set) ([]Change, error) {
+	oldObjects, err := objects(before, skipSet)
+	if err != nil {
+		return nil, fmt.Errorf("reading old snapshot: %w", err)
+	}
+	newObjects, err := objects(after, skipSet)
+	if err != nil {
+		return nil, fmt.Errorf("reading new snapshot: %w", err)
+	}
+
+	var changes []Change
+	for key, n := range newObjects {
+		o, ok := oldObjects[key]
+		if !ok {
+			changes = append(changes, Change{Type: n.typ.name, Path: n.path, Name: n.name, Kind: Added, Attributes: summary(n, Added)})
+			continue
+		}
+		if attrs := compare(n.typ, o.value, n.value); len(attrs) > 0 {
+			changes = append(changes, Change{Type: n.typ.name, Path: n.path, Name: n.name, Kind: Changed, Attributes: attrs})
+		}
+	}
+	for key, o := range oldObjects {
+		if _, ok := newObjects[key]; !ok {
+			changes = append(changes, Change{Type: o.typ.name, Path: o.path, Name: o.name, Kind: Removed, Attributes: summary(o, Removed)})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b Change) int {
+		return cmp.Or(
+			cmp.Compare(a.Type, b.Type),
+			cmp.Compare(a.Path, b.Path),
+			cmp.Compare(a.Name, b.Name),
+			cmp.Compare(a.Kind, b.Kind),
+		)
+	})
+	return changes, nil
+}
+
+// objects collects the compared objects of r keyed by type and ID. Objects
+// of groups and projects that are not in r are left out.
+func objects(r *gitlab.Resources, skipSet skip.Set) (map[string]*object, error) {
+	result := make(map[string]*object)
+
+	groupPaths := make(map[int64]string)
+	for _, g := range r.Groups {
+		groupPaths[g.ID] = g.FullPath
+		if err := addObject(result, &groupType, id(g.ID), g.FullPath, "", g); err != nil {
+			return nil, err
+		}
+	}
+	projectPaths := make(map[int64]string)
+	for _, p := range r.Projects {
+		projectPaths[p.ID] = p.PathWithNamespace
+		if err := addObject(result, &projectType, id(p.ID), p.PathWithNamespace, "", p); err != nil {
+			return nil, err
+		}
+		for _, s := range p.SharedWithGroups {
+			if err := addObject(result, &projectShareType, id(p.ID, s.GroupID), p.PathWithNamespace, s.GroupFullPath, s); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var errs []error
+	if !skipSet.Has("memberships") {
+		errs = append(errs,
+			collect(result, &groupMemberType, groupPaths, r.GroupMembers, func(m *gl.GroupMember) (int64, string) { return m.ID, m.Username }),
+			collect(result, &projectMemberType, projectPaths, r.ProjectMembers, func(m *gl.ProjectMember) (int64, string) { return m.ID, m.Username }),
+			collect(result, &groupShareType, groupPaths, r.GroupShares, func(s gl.SharedWithGroup) (int64, string) { return s.GroupID, s.GroupFullPath }),
+		)
+	}
+	if !skipSet.Has("labels") {
+		errs = append(errs,
+			collect(result, &groupLabelType, groupPaths, r.GroupLabels, func(l *gl.GroupLabel) (int64, string) { return l.ID, l.Name }),
+			collect(result, &projectLabelType, projectPaths, r.ProjectLabels, func(l *gl.Label) (int64, string) { return l.ID, l.Name }),
+		)
+	}
+	if !skipSet.Has("hooks") {
+		errs = append(errs,
+			collect(result, &groupHookType, groupPaths, r.GroupHooks, func(h *gl.GroupHook) (int64, string) { return h.ID, h.URL }),
+			collect(result, &projectHookType, projectPaths, r.ProjectHooks, func(h *gl.ProjectHook) (int64, string) { return h.ID, h.URL }),
+		)
+	}
+	if !skipSet.Has("schedules") {
+		errs = append(errs,
+			collect(result, &pipelineScheduleType, projectPaths, r.PipelineSchedules, func(s *gl.PipelineSchedule) (int64, string) { return s.ID, s.Description }),
+		)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// collect adds the objects of the groups or projects in owners, keyed by
+// owner and the ID returned by key.
+func collect[T any](result map[string]*object, typ *objectType, owners map[int64]string, byOwner map[int64][]T, key func(T) (int64, string)) error {
+	for ownerID, items := range byOwner {
+		path, ok := owners[ownerID]
+		if !ok {
+			continue
+		}
+		for _, item := range items {
+			itemID, name := key(item)
+			if err := addObject(result, typ, id(ownerID, itemID), path, name, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func addObject(result map[string]*object, typ *objectType, key, path, name string, v any) error {
+	value, err := flatten(v)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", typ.name, key, err)
+	}
+	result[typ.name+":"+key] = &object{typ: typ, path: path, name: name, value: value}
+	return nil
+}
+
+func id(ids ...int64) string {
+	parts := make([]string, len(ids))
+	for i, n := range ids {
+		parts[i] = strconv.FormatInt(n, 10)
+	}
+	return strings.Join(parts, "/")
+}
+
+// flatten encodes v as JSON and returns its fields by dotted path. Nested
+// objects are flattened, lists and scalars are kept as JSON.
+func flatten(v any) (map[string]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	flattenInto(result, "", fields)
+	return result, nil
+}
+
+func flattenInto(result map[string]string, prefix string, fields map[string]any) {
+	for name, v := range fields {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		switch v := v.(type) {
+		case nil:
+		case map[string]any:
+			flattenInto(result, path, v)
+		default:
+			// Decoded JSON always encodes again.
+			data, _ := json.Marshal(v)
+			result[path] = string(data)
+		}
+	}
+}
+
+// compare returns the fields of typ that differ between before and after.
+func compare(typ *objectType, before, after map[string]string) []AttributeChange {
+	var attrs []AttributeChange
+	for _, name := range sortedUnion(before, after) {
+		if matchesField(typ.ignore, name) || before[name] == after[name] {
+			continue
+		}
+		attrs = append(attrs, AttributeChange{
+			Name: name,
+			Old:  display(typ, name, before[name]),
+			New:  display(typ, name, after[name]),
+		})
+	}
+	return attrs
+}
+
+// summary returns the summary fields of an added or removed object.
+func summary(o *object, kind Kind) []AttributeChange {
+	var attrs []AttributeChange
+	for _, name := range o.typ.summary {
+		v, ok := o.value[name]
+		if !ok {
+			continue
+		}
+		a := AttributeChange{Name: name}
+		if kind == Added {
+			a.New = display(o.typ, name, v)
+		} else {
+			a.Old = display(o.typ, name, v)
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs
+}
+
+// display formats a flattened JSON value for output.
+func display(typ *objectType, name, value string) string {
+	if value == "" {
+		return ""
+	}
+	if matchesField(typ.sensitive, name) {
+		return sensitive
+	}
+	if strings.HasSuffix(name, "access_level") {
+		if level, err := strconv.Atoi(value); err == nil {
+			return accessLevelName(gl.AccessLevelValue(level))
+		}
+	}
+	return value
+}
+
+// matchesField reports whether name is one of fields or nested in one.
+func matchesField(fields []string, name string) bool {
+	for _, f := range fields {
+		if name == f || strings.HasPrefix(name, f+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedUnion(a, b map[string]string) []string {
+	names := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// accessLevelName names an access level the way the GitLab UI does.
+func accessLevelName(level gl.AccessLevelValue) string {
+	switch level {
+	case gl.NoPermissions:
+		return "no access"
+	case gl.MinimalAccessPermissions:
+		return "minimal access"
+	case gl.GuestPermissions:
+		return "guest"
+	case gl.PlannerPermissions:
+		return "planner"
+	case gl.ReporterPermissions:
+		return "reporter"
+	case gl.DeveloperPermissions:
+		return "developer"
+	case gl.MaintainerPermissions:
+		return "maintainer"
+	case gl.OwnerPermissions:
+		return "owner"
+	case gl.AdminPermissions:
+		return "admin"
+	default:
+		return strconv.Itoa(int(level))
+	}
+}
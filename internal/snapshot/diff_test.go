@@ -0,0 +1,114 @@
+package snapshot
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
+)
+
+func testResources() *gitlab.Resources {
+	return &gitlab.Resources{
+		Groups: []*gl.Group{
+			{ID: 1, FullPath: "mygroup", Name: "mygroup", Visibility: gl.PrivateVisibility},
+		},
+		Projects: []*gl.Project{
+			{ID: 10, PathWithNamespace: "mygroup/api", Name: "api", Visibility: gl.PrivateVisibility, StarCount: 1},
+		},
+		GroupMembers: gitlab.GroupMembers{
+			1: {
+				{ID: 100, Username: "alice", AccessLevel: gl.DeveloperPermissions},
+				{ID: 101, Username: "bob", AccessLevel: gl.ReporterPermissions},
+			},
+		},
+		ProjectHooks: gitlab.ProjectHooks{
+			10: {{ID: 3, URL: "https://example.com/hook", PushEvents: true,
+				CustomHeaders: []*gl.HookCustomHeader{{Key: "X-Token", Value: "abc"}}}},
+		},
+		PipelineSchedules: gitlab.PipelineSchedules{
+			10: {{ID: 7, Description: "nightly", Cron: "0 1 * * *", Ref: "main"}},
+		},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := testResources()
+	after := testResources()
+
+	next := time.Date(2024, 3, 2, 1, 0, 0, 0, time.UTC)
+	after.Projects[0].Visibility = gl.InternalVisibility
+	after.Projects[0].StarCount = 5
+	after.GroupMembers[1] = []*gl.GroupMember{
+		{ID: 100, Username: "alice", AccessLevel: gl.MaintainerPermissions},
+		{ID: 102, Username: "carol", AccessLevel: gl.OwnerPermissions},
+	}
+	after.ProjectHooks[10][0].CustomHeaders[0].Value = "def"
+	after.PipelineSchedules[10][0].NextRunAt = &next
+
+	changes, err := Diff(before, after, nil)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Print(&buf, changes); err != nil {
+		t.Fatalf("Print error: %v", err)
+	}
+	want := `~ group_member mygroup: alice
+    ~ access_level: developer => maintainer
+- group_member mygroup: bob
+    - access_level = reporter
++ group_member mygroup: carol
+    + access_level = owner
+~ project mygroup/api
+    ~ visibility: "private" => "internal"
+~ project_hook mygroup/api: https://example.com/hook
+    ~ custom_headers: (sensitive) => (sensitive)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Print output mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestDiffSkipsTypes(t *testing.T) {
+	before := testResources()
+	after := testResources()
+	before.ProjectHooks = nil
+
+	changes, err := Diff(before, after, skip.Set{"hooks": true})
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes for skipped hooks, got %+v", changes)
+	}
+}
+
+func TestDiffRemovedProject(t *testing.T) {
+	before := testResources()
+	after := testResources()
+	after.Projects = nil
+
+	changes, err := Diff(before, after, nil)
+	if err != nil {
+		t.Fatalf("Diff error: %v", err)
+	}
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, string(c.Kind)+" "+c.Type+" "+c.Name)
+	}
+	want := []string{"removed pipeline_schedule nightly", "removed project ", "removed project_hook https://example.com/hook"}
+	if len(got) != len(want) {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("changes[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Print writes changes as a human-readable list, one object per line
+// followed by its changed fields.
+func Print(w io.Writer, changes []Change) error {
+	for _, c := range changes {
+		line := fmt.Sprintf("%s %s %s", symbol(c.Kind), c.Type, c.Path)
+		if c.Name != "" {
+			line += ": " + c.Name
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, a := range c.Attributes {
+			var err error
+			switch {
+			case a.Old == "":
+				_, err = fmt.Fprintf(w, "    + %s = %s\n", a.Name, a.New)
+			case a.New == "":
+				_, err = fmt.Fprintf(w, "    - %s = %s\n", a.Name, a.Old)
+			default:
+				_, err = fmt.Fprintf(w, "    ~ %s: %s => %s\n", a.Name, a.Old, a.New)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes changes as a JSON array.
+func WriteJSON(w io.Writer, changes []Change) error {
+	if changes == nil {
+		changes = []Change{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(changes)
+}
+
+func symbol(kind Kind) string {
+	switch kind {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	default:
+		return "~"
+	}
+}
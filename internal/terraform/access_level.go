@@ -20,6 +20,8 @@ func accessLevelToString(level gl.AccessLevelValue) string {
 		return "maintainer"
 	case gl.OwnerPermissions:
 		return "owner"
+	case gl.AdminPermissions:
+		return "admin"
 	default:
 		return "guest"
 	}
@@ -20,6 +20,7 @@ func TestAccessLevelToString(t *testing.T) {
 		{"developer", gl.DeveloperPermissions, "developer"},
 		{"maintainer", gl.MaintainerPermissions, "maintainer"},
 		{"owner", gl.OwnerPermissions, "owner"},
+		{"admin", gl.AdminPermissions, "admin"},
 		{"unknown defaults to guest", gl.AccessLevelValue(99), "guest"},
 	}
 
@@ -0,0 +1,252 @@
+package terraform
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// codeOwnerRuleType is the rule type GitLab derives from CODEOWNERS files.
+// Such rules cannot be managed through gitlab_project_approval_rule.
+const codeOwnerRuleType = "code_owner"
+
+// approvalRuleNames maps the IDs of the manageable approval rules of a
+// project to their resource names. Rules whose names normalize to the same
+// name, e.g. "QA review" and "qa-review", get a numeric suffix in sorted name
+// order, like refResourceNames does for protected refs.
+func approvalRuleNames(p *gl.Project, rules []*gl.ProjectApprovalRule) map[int64]string {
+	rules = manageableApprovalRules(rules)
+	slices.SortStableFunc(rules, func(a, b *gl.ProjectApprovalRule) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+	names := make(map[int64]string, len(rules))
+	taken := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		base := projectResourceName(p) + "_" + normalizeName(r.Name)
+		name := base
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		taken[name] = true
+		names[r.ID] = name
+	}
+	return names
+}
+
+// manageableApprovalRules returns the rules that can be expressed as
+// gitlab_project_approval_rule resources.
+func manageableApprovalRules(rules []*gl.ProjectApprovalRule) []*gl.ProjectApprovalRule {
+	var result []*gl.ProjectApprovalRule
+	for _, r := range rules {
+		if r == nil || r.RuleType == codeOwnerRuleType {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// approverNames collects the sorted, unique usernames and group full paths
+// referenced by all approval rules.
+func approverNames(projects []*gl.Project, rules gitlab.ProjectApprovalRules) (users, groups []string) {
+	for _, p := range projects {
+		if p == nil {
+			continue
+		}
+		for _, r := range manageableApprovalRules(rules[p.ID]) {
+			for _, u := range r.Users {
+				if u != nil && !slices.Contains(users, u.Username) {
+					users = append(users, u.Username)
+				}
+			}
+			for _, g := range r.Groups {
+				if g != nil && !slices.Contains(groups, g.FullPath) {
+					groups = append(groups, g.FullPath)
+				}
+			}
+		}
+	}
+	slices.Sort(users)
+	slices.Sort(groups)
+	return users, groups
+}
+
+// WriteApproverDataSources writes the data sources that resolve approval
+// rule users and groups to IDs.
+func WriteApproverDataSources(users, groups []string, w io.Writer) error {
+	f := hclwrite.NewEmptyFile()
+	rootBody := f.Body()
+
+	if len(users) > 0 {
+		body := rootBody.AppendNewBlock("data", []string{"gitlab_user", "approvers"}).Body()
+		body.SetAttributeRaw("for_each", tokensForStringSet(users))
+		body.SetAttributeTraversal("username", hcl.Traversal{
+			hcl.TraverseRoot{Name: "each"},
+			hcl.TraverseAttr{Name: "key"},
+		})
+	}
+
+	if len(groups) > 0 {
+		if len(users) > 0 {
+			rootBody.AppendNewline()
+		}
+		body := rootBody.AppendNewBlock("data", []string{"gitlab_group", "approvers"}).Body()
+		body.SetAttributeRaw("for_each", tokensForStringSet(groups))
+		body.SetAttributeTraversal("full_path", hcl.Traversal{
+			hcl.TraverseRoot{Name: "each"},
+			hcl.TraverseAttr{Name: "key"},
+		})
+	}
+
+	_, err := w.Write(f.Bytes())
+	return err
+}
+
+// WriteProjectApprovalRules writes approval rules for a project. Protected
+// branches in branchNames, which maps branch names to the generated
+// gitlab_branch_protection resources, are referenced through them; all
+// others by ID.
+func WriteProjectApprovalRules(p *gl.Project, rules []*gl.ProjectApprovalRule, w io.Writer, branchNames map[string]string) error {
+	f := hclwrite.NewEmptyFile()
+	rootBody := f.Body()
+	projName := projectResourceName(p)
+	names := approvalRuleNames(p, rules)
+
+	for i, r := range manageableApprovalRules(rules) {
+		if i > 0 {
+			rootBody.AppendNewline()
+		}
+		name := names[r.ID]
+		block := rootBody.AppendNewBlock("resource", []string{"gitlab_project_approval_rule", name})
+		body := block.Body()
+
+		body.SetAttributeTraversal("project", hcl.Traversal{
+			hcl.TraverseRoot{Name: "gitlab_project"},
+			hcl.TraverseAttr{Name: projName},
+			hcl.TraverseAttr{Name: "id"},
+		})
+		body.SetAttributeValue("name", cty.StringVal(r.Name))
+		body.SetAttributeValue("approvals_required", cty.NumberIntVal(r.ApprovalsRequired))
+		if r.RuleType != "" && r.RuleType != "regular" {
+			body.SetAttributeValue("rule_type", cty.StringVal(r.RuleType))
+		}
+		if r.ReportType != "" {
+			body.SetAttributeValue("report_type", cty.StringVal(r.ReportType))
+		}
+
+		var userIDs []hclwrite.Tokens
+		for _, u := range r.Users {
+			if u == nil {
+				continue
+			}
+			userIDs = append(userIDs, hclwrite.TokensForTraversal(dataSourceIDTraversal("gitlab_user", u.Username)))
+		}
+		if len(userIDs) > 0 {
+			body.SetAttributeRaw("user_ids", hclwrite.TokensForTuple(userIDs))
+		}
+
+		var groupIDs []hclwrite.Tokens
+		for _, g := range r.Groups {
+			if g == nil {
+				continue
+			}
+			groupIDs = append(groupIDs, hclwrite.TokensForTraversal(dataSourceIDTraversal("gitlab_group", g.FullPath)))
+		}
+		if len(groupIDs) > 0 {
+			body.SetAttributeRaw("group_ids", hclwrite.TokensForTuple(groupIDs))
+		}
+
+		if r.AppliesToAllProtectedBranches {
+			body.SetAttributeValue("applies_to_all_protected_branches", cty.True)
+		} else if len(r.ProtectedBranches) > 0 {
+			branchIDs := make([]hclwrite.Tokens, 0, len(r.ProtectedBranches))
+			for _, b := range r.ProtectedBranches {
+				if b == nil {
+					continue
+				}
+				if name, ok := branchNames[b.Name]; ok {
+					branchIDs = append(branchIDs, hclwrite.TokensForTraversal(hcl.Traversal{
+						hcl.TraverseRoot{Name: "gitlab_branch_protection"},
+						hcl.TraverseAttr{Name: name},
+						hcl.TraverseAttr{Name: "branch_protection_id"},
+					}))
+				} else {
+					branchIDs = append(branchIDs, hclwrite.TokensForValue(cty.NumberIntVal(b.ID)))
+				}
+			}
+			body.SetAttributeRaw("protected_branch_ids", hclwrite.TokensForTuple(branchIDs))
+		}
+	}
+
+	_, err := w.Write(f.Bytes())
+	return err
+}
+
+func mrApprovalsResourceName(p *gl.Project) string {
+	return projectResourceName(p)
+}
+
+// isDefaultMRApprovals checks if the approval settings are the GitLab defaults.
+// Defaults: reset_approvals_on_push=true, everything else false.
+func isDefaultMRApprovals(a *gl.ProjectApprovals) bool {
+	if a == nil {
+		return true
+	}
+	return a.ResetApprovalsOnPush &&
+		!a.DisableOverridingApproversPerMergeRequest &&
+		!a.MergeRequestsAuthorApproval &&
+		!a.MergeRequestsDisableCommittersApproval &&
+		!a.RequirePasswordToApprove &&
+		!a.SelectiveCodeOwnerRemovals
+}
+
+func WriteProjectMRApprovals(p *gl.Project, a *gl.ProjectApprovals, w io.Writer) error {
+	f := hclwrite.NewEmptyFile()
+	rootBody := f.Body()
+
+	block := rootBody.AppendNewBlock("resource", []string{"gitlab_project_level_mr_approvals", mrApprovalsResourceName(p)})
+	body := block.Body()
+
+	body.SetAttributeTraversal("project", hcl.Traversal{
+		hcl.TraverseRoot{Name: "gitlab_project"},
+		hcl.TraverseAttr{Name: projectResourceName(p)},
+		hcl.TraverseAttr{Name: "id"},
+	})
+	body.SetAttributeValue("reset_approvals_on_push", cty.BoolVal(a.ResetApprovalsOnPush))
+	body.SetAttributeValue("disable_overriding_approvers_per_merge_request", cty.BoolVal(a.DisableOverridingApproversPerMergeRequest))
+	body.SetAttributeValue("merge_requests_author_approval", cty.BoolVal(a.MergeRequestsAuthorApproval))
+	body.SetAttributeValue("merge_requests_disable_committers_approval", cty.BoolVal(a.MergeRequestsDisableCommittersApproval))
+	body.SetAttributeValue("require_password_to_approve", cty.BoolVal(a.RequirePasswordToApprove))
+	body.SetAttributeValue("selective_code_owner_removals", cty.BoolVal(a.SelectiveCodeOwnerRemovals))
+
+	_, err := w.Write(f.Bytes())
+	return err
+}
+
+func dataSourceIDTraversal(dataType, key string) hcl.Traversal {
+	return hcl.Traversal{
+		hcl.TraverseRoot{Name: "data"},
+		hcl.TraverseAttr{Name: dataType},
+		hcl.TraverseAttr{Name: "approvers"},
+		hcl.TraverseIndex{Key: cty.StringVal(key)},
+		hcl.TraverseAttr{Name: "id"},
+	}
+}
+
+// tokensForStringSet renders values as a toset([...]) expression, which
+// for_each accepts unlike a plain tuple.
+func tokensForStringSet(values []string) hclwrite.Tokens {
+	elems := make([]cty.Value, len(values))
+	for i, v := range values {
+		elems[i] = cty.StringVal(v)
+	}
+	return hclwrite.TokensForFunctionCall("toset", hclwrite.TokensForValue(cty.ListVal(elems)))
+}
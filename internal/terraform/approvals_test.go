@@ -0,0 +1,150 @@
+package terraform
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestWriteApproverDataSources(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteApproverDataSources([]string{"alice", "bob"}, []string{"my-group/reviewers"}, &buf); err != nil {
+		t.Fatalf("WriteApproverDataSources error: %v", err)
+	}
+
+	compareGolden(t, "approver_data_sources.tf", buf.String())
+}
+
+func TestWriteProjectApprovalRules(t *testing.T) {
+	project := &gl.Project{
+		ID:                1,
+		Path:              "my-project",
+		Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+		PathWithNamespace: "my-group/my-project",
+	}
+
+	rules := []*gl.ProjectApprovalRule{
+		{
+			ID:                5,
+			Name:              "Security",
+			RuleType:          "regular",
+			ApprovalsRequired: 2,
+			Users:             []*gl.BasicUser{{ID: 42, Username: "alice"}},
+			Groups:            []*gl.Group{{ID: 30, FullPath: "my-group/reviewers"}},
+			ProtectedBranches: []*gl.ProtectedBranch{{ID: 7, Name: "main"}},
+		},
+		{
+			ID:                            6,
+			Name:                          "All Members",
+			RuleType:                      "any_approver",
+			ApprovalsRequired:             1,
+			AppliesToAllProtectedBranches: true,
+		},
+		{
+			ID:       8,
+			Name:     "Code Owners",
+			RuleType: codeOwnerRuleType,
+		},
+	}
+
+	var buf bytes.Buffer
+	branchNames := branchProtectionNames(project, []*gl.ProtectedBranch{{ID: 7, Name: "main"}})
+	if err := WriteProjectApprovalRules(project, rules, &buf, branchNames); err != nil {
+		t.Fatalf("WriteProjectApprovalRules error: %v", err)
+	}
+
+	compareGolden(t, "project_approval_rules.tf", buf.String())
+}
+
+func TestApprovalRuleNamesUnique(t *testing.T) {
+	project := &gl.Project{ID: 1, Path: "my-project", Namespace: &gl.ProjectNamespace{FullPath: "my-group"}}
+	rules := []*gl.ProjectApprovalRule{
+		{ID: 3, Name: "qa-review"},
+		{ID: 2, Name: "QA review"},
+		{ID: 1, Name: "Security"},
+		{ID: 4, Name: "CODEOWNERS", RuleType: codeOwnerRuleType},
+	}
+
+	got := approvalRuleNames(project, rules)
+	want := map[int64]string{
+		1: "my_group_my_project_security",
+		2: "my_group_my_project_qa_review",
+		3: "my_group_my_project_qa_review_2",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for id, name := range want {
+		if got[id] != name {
+			t.Errorf("name of rule %d = %q, want %q", id, got[id], name)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectApprovalRules(project, rules, &buf, nil); err != nil {
+		t.Fatalf("WriteProjectApprovalRules error: %v", err)
+	}
+	for _, name := range want {
+		if n := strings.Count(buf.String(), `"`+name+`"`); n != 1 {
+			t.Errorf("resource %s declared %d times:\n%s", name, n, buf.String())
+		}
+	}
+}
+
+func TestApproverNames(t *testing.T) {
+	projects := []*gl.Project{{ID: 1}, {ID: 2}}
+	rules := map[int64][]*gl.ProjectApprovalRule{
+		1: {{Users: []*gl.BasicUser{{Username: "bob"}, {Username: "alice"}}}},
+		2: {
+			{Users: []*gl.BasicUser{{Username: "alice"}}, Groups: []*gl.Group{{FullPath: "grp/reviewers"}}},
+			{RuleType: codeOwnerRuleType, Users: []*gl.BasicUser{{Username: "carol"}}},
+		},
+	}
+
+	users, groups := approverNames(projects, rules)
+
+	if len(users) != 2 || users[0] != "alice" || users[1] != "bob" {
+		t.Errorf("users = %v, want [alice bob]", users)
+	}
+	if len(groups) != 1 || groups[0] != "grp/reviewers" {
+		t.Errorf("groups = %v, want [grp/reviewers]", groups)
+	}
+}
+
+func TestWriteProjectMRApprovals(t *testing.T) {
+	project := &gl.Project{
+		ID:                1,
+		Path:              "my-project",
+		Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+		PathWithNamespace: "my-group/my-project",
+	}
+
+	approvals := &gl.ProjectApprovals{
+		ResetApprovalsOnPush:                   true,
+		MergeRequestsDisableCommittersApproval: true,
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectMRApprovals(project, approvals, &buf); err != nil {
+		t.Fatalf("WriteProjectMRApprovals error: %v", err)
+	}
+
+	compareGolden(t, "project_mr_approvals.tf", buf.String())
+}
+
+func TestIsDefaultMRApprovals(t *testing.T) {
+	if !isDefaultMRApprovals(nil) {
+		t.Error("nil settings should be default")
+	}
+	if !isDefaultMRApprovals(&gl.ProjectApprovals{ResetApprovalsOnPush: true}) {
+		t.Error("reset_approvals_on_push=true only should be default")
+	}
+	if isDefaultMRApprovals(&gl.ProjectApprovals{}) {
+		t.Error("reset_approvals_on_push=false should not be default")
+	}
+	if isDefaultMRApprovals(&gl.ProjectApprovals{ResetApprovalsOnPush: true, RequirePasswordToApprove: true}) {
+		t.Error("require_password_to_approve=true should not be default")
+	}
+}
@@ -0,0 +1,216 @@
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// normalizeRefName normalizes a branch or tag name, which may contain
+// wildcards such as "release/*", into a terraform name fragment.
+func normalizeRefName(ref string) string {
+	return normalizeName(strings.ReplaceAll(ref, "*", "wildcard"))
+}
+
+// refResourceNames maps the protected branches or tags of a project to their
+// resource names. Refs that normalize to the same name, e.g. "release-*" and
+// "release-wildcard", get a numeric suffix in sorted ref order, so the names
+// do not depend on the order GitLab returns them in.
+func refResourceNames(p *gl.Project, refs []string) map[string]string {
+	refs = slices.Clone(refs)
+	slices.Sort(refs)
+	names := make(map[string]string, len(refs))
+	taken := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		if _, ok := names[ref]; ok {
+			continue
+		}
+		base := projectResourceName(p) + "_" + normalizeRefName(ref)
+		name := base
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		taken[name] = true
+		names[ref] = name
+	}
+	return names
+}
+
+func branchProtectionNames(p *gl.Project, branches []*gl.ProtectedBranch) map[string]string {
+	refs := make([]string, 0, len(branches))
+	for _, b := range branches {
+		if b != nil {
+			refs = append(refs, b.Name)
+		}
+	}
+	return refResourceNames(p, refs)
+}
+
+func tagProtectionNames(p *gl.Project, tags []*gl.ProtectedTag) map[string]string {
+	refs := make([]string, 0, len(tags))
+	for _, t := range tags {
+		if t != nil {
+			refs = append(refs, t.Name)
+		}
+	}
+	return refResourceNames(p, refs)
+}
+
+// accessGrant is a user, group or deploy key entry of a protected ref.
+type accessGrant struct {
+	UserID      int64
+	GroupID     int64
+	DeployKeyID int64
+}
+
+// splitBranchAccess separates the role-based access level from the per-user,
+// per-group and per-deploy-key entries of a protected branch.
+func splitBranchAccess(levels []*gl.BranchAccessDescription) (*gl.AccessLevelValue, []accessGrant) {
+	var role *gl.AccessLevelValue
+	var grants []accessGrant
+	for _, l := range levels {
+		if l == nil {
+			continue
+		}
+		if l.UserID == 0 && l.GroupID == 0 && l.DeployKeyID == 0 {
+			if role == nil {
+				role = gl.Ptr(l.AccessLevel)
+			}
+			continue
+		}
+		grants = append(grants, accessGrant{UserID: l.UserID, GroupID: l.GroupID, DeployKeyID: l.DeployKeyID})
+	}
+	return role, grants
+}
+
+func splitTagAccess(levels []*gl.TagAccessDescription) (*gl.AccessLevelValue, []accessGrant) {
+	var role *gl.AccessLevelValue
+	var grants []accessGrant
+	for _, l := range levels {
+		if l == nil {
+			continue
+		}
+		if l.UserID == 0 && l.GroupID == 0 && l.DeployKeyID == 0 {
+			if role == nil {
+				role = gl.Ptr(l.AccessLevel)
+			}
+			continue
+		}
+		grants = append(grants, accessGrant{UserID: l.UserID, GroupID: l.GroupID, DeployKeyID: l.DeployKeyID})
+	}
+	return role, grants
+}
+
+func WriteBranchProtections(p *gl.Project, branches []*gl.ProtectedBranch, w io.Writer, groupRefs groupRefMap) error {
+	f := hclwrite.NewEmptyFile()
+	rootBody := f.Body()
+	projName := projectResourceName(p)
+	names := branchProtectionNames(p, branches)
+
+	first := true
+	for _, b := range branches {
+		if b == nil {
+			continue
+		}
+		if !first {
+			rootBody.AppendNewline()
+		}
+		first = false
+		name := names[b.Name]
+		block := rootBody.AppendNewBlock("resource", []string{"gitlab_branch_protection", name})
+		body := block.Body()
+
+		body.SetAttributeTraversal("project", hcl.Traversal{
+			hcl.TraverseRoot{Name: "gitlab_project"},
+			hcl.TraverseAttr{Name: projName},
+			hcl.TraverseAttr{Name: "id"},
+		})
+		body.SetAttributeValue("branch", cty.StringVal(b.Name))
+
+		pushRole, pushGrants := splitBranchAccess(b.PushAccessLevels)
+		mergeRole, mergeGrants := splitBranchAccess(b.MergeAccessLevels)
+		unprotectRole, unprotectGrants := splitBranchAccess(b.UnprotectAccessLevels)
+
+		if pushRole != nil {
+			body.SetAttributeValue("push_access_level", cty.StringVal(accessLevelToString(*pushRole)))
+		}
+		if mergeRole != nil {
+			body.SetAttributeValue("merge_access_level", cty.StringVal(accessLevelToString(*mergeRole)))
+		}
+		if unprotectRole != nil {
+			body.SetAttributeValue("unprotect_access_level", cty.StringVal(accessLevelToString(*unprotectRole)))
+		}
+		if b.AllowForcePush {
+			body.SetAttributeValue("allow_force_push", cty.BoolVal(b.AllowForcePush))
+		}
+		if b.CodeOwnerApprovalRequired {
+			body.SetAttributeValue("code_owner_approval_required", cty.BoolVal(b.CodeOwnerApprovalRequired))
+		}
+
+		writeAccessGrants(body, "allowed_to_push", pushGrants, groupRefs)
+		writeAccessGrants(body, "allowed_to_merge", mergeGrants, groupRefs)
+		writeAccessGrants(body, "allowed_to_unprotect", unprotectGrants, groupRefs)
+	}
+
+	_, err := w.Write(f.Bytes())
+	return err
+}
+
+func WriteTagProtections(p *gl.Project, tags []*gl.ProtectedTag, w io.Writer, groupRefs groupRefMap) error {
+	f := hclwrite.NewEmptyFile()
+	rootBody := f.Body()
+	projName := projectResourceName(p)
+	names := tagProtectionNames(p, tags)
+
+	first := true
+	for _, t := range tags {
+		if t == nil {
+			continue
+		}
+		if !first {
+			rootBody.AppendNewline()
+		}
+		first = false
+		name := names[t.Name]
+		block := rootBody.AppendNewBlock("resource", []string{"gitlab_tag_protection", name})
+		body := block.Body()
+
+		body.SetAttributeTraversal("project", hcl.Traversal{
+			hcl.TraverseRoot{Name: "gitlab_project"},
+			hcl.TraverseAttr{Name: projName},
+			hcl.TraverseAttr{Name: "id"},
+		})
+		body.SetAttributeValue("tag", cty.StringVal(t.Name))
+
+		createRole, createGrants := splitTagAccess(t.CreateAccessLevels)
+		if createRole != nil {
+			body.SetAttributeValue("create_access_level", cty.StringVal(accessLevelToString(*createRole)))
+		}
+
+		writeAccessGrants(body, "allowed_to_create", createGrants, groupRefs)
+	}
+
+	_, err := w.Write(f.Bytes())
+	return err
+}
+
+func writeAccessGrants(body *hclwrite.Body, blockType string, grants []accessGrant, groupRefs groupRefMap) {
+	for _, g := range grants {
+		body.AppendNewline()
+		grantBody := body.AppendNewBlock(blockType, nil).Body()
+		switch {
+		case g.UserID != 0:
+			grantBody.SetAttributeValue("user_id", cty.NumberIntVal(g.UserID))
+		case g.GroupID != 0:
+			setGroupIDAttribute(grantBody, "group_id", g.GroupID, groupRefs)
+		case g.DeployKeyID != 0:
+			grantBody.SetAttributeValue("deploy_key_id", cty.NumberIntVal(g.DeployKeyID))
+		}
+	}
+}
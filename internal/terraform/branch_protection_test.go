@@ -0,0 +1,158 @@
+package terraform
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestNormalizeRefName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"main", "main"},
+		{"release/*", "release_wildcard"},
+		{"v*", "vwildcard"},
+		{"feature/Foo-Bar", "feature_foo_bar"},
+	}
+	for _, tt := range tests {
+		got := normalizeRefName(tt.input)
+		if got != tt.want {
+			t.Errorf("normalizeRefName(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestWriteBranchProtections(t *testing.T) {
+	project := &gl.Project{
+		ID:                1,
+		Path:              "my-project",
+		Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+		PathWithNamespace: "my-group/my-project",
+	}
+
+	branches := []*gl.ProtectedBranch{
+		{
+			ID:   1,
+			Name: "main",
+			PushAccessLevels: []*gl.BranchAccessDescription{
+				{AccessLevel: gl.NoPermissions},
+				{AccessLevel: gl.MaintainerPermissions, UserID: 42},
+				{AccessLevel: gl.MaintainerPermissions, GroupID: 10},
+			},
+			MergeAccessLevels: []*gl.BranchAccessDescription{
+				{AccessLevel: gl.DeveloperPermissions},
+			},
+			UnprotectAccessLevels: []*gl.BranchAccessDescription{
+				{AccessLevel: gl.MaintainerPermissions},
+				{AccessLevel: gl.MaintainerPermissions, GroupID: 99},
+			},
+			CodeOwnerApprovalRequired: true,
+		},
+		{
+			ID:   2,
+			Name: "release/*",
+			PushAccessLevels: []*gl.BranchAccessDescription{
+				{AccessLevel: gl.MaintainerPermissions},
+				{AccessLevel: gl.MaintainerPermissions, DeployKeyID: 7},
+			},
+			MergeAccessLevels: []*gl.BranchAccessDescription{
+				{AccessLevel: gl.MaintainerPermissions},
+			},
+			AllowForcePush: true,
+		},
+	}
+
+	refs := groupRefMap{10: "my_group"}
+
+	var buf bytes.Buffer
+	if err := WriteBranchProtections(project, branches, &buf, refs); err != nil {
+		t.Fatalf("WriteBranchProtections error: %v", err)
+	}
+
+	compareGolden(t, "branch_protection.tf", buf.String())
+}
+
+func TestBranchProtectionNamesUnique(t *testing.T) {
+	project := &gl.Project{ID: 1, Path: "my-project", Namespace: &gl.ProjectNamespace{FullPath: "my-group"}}
+	branches := []*gl.ProtectedBranch{
+		{Name: "release-wildcard"},
+		{Name: "release-*"},
+		{Name: "main"},
+	}
+
+	got := branchProtectionNames(project, branches)
+	want := map[string]string{
+		"main":             "my_group_my_project_main",
+		"release-*":        "my_group_my_project_release_wildcard",
+		"release-wildcard": "my_group_my_project_release_wildcard_2",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for ref, name := range want {
+		if got[ref] != name {
+			t.Errorf("name of %q = %q, want %q", ref, got[ref], name)
+		}
+	}
+}
+
+func TestWriteTagProtections(t *testing.T) {
+	project := &gl.Project{
+		ID:                1,
+		Path:              "my-project",
+		Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+		PathWithNamespace: "my-group/my-project",
+	}
+
+	tags := []*gl.ProtectedTag{
+		{
+			Name: "v*",
+			CreateAccessLevels: []*gl.TagAccessDescription{
+				{AccessLevel: gl.MaintainerPermissions},
+				{AccessLevel: gl.MaintainerPermissions, UserID: 42},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTagProtections(project, tags, &buf, nil); err != nil {
+		t.Fatalf("WriteTagProtections error: %v", err)
+	}
+
+	compareGolden(t, "tag_protection.tf", buf.String())
+}
+
+func TestWriteRefProtectionsSkipNil(t *testing.T) {
+	project := &gl.Project{ID: 1, Path: "my-project", Namespace: &gl.ProjectNamespace{FullPath: "my-group"}}
+
+	var branchBuf bytes.Buffer
+	branches := []*gl.ProtectedBranch{nil, {Name: "main"}, nil, {Name: "develop"}}
+	if err := WriteBranchProtections(project, branches, &branchBuf, nil); err != nil {
+		t.Fatalf("WriteBranchProtections error: %v", err)
+	}
+
+	var tagBuf bytes.Buffer
+	tags := []*gl.ProtectedTag{nil, {Name: "v*"}}
+	if err := WriteTagProtections(project, tags, &tagBuf, nil); err != nil {
+		t.Fatalf("WriteTagProtections error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		out    string
+		blocks int
+	}{
+		{branchBuf.String(), 2},
+		{tagBuf.String(), 1},
+	} {
+		if !strings.HasPrefix(tt.out, "resource ") {
+			t.Errorf("output does not start with a resource block:\n%s", tt.out)
+		}
+		if got := strings.Count(tt.out, "resource "); got != tt.blocks {
+			t.Errorf("got %d resource blocks, want %d:\n%s", got, tt.blocks, tt.out)
+		}
+	}
+}
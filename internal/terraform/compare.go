@@ -0,0 +1,437 @@
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// ChangeKind describes how a block or attribute differs between the existing
+// and the generated configuration.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// AttributeChange is a single attribute, map entry or nested block attribute
+// that differs. Path is relative to the block, e.g. `default["my-group"]`.
+type AttributeChange struct {
+	Path string
+	Kind ChangeKind
+	Old  string
+	New  string
+}
+
+// BlockChange is a top-level block that was added, removed or changed.
+// File and Line locate the generated block for added and changed blocks and
+// the existing block for removed ones.
+type BlockChange struct {
+	Address    string
+	File       string
+	Line       int
+	Kind       ChangeKind
+	Attributes []AttributeChange
+}
+
+// configBlock is a parsed top-level block reduced to comparable attributes.
+type configBlock struct {
+	file  string
+	line  int
+	attrs map[string]*exprNode
+}
+
+// exprNode is the normalized form of an expression or nested block. Object
+// constructors and blocks keep their children so maps can be compared key
+// by key regardless of order.
+type exprNode struct {
+	canon    string
+	text     string
+	children map[string]*exprNode
+}
+
+// CompareDirs compares the generated .tf files in generatedDir with the files
+// of the same name in existingDir. Blocks are matched by address, so moving a
+// block between these files or reformatting it is not reported as drift.
+// Generated resources renamed to the existing addresses in matches are
+// compared like any other block, but not reported as added when they are
+// declared in an existing file of another name.
+func CompareDirs(existingDir, generatedDir string, matches map[string]string) ([]BlockChange, error) {
+	files, err := filepath.Glob(filepath.Join(generatedDir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("listing generated files: %w", err)
+	}
+
+	generated := make(map[string]*configBlock)
+	existing := make(map[string]*configBlock)
+	for _, genFile := range files {
+		if err := parseConfigBlocks(genFile, generated); err != nil {
+			return nil, err
+		}
+
+		existingFile := filepath.Join(existingDir, filepath.Base(genFile))
+		if _, err := os.Stat(existingFile); os.IsNotExist(err) {
+			continue
+		}
+		if err := parseConfigBlocks(existingFile, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := compareBlocks(existing, generated)
+	if len(matches) == 0 {
+		return changes, nil
+	}
+	matched := make(map[string]bool, len(matches))
+	for _, addr := range matches {
+		matched[addr] = true
+	}
+	return slices.DeleteFunc(changes, func(c BlockChange) bool {
+		return c.Kind == ChangeAdded && matched[c.Address]
+	}), nil
+}
+
+// ParseBlockLocations reads all .tf files in dir and returns where each
+// top-level block is declared, by the address CompareDirs reports it under.
+func ParseBlockLocations(dir string) (map[string]Location, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("listing tf files: %w", err)
+	}
+	blocks := make(map[string]*configBlock)
+	for _, path := range files {
+		if err := parseConfigBlocks(path, blocks); err != nil {
+			return nil, err
+		}
+	}
+	locations := make(map[string]Location, len(blocks))
+	for addr, block := range blocks {
+		locations[addr] = Location{File: block.file, Line: block.line}
+	}
+	return locations, nil
+}
+
+func compareBlocks(existing, generated map[string]*configBlock) []BlockChange {
+	var changes []BlockChange
+	for addr, gen := range generated {
+		old, ok := existing[addr]
+		if !ok {
+			changes = append(changes, BlockChange{Address: addr, File: gen.file, Line: gen.line, Kind: ChangeAdded})
+			continue
+		}
+		var attrs []AttributeChange
+		diffChildren("", old.attrs, gen.attrs, &attrs)
+		if len(attrs) > 0 {
+			changes = append(changes, BlockChange{Address: addr, File: gen.file, Line: gen.line, Kind: ChangeChanged, Attributes: attrs})
+		}
+	}
+	for addr, old := range existing {
+		if _, ok := generated[addr]; !ok {
+			changes = append(changes, BlockChange{Address: addr, File: old.file, Line: old.line, Kind: ChangeRemoved})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b BlockChange) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Address, b.Address)
+	})
+	return changes
+}
+
+func diffChildren(path string, old, gen map[string]*exprNode, out *[]AttributeChange) {
+	keys := make([]string, 0, len(old)+len(gen))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range gen {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		diffNodes(joinPath(path, k), old[k], gen[k], out)
+	}
+}
+
+func diffNodes(path string, old, gen *exprNode, out *[]AttributeChange) {
+	switch {
+	case old == nil:
+		*out = append(*out, AttributeChange{Path: path, Kind: ChangeAdded, New: gen.text})
+	case gen == nil:
+		*out = append(*out, AttributeChange{Path: path, Kind: ChangeRemoved, Old: old.text})
+	case old.children != nil && gen.children != nil:
+		diffChildren(path, old.children, gen.children, out)
+	case old.canon != gen.canon:
+		*out = append(*out, AttributeChange{Path: path, Kind: ChangeChanged, Old: old.text, New: gen.text})
+	}
+}
+
+// joinPath appends a child segment to path. Object keys are already
+// bracketed, attribute and block names are joined with a dot.
+func joinPath(path, segment string) string {
+	if path == "" || strings.HasPrefix(segment, "[") {
+		return path + segment
+	}
+	return path + "." + segment
+}
+
+// blockAddress returns the address used to match top-level blocks, following
+// terraform's reference syntax where one exists. Import and removed blocks
+// have no labels and are matched by the address they refer to.
+func blockAddress(block *hclsyntax.Block, src []byte) string {
+	switch block.Type {
+	case "import":
+		if to, ok := block.Body.Attributes["to"]; ok {
+			return "import." + compactSource(to.Expr.Range(), src)
+		}
+		return "import"
+	case "removed":
+		if from, ok := block.Body.Attributes["from"]; ok {
+			return "removed." + compactSource(from.Expr.Range(), src)
+		}
+		return "removed"
+	case "resource":
+		return strings.Join(block.Labels, ".")
+	case "data":
+		return "data." + strings.Join(block.Labels, ".")
+	case "variable":
+		return "var." + strings.Join(block.Labels, ".")
+	default:
+		return strings.Join(append([]string{block.Type}, block.Labels...), ".")
+	}
+}
+
+func parseConfigBlocks(path string, blocks map[string]*configBlock) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	f, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return fmt.Errorf("parsing %s: %s", path, diags.Error())
+	}
+
+	file := filepath.Base(path)
+	for _, block := range f.Body.(*hclsyntax.Body).Blocks {
+		addr := blockAddress(block, src)
+		attrs := bodyNodes(block.Body, src)
+
+		// All locals blocks share one namespace, so they are merged.
+		if existing, ok := blocks[addr]; ok && block.Type == "locals" {
+			for k, v := range attrs {
+				existing.attrs[k] = v
+			}
+			continue
+		}
+		blocks[addr] = &configBlock{file: file, line: block.DefRange().Start.Line, attrs: attrs}
+	}
+	return nil
+}
+
+// bodyNodes normalizes the attributes and nested blocks of a body. Repeated
+// nested blocks of the same type are sorted by content, so their order does
+// not matter.
+func bodyNodes(body *hclsyntax.Body, src []byte) map[string]*exprNode {
+	nodes := make(map[string]*exprNode, len(body.Attributes))
+	for name, attr := range body.Attributes {
+		nodes[name] = exprToNode(attr.Expr, src)
+	}
+
+	byType := make(map[string][]*exprNode)
+	var types []string
+	for _, nested := range body.Blocks {
+		key := strings.Join(append([]string{nested.Type}, nested.Labels...), ".")
+		if _, ok := byType[key]; !ok {
+			types = append(types, key)
+		}
+		children := bodyNodes(nested.Body, src)
+		byType[key] = append(byType[key], &exprNode{
+			canon:    canonicalChildren(children),
+			text:     key + " {...}",
+			children: children,
+		})
+	}
+	for _, key := range types {
+		list := byType[key]
+		slices.SortFunc(list, func(a, b *exprNode) int { return strings.Compare(a.canon, b.canon) })
+		for i, n := range list {
+			nodes[fmt.Sprintf("%s[%d]", key, i)] = n
+		}
+	}
+	return nodes
+}
+
+func exprToNode(expr hclsyntax.Expression, src []byte) *exprNode {
+	if wrap, ok := expr.(*hclsyntax.TemplateWrapExpr); ok {
+		return exprToNode(wrap.Wrapped, src)
+	}
+
+	if obj, ok := expr.(*hclsyntax.ObjectConsExpr); ok {
+		children := make(map[string]*exprNode, len(obj.Items))
+		for _, item := range obj.Items {
+			key := exprToNode(item.KeyExpr, src).canon
+			if v, diags := item.KeyExpr.Value(nil); !diags.HasErrors() && v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+				key = fmt.Sprintf("[%q]", v.AsString())
+			}
+			children[key] = exprToNode(item.ValueExpr, src)
+		}
+		return &exprNode{
+			canon:    canonicalChildren(children),
+			text:     compactSource(expr.Range(), src),
+			children: children,
+		}
+	}
+
+	text := compactSource(expr.Range(), src)
+	if v, diags := expr.Value(nil); !diags.HasErrors() && v.IsWhollyKnown() {
+		if data, err := ctyjson.Marshal(v, v.Type()); err == nil {
+			return &exprNode{canon: string(data), text: text}
+		}
+	}
+	return &exprNode{canon: canonicalTokens(expr.Range(), src), text: text}
+}
+
+func canonicalChildren(children map[string]*exprNode) string {
+	keys := make([]string, 0, len(children))
+	for k := range children {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var b strings.Builder
+	b.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(k + "=" + children[k].canon)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// canonicalTokens renders an expression that cannot be evaluated statically,
+// such as a reference or function call, without whitespace and comments.
+func canonicalTokens(rng hcl.Range, src []byte) string {
+	tokens, _ := hclsyntax.LexExpression(rng.SliceBytes(src), rng.Filename, rng.Start)
+	parts := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		switch t.Type {
+		case hclsyntax.TokenNewline, hclsyntax.TokenComment, hclsyntax.TokenEOF:
+			continue
+		}
+		parts = append(parts, string(t.Bytes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// compactSource returns the source text of an expression on a single line.
+func compactSource(rng hcl.Range, src []byte) string {
+	return strings.Join(strings.Fields(string(rng.SliceBytes(src))), " ")
+}
+
+// companionVariableSuffixes are appended to the name of a for_each variable
+// for maps keyed like it, e.g. var.gitlab_group_share_group_expires_at.
+var companionVariableSuffixes = []string{"_expires_at", "_secrets"}
+
+// InstanceChanges maps the changed entries of for_each variable maps to the
+// resource instances iterating them, e.g. a changed `default["my-group"]["jdoe"]`
+// of var.gitlab_group_membership to `gitlab_group_membership.my_group["jdoe"]`.
+// locations are the generated resources as returned by ParseResourceLocations.
+// Paths keep the variable address as prefix, since they are not relative to
+// the resource block.
+func InstanceChanges(changes []BlockChange, locations map[string]Location) map[string][]AttributeChange {
+	iterated := make(map[string]string)
+	for addr, loc := range locations {
+		if loc.ForEach != "" {
+			iterated[loc.ForEach] = addr
+		}
+	}
+
+	result := make(map[string][]AttributeChange)
+	for _, c := range changes {
+		name, ok := strings.CutPrefix(c.Address, "var.")
+		if !ok || c.Kind != ChangeChanged {
+			continue
+		}
+		for _, a := range c.Attributes {
+			segments := pathSegments(a.Path)
+			if len(segments) < 3 || segments[0] != "default" {
+				continue
+			}
+			block, ok := iteratingResource(iterated, name, segments[1])
+			if !ok {
+				continue
+			}
+			addr := fmt.Sprintf("%s[%q]", block, segments[2])
+			a.Path = joinPath(c.Address, a.Path)
+			result[addr] = append(result[addr], a)
+		}
+	}
+	return result
+}
+
+// iteratingResource returns the resource iterating entry key of variable
+// name, or of the variable name is a companion to.
+func iteratingResource(iterated map[string]string, name, key string) (string, bool) {
+	if block, ok := iterated[fmt.Sprintf("var.%s[%q]", name, key)]; ok {
+		return block, true
+	}
+	for _, suffix := range companionVariableSuffixes {
+		if base, ok := strings.CutSuffix(name, suffix); ok {
+			if block, ok := iterated[fmt.Sprintf("var.%s[%q]", base, key)]; ok {
+				return block, true
+			}
+		}
+	}
+	return "", false
+}
+
+// PrintDrift writes changes in a plan-like format.
+func PrintDrift(w io.Writer, changes []BlockChange) error {
+	for _, c := range changes {
+		if _, err := fmt.Fprintf(w, "%s %s (%s)\n", changeSymbol(c.Kind), c.Address, c.File); err != nil {
+			return err
+		}
+		for _, a := range c.Attributes {
+			var err error
+			switch a.Kind {
+			case ChangeAdded:
+				_, err = fmt.Fprintf(w, "    + %s = %s\n", a.Path, a.New)
+			case ChangeRemoved:
+				_, err = fmt.Fprintf(w, "    - %s = %s\n", a.Path, a.Old)
+			default:
+				_, err = fmt.Fprintf(w, "    ~ %s: %s => %s\n", a.Path, a.Old, a.New)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func changeSymbol(kind ChangeKind) string {
+	switch kind {
+	case ChangeAdded:
+		return "+"
+	case ChangeRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
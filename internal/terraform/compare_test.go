@@ -0,0 +1,432 @@
+package terraform
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func compareTestDirs(t *testing.T, existing, generated map[string]string) []BlockChange {
+	t.Helper()
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+	writeTestFiles(t, existingDir, existing)
+	writeTestFiles(t, generatedDir, generated)
+
+	changes, err := CompareDirs(existingDir, generatedDir, nil)
+	if err != nil {
+		t.Fatalf("CompareDirs error: %v", err)
+	}
+	return changes
+}
+
+func TestCompareDirsIgnoresFormattingAndOrder(t *testing.T) {
+	existing := `# managed by hand
+resource "gitlab_group" "my_group" {
+  path = "my-group"
+  name      = "My Group"
+  description = <<-EOT
+    Team group
+  EOT
+}
+
+variable "gitlab_group_membership" {
+  default = {
+    "my-group" = { "bob" = "developer", "alice" = "owner" }
+  }
+}
+`
+	generated := `resource "gitlab_group" "my_group" {
+  name        = "My Group"
+  path        = "my-group"
+  description = "Team group\n"
+}
+
+variable "gitlab_group_membership" {
+  default = {
+    "my-group" = {
+      "alice" = "owner"
+      "bob"   = "developer"
+    }
+  }
+}
+`
+	changes := compareTestDirs(t,
+		map[string]string{"my_group.tf": existing},
+		map[string]string{"my_group.tf": generated},
+	)
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestCompareDirsEquivalentExpressions(t *testing.T) {
+	existing := `resource "gitlab_group_membership" "my_group" {
+  for_each = var.gitlab_group_membership[ "my-group" ]
+  group_id = "${gitlab_group.my_group.id}"
+  user_id  = data.gitlab_user.main[each.key].id
+}
+`
+	generated := `resource "gitlab_group_membership" "my_group" {
+  for_each = var.gitlab_group_membership["my-group"]
+  group_id = gitlab_group.my_group.id
+  user_id  = data.gitlab_user.main[each.key].id
+}
+`
+	changes := compareTestDirs(t,
+		map[string]string{"my_group.tf": existing},
+		map[string]string{"my_group.tf": generated},
+	)
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestCompareDirsReportsAttributeChanges(t *testing.T) {
+	existing := `resource "gitlab_project" "my_project" {
+  name             = "My Project"
+  visibility_level = "private"
+  archived         = false
+}
+
+variable "gitlab_group_membership" {
+  default = {
+    "my-group" = {
+      "alice" = "owner"
+      "bob"   = "developer"
+    }
+  }
+}
+`
+	generated := `resource "gitlab_project" "my_project" {
+  name             = "My Project"
+  visibility_level = "internal"
+  description      = "New"
+}
+
+variable "gitlab_group_membership" {
+  default = {
+    "my-group" = {
+      "alice" = "maintainer"
+      "carol" = "reporter"
+    }
+  }
+}
+`
+	changes := compareTestDirs(t,
+		map[string]string{"my_group.tf": existing},
+		map[string]string{"my_group.tf": generated},
+	)
+
+	want := []BlockChange{
+		{
+			Address: "gitlab_project.my_project",
+			File:    "my_group.tf",
+			Kind:    ChangeChanged,
+			Attributes: []AttributeChange{
+				{Path: "archived", Kind: ChangeRemoved, Old: "false"},
+				{Path: "description", Kind: ChangeAdded, New: `"New"`},
+				{Path: "visibility_level", Kind: ChangeChanged, Old: `"private"`, New: `"internal"`},
+			},
+		},
+		{
+			Address: "var.gitlab_group_membership",
+			File:    "my_group.tf",
+			Kind:    ChangeChanged,
+			Attributes: []AttributeChange{
+				{Path: `default["my-group"]["alice"]`, Kind: ChangeChanged, Old: `"owner"`, New: `"maintainer"`},
+				{Path: `default["my-group"]["bob"]`, Kind: ChangeRemoved, Old: `"developer"`},
+				{Path: `default["my-group"]["carol"]`, Kind: ChangeAdded, New: `"reporter"`},
+			},
+		},
+	}
+	assertChanges(t, changes, want)
+}
+
+func TestCompareDirsAddedAndRemovedBlocks(t *testing.T) {
+	existing := `resource "gitlab_project" "old_project" {
+  name = "Old"
+}
+`
+	generated := `resource "gitlab_project" "new_project" {
+  name = "New"
+}
+`
+	changes := compareTestDirs(t,
+		map[string]string{"my_group.tf": existing},
+		map[string]string{"my_group.tf": generated, "other.tf": "data \"gitlab_user\" \"main\" {\n  username = \"root\"\n}\n"},
+	)
+
+	want := []BlockChange{
+		{Address: "gitlab_project.new_project", File: "my_group.tf", Kind: ChangeAdded},
+		{Address: "gitlab_project.old_project", File: "my_group.tf", Kind: ChangeRemoved},
+		{Address: "data.gitlab_user.main", File: "other.tf", Kind: ChangeAdded},
+	}
+	assertChanges(t, changes, want)
+	for _, c := range changes {
+		if c.Line != 1 {
+			t.Errorf("%s: Line = %d, want 1", c.Address, c.Line)
+		}
+	}
+}
+
+func TestCompareDirsMatchesBlocksAcrossFiles(t *testing.T) {
+	block := `resource "gitlab_group" "sub" {
+  name = "Sub"
+}
+`
+	changes := compareTestDirs(t,
+		map[string]string{"parent.tf": block, "sub.tf": ""},
+		map[string]string{"parent.tf": "", "sub.tf": block},
+	)
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestCompareDirsNestedBlocksOrderInsensitive(t *testing.T) {
+	existing := `resource "gitlab_branch_protection" "main" {
+  branch = "main"
+
+  allowed_to_push {
+    user_id = 2
+  }
+
+  allowed_to_push {
+    user_id = 1
+  }
+}
+`
+	generated := `resource "gitlab_branch_protection" "main" {
+  branch = "main"
+
+  allowed_to_push {
+    user_id = 1
+  }
+
+  allowed_to_push {
+    user_id = 3
+  }
+}
+`
+	changes := compareTestDirs(t,
+		map[string]string{"branch_protection.tf": existing},
+		map[string]string{"branch_protection.tf": generated},
+	)
+
+	want := []BlockChange{
+		{
+			Address: "gitlab_branch_protection.main",
+			File:    "branch_protection.tf",
+			Kind:    ChangeChanged,
+			Attributes: []AttributeChange{
+				{Path: "allowed_to_push[1].user_id", Kind: ChangeChanged, Old: "2", New: "3"},
+			},
+		},
+	}
+	assertChanges(t, changes, want)
+}
+
+func TestCompareDirsIgnoresUnrelatedExistingFiles(t *testing.T) {
+	block := `resource "gitlab_group" "my_group" {
+  name = "My Group"
+}
+`
+	changes := compareTestDirs(t,
+		map[string]string{"my_group.tf": block, "providers.tf": "provider \"gitlab\" {}\n"},
+		map[string]string{"my_group.tf": block},
+	)
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestCompareDirsParseError(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+	writeTestFiles(t, existingDir, map[string]string{"my_group.tf": "resource {"})
+	writeTestFiles(t, generatedDir, map[string]string{"my_group.tf": ""})
+
+	if _, err := CompareDirs(existingDir, generatedDir, nil); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestPrintDrift(t *testing.T) {
+	changes := []BlockChange{
+		{Address: "gitlab_project.new_project", File: "my_group.tf", Kind: ChangeAdded},
+		{
+			Address: "gitlab_group.my_group",
+			File:    "my_group.tf",
+			Kind:    ChangeChanged,
+			Attributes: []AttributeChange{
+				{Path: "description", Kind: ChangeAdded, New: `"New"`},
+				{Path: "name", Kind: ChangeChanged, Old: `"Old"`, New: `"New"`},
+				{Path: "path", Kind: ChangeRemoved, Old: `"old"`},
+			},
+		},
+		{Address: "gitlab_project.old_project", File: "my_group.tf", Kind: ChangeRemoved},
+	}
+
+	var buf bytes.Buffer
+	if err := PrintDrift(&buf, changes); err != nil {
+		t.Fatalf("PrintDrift error: %v", err)
+	}
+
+	want := `+ gitlab_project.new_project (my_group.tf)
+~ gitlab_group.my_group (my_group.tf)
+    + description = "New"
+    ~ name: "Old" => "New"
+    - path = "old"
+- gitlab_project.old_project (my_group.tf)
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func assertChanges(t *testing.T, got, want []BlockChange) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Address != want[i].Address || got[i].File != want[i].File || got[i].Kind != want[i].Kind {
+			t.Errorf("changes[%d] = %s %s (%s), want %s %s (%s)", i,
+				got[i].Kind, got[i].Address, got[i].File, want[i].Kind, want[i].Address, want[i].File)
+			continue
+		}
+		if len(got[i].Attributes) != len(want[i].Attributes) {
+			t.Errorf("changes[%d] attributes = %+v, want %+v", i, got[i].Attributes, want[i].Attributes)
+			continue
+		}
+		for j := range want[i].Attributes {
+			if got[i].Attributes[j] != want[i].Attributes[j] {
+				t.Errorf("changes[%d].Attributes[%d] = %+v, want %+v", i, j, got[i].Attributes[j], want[i].Attributes[j])
+			}
+		}
+	}
+}
+
+func TestCompareDirsMatchesRemovedBlocksByAddress(t *testing.T) {
+	existing := `removed {
+  from = gitlab_project.a
+
+  lifecycle {
+    destroy = false
+  }
+}
+`
+	generated := existing + `
+removed {
+  from = gitlab_project.b
+
+  lifecycle {
+    destroy = false
+  }
+}
+`
+	changes := compareTestDirs(t,
+		map[string]string{"removed.tf": existing},
+		map[string]string{"removed.tf": generated},
+	)
+
+	want := []BlockChange{
+		{Address: "removed.gitlab_project.b", File: "removed.tf", Kind: ChangeAdded},
+	}
+	assertChanges(t, changes, want)
+}
+
+func TestInstanceChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"my_group.tf": `resource "gitlab_group_share_group" "my_group" {
+  for_each       = var.gitlab_group_share_group["my-group"]
+  group_id       = gitlab_group.my_group.id
+  share_group_id = data.gitlab_group.main[each.key].id
+  group_access   = each.value
+  expires_at     = try(var.gitlab_group_share_group_expires_at["my-group"][each.key], null)
+}
+
+resource "gitlab_group_label" "my_group" {
+  for_each = var.gitlab_group_label["my-group"]
+  group    = gitlab_group.my_group.id
+  name     = each.key
+}
+`,
+	})
+	locations, err := ParseResourceLocations(dir)
+	if err != nil {
+		t.Fatalf("ParseResourceLocations error: %v", err)
+	}
+	if got := locations["gitlab_group_label.my_group"].ForEach; got != `var.gitlab_group_label["my-group"]` {
+		t.Errorf("ForEach = %q", got)
+	}
+
+	changes := []BlockChange{
+		{Address: "var.gitlab_group_share_group", Kind: ChangeChanged, Attributes: []AttributeChange{
+			{Path: `default["my-group"]["other"]`, Kind: ChangeChanged, Old: `"developer"`, New: `"maintainer"`},
+			{Path: `default["my-group/new"]`, Kind: ChangeAdded, New: "{}"},
+		}},
+		{Address: "var.gitlab_group_share_group_expires_at", Kind: ChangeChanged, Attributes: []AttributeChange{
+			{Path: `default["my-group"]["other"]`, Kind: ChangeAdded, New: `"2030-01-01"`},
+		}},
+		{Address: "var.gitlab_group_label", Kind: ChangeChanged, Attributes: []AttributeChange{
+			{Path: `default["my-group"]["bug"].color`, Kind: ChangeChanged, Old: `"#ff0000"`, New: `"#00ff00"`},
+		}},
+		{Address: "gitlab_group_label.my_group", Kind: ChangeChanged, Attributes: []AttributeChange{
+			{Path: "name", Kind: ChangeChanged, Old: "each.value.name", New: "each.key"},
+		}},
+	}
+
+	got := InstanceChanges(changes, locations)
+	want := map[string][]AttributeChange{
+		`gitlab_group_share_group.my_group["other"]`: {
+			{Path: `var.gitlab_group_share_group.default["my-group"]["other"]`, Kind: ChangeChanged, Old: `"developer"`, New: `"maintainer"`},
+			{Path: `var.gitlab_group_share_group_expires_at.default["my-group"]["other"]`, Kind: ChangeAdded, New: `"2030-01-01"`},
+		},
+		`gitlab_group_label.my_group["bug"]`: {
+			{Path: `var.gitlab_group_label.default["my-group"]["bug"].color`, Kind: ChangeChanged, Old: `"#ff0000"`, New: `"#00ff00"`},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("InstanceChanges() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseBlockLocations(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"my_group.tf": `variable "gitlab_group_label" {
+  default = {}
+}
+
+resource "gitlab_group" "my_group" {
+  name = "My Group"
+}
+`,
+	})
+
+	got, err := ParseBlockLocations(dir)
+	if err != nil {
+		t.Fatalf("ParseBlockLocations error: %v", err)
+	}
+	want := map[string]Location{
+		"var.gitlab_group_label": {File: "my_group.tf", Line: 1},
+		"gitlab_group.my_group":  {File: "my_group.tf", Line: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseBlockLocations() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func WriteGroupShareGroupVariable(groups []*gl.Group, groupShares gitlab.GroupShares, w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("variable \"gitlab_group_share_group\" {\n")
+	b.WriteString("  description = \"Share groups with other groups.\"\n")
+	b.WriteString("  default = {\n")
+
+	var expiring []*gl.Group
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		shares := groupShares[g.ID]
+		if len(shares) == 0 {
+			fmt.Fprintf(&b, "    \"%s\" = {}\n", g.FullPath)
+			continue
+		}
+		fmt.Fprintf(&b, "    \"%s\" = {\n", g.FullPath)
+		hasExpiry := false
+		for _, sg := range shares {
+			fmt.Fprintf(&b, "      \"%s\" = \"%s\"\n", sg.GroupFullPath, accessLevelIntToString(sg.GroupAccessLevel))
+			if sg.ExpiresAt != nil {
+				hasExpiry = true
+			}
+		}
+		b.WriteString("    }\n")
+		if hasExpiry {
+			expiring = append(expiring, g)
+		}
+	}
+
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	b.WriteString("\n")
+
+	b.WriteString("variable \"gitlab_group_share_group_expires_at\" {\n")
+	b.WriteString("  description = \"Expiry dates of group shares.\"\n")
+	b.WriteString("  default = {\n")
+	for _, g := range expiring {
+		fmt.Fprintf(&b, "    \"%s\" = {\n", g.FullPath)
+		for _, sg := range groupShares[g.ID] {
+			if sg.ExpiresAt != nil {
+				fmt.Fprintf(&b, "      \"%s\" = \"%s\"\n", sg.GroupFullPath, sg.ExpiresAt.String())
+			}
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+
+	_, err := w.Write(hclwrite.Format([]byte(b.String())))
+	return err
+}
+
+func WriteGroupShareGroupHelpers(w io.Writer) error {
+	_, err := fmt.Fprint(w, `locals {
+  groups_by_groups = toset(distinct(flatten([
+    for key, group in var.gitlab_group_share_group : [
+      for share, access in group : share
+    ]
+  ])))
+}
+
+data "gitlab_group" "by_groups" {
+  for_each  = local.groups_by_groups
+  full_path = each.key
+}
+`)
+	return err
+}
+
+func WriteGroupShareGroupResource(group *gl.Group, w io.Writer) error {
+	name := normalizeToTerraformName(group.Path)
+	_, err := fmt.Fprintf(w, `resource "gitlab_group_share_group" "%s" {
+  for_each       = var.gitlab_group_share_group["%s"]
+  group_id       = gitlab_group.%s.id
+  share_group_id = data.gitlab_group.by_groups[each.key].id
+  group_access   = each.value
+  expires_at     = try(var.gitlab_group_share_group_expires_at["%s"][each.key], null)
+}
+`, name, group.FullPath, name, group.FullPath)
+	return err
+}
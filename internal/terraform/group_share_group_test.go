@@ -0,0 +1,51 @@
+package terraform
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestWriteGroupShareGroupVariable(t *testing.T) {
+	groups := []*gl.Group{
+		{ID: 1, Path: "my-group", FullPath: "my-group"},
+		{ID: 2, Path: "sub-group", FullPath: "my-group/sub-group"},
+	}
+	expiry := gl.ISOTime(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC))
+	shares := gitlab.GroupShares{
+		1: {
+			{GroupID: 10, GroupFullPath: "other-group", GroupAccessLevel: int64(gl.DeveloperPermissions)},
+			{GroupID: 11, GroupFullPath: "partners/auditors", GroupAccessLevel: int64(gl.ReporterPermissions), ExpiresAt: &expiry},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGroupShareGroupVariable(groups, shares, &buf); err != nil {
+		t.Fatalf("WriteGroupShareGroupVariable error: %v", err)
+	}
+
+	compareGolden(t, "group_share_group_variable.tf", buf.String())
+}
+
+func TestWriteGroupShareGroupHelpers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGroupShareGroupHelpers(&buf); err != nil {
+		t.Fatalf("WriteGroupShareGroupHelpers error: %v", err)
+	}
+
+	compareGolden(t, "group_share_group_helpers.tf", buf.String())
+}
+
+func TestWriteGroupShareGroupResource(t *testing.T) {
+	group := &gl.Group{ID: 1, Path: "my-group", FullPath: "my-group"}
+
+	var buf bytes.Buffer
+	if err := WriteGroupShareGroupResource(group, &buf); err != nil {
+		t.Fatalf("WriteGroupShareGroupResource error: %v", err)
+	}
+
+	compareGolden(t, "group_share_group_resource.tf", buf.String())
+}
@@ -0,0 +1,383 @@
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+)
+
+// refKind tells how an identifying attribute is resolved.
+type refKind int
+
+const (
+	// literalRef attributes must be literal values, e.g. a hook url.
+	literalRef refKind = iota
+	// groupRef attributes hold a group ID or full path, or reference a
+	// gitlab_group resource.
+	groupRef
+	// projectRef attributes hold a project ID or full path, or reference a
+	// gitlab_project resource.
+	projectRef
+	// resourceRef attributes reference another resource, e.g. the pipeline
+	// schedule of a schedule variable.
+	resourceRef
+)
+
+type identityAttr struct {
+	name string
+	kind refKind
+}
+
+type identitySpec struct {
+	attrs []identityAttr
+	// forEach marks resources that manage all objects of their parent
+	// through for_each, so only for_each blocks are matched.
+	forEach bool
+}
+
+// identitySpecs lists the attributes that identify the GitLab object of each
+// resource type, apart from groups and projects which are identified by
+// their full path.
+var identitySpecs = map[string]identitySpec{
+	"gitlab_group_hook":                 {attrs: []identityAttr{{"group", groupRef}, {"url", literalRef}}},
+	"gitlab_project_hook":               {attrs: []identityAttr{{"project", projectRef}, {"url", literalRef}}},
+	"gitlab_branch_protection":          {attrs: []identityAttr{{"project", projectRef}, {"branch", literalRef}}},
+	"gitlab_tag_protection":             {attrs: []identityAttr{{"project", projectRef}, {"tag", literalRef}}},
+	"gitlab_project_approval_rule":      {attrs: []identityAttr{{"project", projectRef}, {"name", literalRef}}},
+	"gitlab_project_level_mr_approvals": {attrs: []identityAttr{{"project", projectRef}}},
+	"gitlab_group_service_account":      {attrs: []identityAttr{{"group", groupRef}, {"username", literalRef}}},
+	"gitlab_pipeline_schedule":          {attrs: []identityAttr{{"project", projectRef}, {"description", literalRef}, {"ref", literalRef}}},
+	"gitlab_pipeline_schedule_variable": {attrs: []identityAttr{{"pipeline_schedule_id", resourceRef}, {"key", literalRef}}},
+	"gitlab_group_label":                {attrs: []identityAttr{{"group", groupRef}}, forEach: true},
+	"gitlab_project_label":              {attrs: []identityAttr{{"project", projectRef}}, forEach: true},
+	"gitlab_group_variable":             {attrs: []identityAttr{{"group", groupRef}}, forEach: true},
+	"gitlab_project_variable":           {attrs: []identityAttr{{"project", projectRef}}, forEach: true},
+	"gitlab_group_share_group":          {attrs: []identityAttr{{"group_id", groupRef}}, forEach: true},
+	"gitlab_project_share_group":        {attrs: []identityAttr{{"project", projectRef}}, forEach: true},
+	"gitlab_project_membership":         {attrs: []identityAttr{{"project", projectRef}}, forEach: true},
+	// Generated group memberships iterate all members of a group, but
+	// human and service account members share the group_id, so only
+	// hand-written memberships of a single user are identified.
+	"gitlab_group_membership": {attrs: []identityAttr{{"group_id", groupRef}, {"user_id", literalRef}}},
+}
+
+// groupMemberIdentity returns the identity of a hand-written
+// gitlab_group_membership of the user userID in the group at groupPath.
+func groupMemberIdentity(groupPath string, userID int64) string {
+	return "gitlab_group_membership:gitlab_group:" + groupPath + ":" + strconv.FormatInt(userID, 10)
+}
+
+// MatchExistingResources matches the resource blocks declared in existingDir
+// to the generated ones in generatedDir by the GitLab object they manage,
+// e.g. a project's namespace and path, regardless of resource name or file.
+// It returns the generated address mapped to the existing address for every
+// object that is declared under a different address.
+func MatchExistingResources(resources *gitlab.Resources, existingDir, generatedDir string) (map[string]string, error) {
+	existing, err := ParseExistingResources(existingDir, resources)
+	if err != nil {
+		return nil, err
+	}
+	generated, err := parseResourceBlocks(generatedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	byIdentity := make(map[string]string)
+	for _, addr := range sortedKeys(existing) {
+		id := existing[addr]
+		if id == "" {
+			continue
+		}
+		if _, ok := byIdentity[id]; !ok {
+			byIdentity[id] = addr
+		}
+	}
+
+	groupPaths, projectPaths := fullPaths(resources)
+	generatedIDs := newIdentityResolver(generated, groupPaths, projectPaths)
+	matches := make(map[string]string)
+	for _, addr := range sortedKeys(generated) {
+		if _, ok := existing[addr]; ok {
+			continue
+		}
+		id := generatedIDs.identity(addr)
+		if id == "" {
+			continue
+		}
+		// The generated block cannot take the existing address if that is
+		// generated for another object.
+		if match, ok := byIdentity[id]; ok && generated[match] == nil {
+			matches[addr] = match
+		}
+	}
+	return matches, nil
+}
+
+// RenameMatchedResources renames the generated resources in dir to the
+// existing addresses MatchExistingResources matched them to, along with the
+// references to them. Merge requests and --overwrite then keep the existing
+// names, instead of making terraform destroy and recreate the objects.
+func RenameMatchedResources(dir string, matches map[string]string) error {
+	if len(matches) == 0 {
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return fmt.Errorf("listing generated files: %w", err)
+	}
+
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		f, diags := hclwrite.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		renameResources(f.Body(), matches)
+
+		out := hclwrite.Format(f.Bytes())
+		if bytes.Equal(out, src) {
+			continue
+		}
+		if err := os.WriteFile(path, out, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// renameResources renames the resource blocks of body and references in
+// its attributes and nested blocks from the keys of renames to their values.
+func renameResources(body *hclwrite.Body, renames map[string]string) {
+	for _, attr := range body.Attributes() {
+		for from, to := range renames {
+			attr.Expr().RenameVariablePrefix(strings.Split(from, "."), strings.Split(to, "."))
+		}
+	}
+	for _, block := range body.Blocks() {
+		labels := block.Labels()
+		if block.Type() == "resource" && len(labels) == 2 {
+			if to, ok := renames[labels[0]+"."+labels[1]]; ok {
+				block.SetLabels(strings.Split(to, "."))
+			}
+		}
+		renameResources(block.Body(), renames)
+	}
+}
+
+// fullPaths maps the IDs of the fetched groups and projects to their full
+// paths.
+func fullPaths(resources *gitlab.Resources) (groupPaths, projectPaths map[int64]string) {
+	groupPaths = make(map[int64]string)
+	for _, g := range resources.Groups {
+		if g != nil {
+			groupPaths[g.ID] = g.FullPath
+		}
+	}
+	projectPaths = make(map[int64]string)
+	for _, p := range resources.Projects {
+		if p != nil {
+			projectPaths[p.ID] = projectFullPath(p)
+		}
+	}
+	return groupPaths, projectPaths
+}
+
+// resourceBlock holds the attribute expressions of a resource block.
+type resourceBlock struct {
+	resourceType string
+	attrs        hclsyntax.Attributes
+}
+
+func parseResourceBlocks(dir string) (map[string]*resourceBlock, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("listing tf files: %w", err)
+	}
+
+	blocks := make(map[string]*resourceBlock)
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		f, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		for _, block := range f.Body.(*hclsyntax.Body).Blocks {
+			if block.Type == "resource" && len(block.Labels) == 2 {
+				blocks[block.Labels[0]+"."+block.Labels[1]] = &resourceBlock{
+					resourceType: block.Labels[0],
+					attrs:        block.Body.Attributes,
+				}
+			}
+		}
+	}
+	return blocks, nil
+}
+
+// identityResolver computes identities of resource blocks, following
+// references between them.
+type identityResolver struct {
+	blocks       map[string]*resourceBlock
+	groupPaths   map[int64]string
+	projectPaths map[int64]string
+	memo         map[string]string
+	visiting     map[string]bool
+}
+
+func newIdentityResolver(blocks map[string]*resourceBlock, groupPaths, projectPaths map[int64]string) *identityResolver {
+	return &identityResolver{
+		blocks:       blocks,
+		groupPaths:   groupPaths,
+		projectPaths: projectPaths,
+		memo:         make(map[string]string),
+		visiting:     make(map[string]bool),
+	}
+}
+
+// identity returns a string identifying the GitLab object managed by the
+// block at addr, or "" if it cannot be determined statically.
+func (r *identityResolver) identity(addr string) string {
+	if id, ok := r.memo[addr]; ok {
+		return id
+	}
+	block, ok := r.blocks[addr]
+	if !ok || r.visiting[addr] {
+		return ""
+	}
+	r.visiting[addr] = true
+	id := r.compute(block)
+	delete(r.visiting, addr)
+	r.memo[addr] = id
+	return id
+}
+
+func (r *identityResolver) compute(block *resourceBlock) string {
+	switch block.resourceType {
+	case "gitlab_group":
+		path, ok := r.literal(block.attrs, "path")
+		if !ok {
+			return ""
+		}
+		if _, ok := block.attrs["parent_id"]; !ok {
+			return "gitlab_group:" + path
+		}
+		parent := r.resolve(block.attrs, "parent_id", groupRef)
+		if parent == "" {
+			return ""
+		}
+		return parent + "/" + path
+	case "gitlab_project":
+		path, ok := r.literal(block.attrs, "path")
+		if !ok {
+			return ""
+		}
+		namespace := r.resolve(block.attrs, "namespace_id", groupRef)
+		if namespace == "" {
+			return ""
+		}
+		return "gitlab_project:" + strings.TrimPrefix(namespace, "gitlab_group:") + "/" + path
+	}
+
+	spec, ok := identitySpecs[block.resourceType]
+	if !ok {
+		return ""
+	}
+	if _, ok := block.attrs["for_each"]; ok != spec.forEach {
+		return ""
+	}
+	parts := []string{block.resourceType}
+	for _, a := range spec.attrs {
+		v := r.resolve(block.attrs, a.name, a.kind)
+		if v == "" {
+			return ""
+		}
+		parts = append(parts, v)
+	}
+	return strings.Join(parts, ":")
+}
+
+// resolve returns the identity of the object an attribute refers to, or the
+// literal value for literalRef attributes.
+func (r *identityResolver) resolve(attrs hclsyntax.Attributes, name string, kind refKind) string {
+	if kind == literalRef {
+		v, _ := r.literal(attrs, name)
+		return v
+	}
+
+	attr, ok := attrs[name]
+	if !ok {
+		return ""
+	}
+	if traversal, diags := hcl.AbsTraversalForExpr(attr.Expr); !diags.HasErrors() && len(traversal) >= 2 {
+		if next, ok := traversal[1].(hcl.TraverseAttr); ok {
+			return r.identity(traversal.RootName() + "." + next.Name)
+		}
+	}
+
+	v, ok := r.literal(attrs, name)
+	if !ok {
+		return ""
+	}
+	switch kind {
+	case groupRef:
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if path, ok := r.groupPaths[id]; ok {
+				return "gitlab_group:" + path
+			}
+		}
+		return "gitlab_group:" + v
+	case projectRef:
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if path, ok := r.projectPaths[id]; ok {
+				return "gitlab_project:" + path
+			}
+		}
+		return "gitlab_project:" + v
+	}
+	return ""
+}
+
+// literal returns the value of an attribute that can be evaluated without
+// context, such as a string or number.
+func (r *identityResolver) literal(attrs hclsyntax.Attributes, name string) (string, bool) {
+	attr, ok := attrs[name]
+	if !ok {
+		return "", false
+	}
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() || !v.IsKnown() || v.IsNull() {
+		return "", false
+	}
+	switch v.Type() {
+	case cty.String:
+		return v.AsString(), true
+	case cty.Number:
+		return v.AsBigFloat().Text('f', -1), true
+	}
+	return "", false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
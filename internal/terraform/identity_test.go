@@ -0,0 +1,282 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestMatchExistingResources(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+
+	writeTestFiles(t, existingDir, map[string]string{
+		"main.tf": `resource "gitlab_group" "be" {
+  name      = "Backend"
+  path      = "backend"
+  parent_id = 1
+}
+
+resource "gitlab_project" "api" {
+  name         = "API"
+  path         = "api"
+  namespace_id = gitlab_group.be.id
+}
+
+resource "gitlab_project" "web" {
+  name         = "Web"
+  path         = "web"
+  namespace_id = 10
+}
+
+resource "gitlab_project_hook" "api_ci" {
+  project = gitlab_project.api.id
+  url     = "https://ci.example.com"
+}
+
+resource "gitlab_project_label" "api_bug" {
+  project = "my-group/backend/api"
+  name    = "bug"
+}
+
+resource "gitlab_pipeline_schedule" "nightly" {
+  project     = "42"
+  description = "Nightly"
+  ref         = "main"
+  cron        = "0 0 * * *"
+}
+
+resource "gitlab_pipeline_schedule_variable" "nightly_env" {
+  project              = "42"
+  pipeline_schedule_id = gitlab_pipeline_schedule.nightly.pipeline_schedule_id
+  key                  = "ENV"
+  value                = "prod"
+}
+`,
+	})
+	writeTestFiles(t, generatedDir, map[string]string{
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+`,
+		"backend.tf": `resource "gitlab_group" "backend" {
+  name      = "Backend"
+  path      = "backend"
+  parent_id = gitlab_group.my_group.id
+}
+
+resource "gitlab_project" "backend_api" {
+  name         = "API"
+  path         = "api"
+  namespace_id = gitlab_group.backend.id
+}
+
+resource "gitlab_project" "backend_web" {
+  name         = "Web"
+  path         = "web"
+  namespace_id = gitlab_group.backend.id
+}
+
+resource "gitlab_project_label" "backend_api" {
+  for_each = var.gitlab_project_label["my-group/backend/api"]
+  project  = gitlab_project.backend_api.id
+  name     = each.key
+}
+
+resource "gitlab_pipeline_schedule" "backend_api_nightly" {
+  project     = gitlab_project.backend_api.id
+  description = "Nightly"
+  ref         = "main"
+  cron        = "0 0 * * *"
+}
+
+resource "gitlab_pipeline_schedule_variable" "backend_api_nightly_env" {
+  project              = gitlab_project.backend_api.id
+  pipeline_schedule_id = gitlab_pipeline_schedule.backend_api_nightly.pipeline_schedule_id
+  key                  = "ENV"
+  value                = "prod"
+}
+`,
+		"project_hooks.tf": `resource "gitlab_project_hook" "backend_api_0" {
+  project = gitlab_project.backend_api.id
+  url     = "https://ci.example.com"
+}
+`,
+	})
+
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{
+			{ID: 1, Path: "my-group", FullPath: "my-group"},
+			{ID: 10, Path: "backend", FullPath: "my-group/backend", ParentID: 1},
+		},
+		Projects: []*gl.Project{
+			{ID: 42, Path: "api", PathWithNamespace: "my-group/backend/api"},
+			{ID: 43, Path: "web", PathWithNamespace: "my-group/backend/web"},
+		},
+	}
+
+	matches, err := MatchExistingResources(resources, existingDir, generatedDir)
+	if err != nil {
+		t.Fatalf("MatchExistingResources error: %v", err)
+	}
+
+	want := map[string]string{
+		"gitlab_group.backend":                                      "gitlab_group.be",
+		"gitlab_project.backend_api":                                "gitlab_project.api",
+		"gitlab_project.backend_web":                                "gitlab_project.web",
+		"gitlab_project_hook.backend_api_0":                         "gitlab_project_hook.api_ci",
+		"gitlab_pipeline_schedule.backend_api_nightly":              "gitlab_pipeline_schedule.nightly",
+		"gitlab_pipeline_schedule_variable.backend_api_nightly_env": "gitlab_pipeline_schedule_variable.nightly_env",
+	}
+	if len(matches) != len(want) {
+		t.Errorf("got %d matches, want %d: %v", len(matches), len(want), matches)
+	}
+	for gen, existing := range want {
+		if matches[gen] != existing {
+			t.Errorf("matches[%q] = %q, want %q", gen, matches[gen], existing)
+		}
+	}
+
+	// A single hand-written label does not cover all labels of the project.
+	if _, ok := matches["gitlab_project_label.backend_api"]; ok {
+		t.Error("non for_each label should not match the generated for_each resource")
+	}
+
+	orphans, err := FindOrphans(resources, existingDir, generatedDir, matches, nil)
+	if err != nil {
+		t.Fatalf("FindOrphans error: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("expected matched resources not to be orphans, got %+v", orphans)
+	}
+}
+
+func TestMatchExistingResourcesUnresolvable(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+
+	writeTestFiles(t, existingDir, map[string]string{
+		"main.tf": `resource "gitlab_project" "api" {
+  name         = "API"
+  path         = "api"
+  namespace_id = data.gitlab_group.backend.id
+}
+`,
+	})
+	writeTestFiles(t, generatedDir, map[string]string{
+		"backend.tf": `resource "gitlab_project" "backend_api" {
+  name         = "API"
+  path         = "api"
+  namespace_id = 10
+}
+`,
+	})
+
+	matches, err := MatchExistingResources(&gitlab.Resources{}, existingDir, generatedDir)
+	if err != nil {
+		t.Fatalf("MatchExistingResources error: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no matches, got %v", matches)
+	}
+}
+
+func TestRenameMatchedResources(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+
+	writeTestFiles(t, existingDir, map[string]string{
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+
+resource "gitlab_project" "api" {
+  name         = "API"
+  path         = "api"
+  namespace_id = gitlab_group.my_group.id
+}
+`,
+	})
+	generated := `resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+
+resource "gitlab_project" "my_group_api" {
+  name         = "API"
+  path         = "api"
+  namespace_id = gitlab_group.my_group.id
+}
+
+resource "gitlab_project_hook" "my_group_api_0" {
+  project = gitlab_project.my_group_api.id
+  url     = "https://example.com"
+}
+`
+	writeTestFiles(t, generatedDir, map[string]string{"my_group.tf": generated})
+
+	matches, err := MatchExistingResources(&gitlab.Resources{}, existingDir, generatedDir)
+	if err != nil {
+		t.Fatalf("MatchExistingResources error: %v", err)
+	}
+	if err := RenameMatchedResources(generatedDir, matches); err != nil {
+		t.Fatalf("RenameMatchedResources error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(generatedDir, "my_group.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.ReplaceAll(generated, "my_group_api.", "api.")
+	want = strings.Replace(want, `"my_group_api"`, `"api"`, 1)
+	if string(got) != want {
+		t.Errorf("renamed file:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The renamed block is compared with the existing one instead of being
+	// reported as removed and added.
+	changes, err := CompareDirs(existingDir, generatedDir, matches)
+	if err != nil {
+		t.Fatalf("CompareDirs error: %v", err)
+	}
+	assertChanges(t, changes, []BlockChange{
+		{Address: "gitlab_project_hook.my_group_api_0", File: "my_group.tf", Kind: ChangeAdded},
+	})
+}
+
+func TestCompareDirsSkipsMatchesDeclaredElsewhere(t *testing.T) {
+	changes := compareTestDirs(t,
+		map[string]string{"my_group.tf": ""},
+		map[string]string{"my_group.tf": `resource "gitlab_project" "api" {
+  path = "api"
+}
+`},
+	)
+	if len(changes) != 1 {
+		t.Fatalf("expected the unmatched block as added, got %+v", changes)
+	}
+
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+	writeTestFiles(t, existingDir, map[string]string{"main.tf": `resource "gitlab_project" "api" {
+  path = "api"
+}
+`})
+	writeTestFiles(t, generatedDir, map[string]string{"my_group.tf": `resource "gitlab_project" "api" {
+  path = "api"
+}
+`})
+	changes, err := CompareDirs(existingDir, generatedDir, map[string]string{"gitlab_project.my_group_api": "gitlab_project.api"})
+	if err != nil {
+		t.Fatalf("CompareDirs error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes for a matched block in main.tf, got %+v", changes)
+	}
+}
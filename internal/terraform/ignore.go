@@ -0,0 +1,640 @@
+package terraform
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
+)
+
+// ResourcePaths maps the resource blocks declared in dirs to the full path of
+// the GitLab group or project they belong to, e.g. a project hook to its
+// project. Blocks whose owner cannot be determined statically are left out.
+// Earlier dirs take precedence.
+func ResourcePaths(resources *gitlab.Resources, dirs ...string) (map[string]string, error) {
+	groupPaths, projectPaths := fullPaths(resources)
+	paths := make(map[string]string)
+	for _, dir := range dirs {
+		blocks, err := parseResourceBlocks(dir)
+		if err != nil {
+			return nil, err
+		}
+		ids := newIdentityResolver(blocks, groupPaths, projectPaths)
+		for _, addr := range sortedKeys(blocks) {
+			if _, ok := paths[addr]; ok {
+				continue
+			}
+			if p := ownerPath(ids.identity(addr)); p != "" {
+				paths[addr] = p
+			}
+		}
+	}
+	return paths, nil
+}
+
+// ownerPath extracts the first group or project path from an identity, e.g.
+// "my-group/api" from "gitlab_project_hook:gitlab_project:my-group/api:https://...".
+func ownerPath(identity string) string {
+	parts := strings.Split(identity, ":")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "gitlab_group" || parts[i] == "gitlab_project" {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// KeepIgnored copies what the filter leaves out of the scan from the files in
+// existingDir into the generated files in dir, so merge requests and
+// --overwrite leave it as it is: the existing values of ignored attributes,
+// which are left out of the generated blocks if not declared yet, and the
+// resource blocks and variable map entries of groups and projects that are
+// not selected by --include and --exclude, along with the approvers they
+// refer to, and references to declared groups that were not fetched. Blocks
+// are matched by address, so it must run after RenameMatchedResources.
+func KeepIgnored(dir, existingDir string, resources *gitlab.Resources, filter ignore.Filter, paths map[string]string) error {
+	if filter.Empty() {
+		return nil
+	}
+	existing, err := parseFiles(existingDir)
+	if err != nil {
+		return err
+	}
+	generated, err := parseFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	existingBlocks := make(map[string]*hclwrite.Block)
+	generatedAddrs := make(map[string]bool)
+	for _, f := range existing {
+		for _, block := range f.write.Body().Blocks() {
+			if addr, ok := resourceAddr(block); ok {
+				existingBlocks[addr] = block
+			}
+		}
+	}
+	for _, f := range generated {
+		for _, block := range f.write.Body().Blocks() {
+			if addr, ok := resourceAddr(block); ok {
+				generatedAddrs[addr] = true
+			}
+		}
+	}
+	filtered := len(filter.Include) > 0 || len(filter.Exclude) > 0
+	var groupPaths map[int64]string
+	if filtered {
+		groupPaths = knownGroupPaths(resources)
+	}
+
+	for _, name := range sortedKeys(generated) {
+		f := generated[name]
+		for _, block := range f.write.Body().Blocks() {
+			if addr, ok := resourceAddr(block); ok {
+				keepIgnoredAttributes(block.Body(), existingBlocks[addr], filter, block.Labels()[0], paths[addr])
+				if filtered {
+					keepGroupRefs(block.Body(), existingBlocks[addr], groupPaths, paths)
+				}
+			}
+		}
+		if ex, ok := existing[name]; ok && filtered {
+			keepUnselected(f, ex, filter, paths, generatedAddrs)
+		}
+
+		out := hclwrite.Format(f.write.Bytes())
+		if bytes.Equal(out, f.src) {
+			continue
+		}
+		if err := os.WriteFile(f.path, out, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", f.path, err)
+		}
+	}
+	return nil
+}
+
+// parsedFile is a .tf file parsed for both reading and editing.
+type parsedFile struct {
+	path   string
+	src    []byte
+	syntax *hclsyntax.Body
+	write  *hclwrite.File
+}
+
+// parseFiles parses the .tf files of dir, keyed by file name.
+func parseFiles(dir string) (map[string]*parsedFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("listing tf files: %w", err)
+	}
+	files := make(map[string]*parsedFile, len(paths))
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		syntax, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		write, diags := hclwrite.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+		files[filepath.Base(path)] = &parsedFile{path: path, src: src, syntax: syntax.Body.(*hclsyntax.Body), write: write}
+	}
+	return files, nil
+}
+
+func resourceAddr(block *hclwrite.Block) (string, bool) {
+	labels := block.Labels()
+	if block.Type() != "resource" || len(labels) != 2 {
+		return "", false
+	}
+	return labels[0] + "." + labels[1], true
+}
+
+// keepIgnoredAttributes replaces the ignored attributes and nested blocks of
+// a generated block body with those of the existing block, if any.
+func keepIgnoredAttributes(body *hclwrite.Body, existing *hclwrite.Block, filter ignore.Filter, resourceType, path string) {
+	var existingBody *hclwrite.Body
+	if existing != nil {
+		existingBody = existing.Body()
+	}
+
+	names := make(map[string]bool)
+	for name := range body.Attributes() {
+		names[name] = true
+	}
+	if existingBody != nil {
+		for name := range existingBody.Attributes() {
+			names[name] = true
+		}
+	}
+	for _, name := range sortedKeys(names) {
+		if !filter.IgnoredAttribute(resourceType, path, name) {
+			continue
+		}
+		var attr *hclwrite.Attribute
+		if existingBody != nil {
+			attr = existingBody.GetAttribute(name)
+		}
+		if attr != nil {
+			body.SetAttributeRaw(name, attr.Expr().BuildTokens(nil))
+		} else {
+			body.RemoveAttribute(name)
+		}
+	}
+
+	blockTypes := make(map[string]bool)
+	for _, b := range body.Blocks() {
+		blockTypes[b.Type()] = true
+	}
+	if existingBody != nil {
+		for _, b := range existingBody.Blocks() {
+			blockTypes[b.Type()] = true
+		}
+	}
+	for _, blockType := range sortedKeys(blockTypes) {
+		if !filter.IgnoredAttribute(resourceType, path, blockType) {
+			continue
+		}
+		for _, b := range body.Blocks() {
+			if b.Type() == blockType {
+				body.RemoveBlock(b)
+			}
+		}
+		if existingBody == nil {
+			continue
+		}
+		for _, b := range existingBody.Blocks() {
+			if b.Type() == blockType {
+				body.AppendBlock(b)
+			}
+		}
+	}
+}
+
+// knownGroupPaths maps the IDs of the groups resources mention to their full
+// paths, including parents and share targets that were not fetched.
+func knownGroupPaths(resources *gitlab.Resources) map[int64]string {
+	groupPaths := make(map[int64]string)
+	for _, g := range resources.Groups {
+		if g == nil {
+			continue
+		}
+		groupPaths[g.ID] = g.FullPath
+		if g.ParentID != 0 && strings.Contains(g.FullPath, "/") {
+			groupPaths[g.ParentID] = path.Dir(g.FullPath)
+		}
+		for _, s := range g.SharedWithGroups {
+			groupPaths[s.GroupID] = s.GroupFullPath
+		}
+	}
+	for _, p := range resources.Projects {
+		if p == nil {
+			continue
+		}
+		if p.Namespace != nil && p.Namespace.Kind == "group" {
+			groupPaths[p.Namespace.ID] = p.Namespace.FullPath
+		}
+		for _, s := range p.SharedWithGroups {
+			groupPaths[s.GroupID] = s.GroupFullPath
+		}
+	}
+	return groupPaths
+}
+
+// keepGroupRefs keeps the existing gitlab_group references of a generated
+// block body whose group was not fetched, and which was therefore written as
+// a literal ID, if the reference resolves to the group of that ID.
+func keepGroupRefs(body *hclwrite.Body, existing *hclwrite.Block, groupPaths map[int64]string, paths map[string]string) {
+	if existing == nil {
+		return
+	}
+	for name, attr := range body.Attributes() {
+		tokens := attr.Expr().BuildTokens(nil)
+		if len(tokens) != 1 || tokens[0].Type != hclsyntax.TokenNumberLit {
+			continue
+		}
+		id, err := strconv.ParseInt(string(tokens[0].Bytes), 10, 64)
+		if err != nil || groupPaths[id] == "" {
+			continue
+		}
+		existingAttr := existing.Body().GetAttribute(name)
+		if existingAttr == nil {
+			continue
+		}
+		existingTokens := existingAttr.Expr().BuildTokens(nil)
+		expr, diags := hclsyntax.ParseExpression(existingTokens.Bytes(), "", hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			continue
+		}
+		traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+		if !ok || len(traversal.Traversal) != 3 || traversal.Traversal.RootName() != "gitlab_group" {
+			continue
+		}
+		group, ok := traversal.Traversal[1].(hcl.TraverseAttr)
+		if last, isAttr := traversal.Traversal[2].(hcl.TraverseAttr); !ok || !isAttr || last.Name != "id" {
+			continue
+		}
+		if paths["gitlab_group."+group.Name] == groupPaths[id] {
+			body.SetAttributeRaw(name, existingTokens)
+		}
+	}
+}
+
+// keepUnselected appends the resource blocks and variable map entries of
+// groups and projects that are not selected from the existing file ex to the
+// generated file f of the same name, as they were not fetched. Approver data
+// sources are extended by the approvers the kept blocks refer to.
+func keepUnselected(f, ex *parsedFile, filter ignore.Filter, paths map[string]string, generatedAddrs map[string]bool) {
+	rootBody := f.write.Body()
+	exBlocks := ex.write.Body().Blocks()
+	referenced := make(map[string]map[string]bool)
+
+	for i, block := range ex.syntax.Blocks {
+		switch {
+		case block.Type == "resource" && len(block.Labels) == 2:
+			addr := block.Labels[0] + "." + block.Labels[1]
+			p := paths[addr]
+			if generatedAddrs[addr] || p == "" || filter.Selected(p) {
+				continue
+			}
+			rootBody.AppendNewline()
+			rootBody.AppendBlock(exBlocks[i])
+			collectDataKeys(block.Body, referenced)
+		case block.Type == "variable" && len(block.Labels) == 1:
+			keepUnselectedEntries(f, ex, block, filter)
+		}
+	}
+
+	for _, addr := range sortedKeys(referenced) {
+		keepDataKeys(f, ex, addr, referenced[addr])
+	}
+}
+
+// keepUnselectedEntries adds the entries of the existing variable map block
+// keyed by paths that are not selected to the generated variable. Entries
+// keep the order of the existing map, new ones follow in generated order.
+func keepUnselectedEntries(f, ex *parsedFile, block *hclsyntax.Block, filter ignore.Filter) {
+	obj := variableDefault(block)
+	target := findBlock(f.write.Body(), "variable", block.Labels)
+	genObj := variableDefault(findSyntaxBlock(f.syntax, "variable", block.Labels))
+	if obj == nil || target == nil || genObj == nil {
+		return
+	}
+
+	generated := make(map[string][]byte)
+	var generatedOrder []string
+	for _, item := range genObj.Items {
+		if key, ok := objectKeyString(item.KeyExpr); ok {
+			generated[key] = f.src[item.KeyExpr.Range().Start.Byte:item.ValueExpr.Range().End.Byte]
+			generatedOrder = append(generatedOrder, key)
+		}
+	}
+
+	var entries [][]byte
+	kept := false
+	for _, item := range obj.Items {
+		key, ok := objectKeyString(item.KeyExpr)
+		if !ok {
+			continue
+		}
+		if raw, ok := generated[key]; ok {
+			entries = append(entries, raw)
+			delete(generated, key)
+		} else if !filter.Selected(key) {
+			entries = append(entries, ex.src[item.KeyExpr.Range().Start.Byte:item.ValueExpr.Range().End.Byte])
+			kept = true
+		}
+	}
+	if !kept {
+		return
+	}
+	for _, key := range generatedOrder {
+		if raw, ok := generated[key]; ok {
+			entries = append(entries, raw)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	for _, entry := range entries {
+		b.Write(entry)
+		b.WriteString("\n")
+	}
+	b.WriteString("}")
+	target.Body().SetAttributeRaw("default", hclwrite.Tokens{{Type: hclsyntax.TokenIdent, Bytes: b.Bytes()}})
+}
+
+// variableDefault returns the default object of a variable block, or nil if
+// it has none.
+func variableDefault(block *hclsyntax.Block) *hclsyntax.ObjectConsExpr {
+	if block == nil {
+		return nil
+	}
+	attr, ok := block.Body.Attributes["default"]
+	if !ok {
+		return nil
+	}
+	obj, _ := attr.Expr.(*hclsyntax.ObjectConsExpr)
+	return obj
+}
+
+// collectDataKeys records the keys of data sources body refers to, e.g.
+// "alice" of data.gitlab_user.approvers["alice"].id, by data source address.
+func collectDataKeys(body *hclsyntax.Body, keys map[string]map[string]bool) {
+	for _, attr := range body.Attributes {
+		for _, traversal := range attr.Expr.Variables() {
+			if traversal.RootName() != "data" || len(traversal) < 4 {
+				continue
+			}
+			dataType, ok1 := traversal[1].(hcl.TraverseAttr)
+			name, ok2 := traversal[2].(hcl.TraverseAttr)
+			index, ok3 := traversal[3].(hcl.TraverseIndex)
+			if !ok1 || !ok2 || !ok3 || index.Key.Type() != cty.String || !index.Key.IsKnown() || index.Key.IsNull() {
+				continue
+			}
+			addr := dataType.Name + "." + name.Name
+			if keys[addr] == nil {
+				keys[addr] = make(map[string]bool)
+			}
+			keys[addr][index.Key.AsString()] = true
+		}
+	}
+	for _, block := range body.Blocks {
+		collectDataKeys(block.Body, keys)
+	}
+}
+
+// keepDataKeys makes the generated data source at addr, e.g.
+// "gitlab_user.approvers", iterate keys too. If f lacks it, the existing one
+// is kept as it is.
+func keepDataKeys(f, ex *parsedFile, addr string, keys map[string]bool) {
+	labels := strings.Split(addr, ".")
+	target := findBlock(f.write.Body(), "data", labels)
+	if target == nil {
+		if exBlock := findBlock(ex.write.Body(), "data", labels); exBlock != nil {
+			f.write.Body().AppendNewline()
+			f.write.Body().AppendBlock(exBlock)
+		}
+		return
+	}
+
+	genBlock := findSyntaxBlock(f.syntax, "data", labels)
+	if genBlock == nil {
+		return
+	}
+	values, ok := stringSetValues(genBlock.Body.Attributes["for_each"])
+	if !ok {
+		return
+	}
+	changed := false
+	for key := range keys {
+		if !slices.Contains(values, key) {
+			values = append(values, key)
+			changed = true
+		}
+	}
+	if changed {
+		slices.Sort(values)
+		target.Body().SetAttributeRaw("for_each", tokensForStringSet(values))
+	}
+}
+
+// stringSetValues returns the values of a toset([...]) attribute of string
+// literals as written by tokensForStringSet.
+func stringSetValues(attr *hclsyntax.Attribute) ([]string, bool) {
+	if attr == nil {
+		return nil, false
+	}
+	call, ok := attr.Expr.(*hclsyntax.FunctionCallExpr)
+	if !ok || call.Name != "toset" || len(call.Args) != 1 {
+		return nil, false
+	}
+	tuple, ok := call.Args[0].(*hclsyntax.TupleConsExpr)
+	if !ok {
+		return nil, false
+	}
+	var values []string
+	for _, expr := range tuple.Exprs {
+		v, ok := objectKeyString(expr)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
+
+func findBlock(body *hclwrite.Body, blockType string, labels []string) *hclwrite.Block {
+	for _, block := range body.Blocks() {
+		if block.Type() == blockType && slices.Equal(block.Labels(), labels) {
+			return block
+		}
+	}
+	return nil
+}
+
+func findSyntaxBlock(body *hclsyntax.Body, blockType string, labels []string) *hclsyntax.Block {
+	for _, block := range body.Blocks {
+		if block.Type == blockType && slices.Equal(block.Labels, labels) {
+			return block
+		}
+	}
+	return nil
+}
+
+// IgnoreImportCommands drops the import commands of ignored objects.
+func IgnoreImportCommands(cmds []ImportCommand, filter ignore.Filter, paths map[string]string) []ImportCommand {
+	if filter.Empty() {
+		return cmds
+	}
+	var result []ImportCommand
+	for _, cmd := range cmds {
+		block, _, _ := strings.Cut(cmd.Address, "[")
+		resourceType, _, _ := strings.Cut(block, ".")
+		if !filter.Ignored(resourceType, paths[block]) {
+			result = append(result, cmd)
+		}
+	}
+	return result
+}
+
+// IgnoreChanges drops the changes of ignored objects and attributes. For
+// variable maps keyed by full path, such as var.gitlab_project_variable, the
+// variable name is taken as resource type and the last segment of a changed
+// path as attribute name.
+func IgnoreChanges(changes []BlockChange, filter ignore.Filter, paths map[string]string) []BlockChange {
+	if filter.Empty() {
+		return changes
+	}
+	var result []BlockChange
+	for _, c := range changes {
+		kind, addr, _ := strings.Cut(c.Address, ".")
+		switch kind {
+		case "var":
+			if c.Kind == ChangeChanged {
+				c.Attributes = ignoreVariableAttributes(c.Attributes, addr, filter)
+				if len(c.Attributes) == 0 {
+					continue
+				}
+			}
+		case "import", "removed":
+			resourceType, _, _ := strings.Cut(addr, ".")
+			block, _, _ := strings.Cut(addr, "[")
+			if filter.Ignored(resourceType, paths[block]) {
+				continue
+			}
+		case "data", "locals":
+		default:
+			p := paths[c.Address]
+			if filter.Ignored(kind, p) {
+				continue
+			}
+			if c.Kind == ChangeChanged {
+				var attrs []AttributeChange
+				for _, a := range c.Attributes {
+					segments := pathSegments(a.Path)
+					if len(segments) > 0 && filter.IgnoredAttribute(kind, p, segments[0]) {
+						continue
+					}
+					attrs = append(attrs, a)
+				}
+				if len(attrs) == 0 {
+					continue
+				}
+				c.Attributes = attrs
+			}
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+func ignoreVariableAttributes(attrs []AttributeChange, name string, filter ignore.Filter) []AttributeChange {
+	var result []AttributeChange
+	for _, a := range attrs {
+		segments := pathSegments(a.Path)
+		if len(segments) >= 2 && segments[0] == "default" {
+			p := segments[1]
+			if filter.Ignored(name, p) || filter.IgnoredAttribute(name, p, segments[len(segments)-1]) {
+				continue
+			}
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
+// IgnoreOrphans drops orphaned declarations of ignored objects.
+func IgnoreOrphans(orphans []Orphan, filter ignore.Filter, paths map[string]string) []Orphan {
+	if filter.Empty() {
+		return orphans
+	}
+	var result []Orphan
+	for _, o := range orphans {
+		resourceType, rest, _ := strings.Cut(o.Address, ".")
+		p := paths[o.Address]
+		if !o.IsResource() {
+			// var.<name>["<full path>"]
+			segments := pathSegments(rest)
+			resourceType = segments[0]
+			if len(segments) > 1 {
+				p = segments[1]
+			}
+		}
+		if !filter.Ignored(resourceType, p) {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// pathSegments splits an attribute path like `default["my-group"].name` or
+// `push_rules[0].deny_delete_tag` into its names, keys and indexes.
+func pathSegments(path string) []string {
+	var segments []string
+	for path != "" {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if strings.HasPrefix(path, `["`) {
+				if quoted, err := strconv.QuotedPrefix(path[1:]); err == nil {
+					key, _ := strconv.Unquote(quoted)
+					segments = append(segments, key)
+					path = path[1+len(quoted):]
+					path = strings.TrimPrefix(path, "]")
+					continue
+				}
+			}
+			if end < 0 {
+				return append(segments, path)
+			}
+			segments = append(segments, path[1:end])
+			path = path[end+1:]
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			segments = append(segments, path[:end])
+			path = path[end:]
+		}
+	}
+	return segments
+}
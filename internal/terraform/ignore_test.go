@@ -0,0 +1,399 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestResourcePaths(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+
+resource "gitlab_group" "sandbox" {
+  name      = "Sandbox"
+  path      = "sandbox"
+  parent_id = gitlab_group.my_group.id
+}
+
+resource "gitlab_project" "sandbox_test" {
+  name         = "Test"
+  path         = "test"
+  namespace_id = gitlab_group.sandbox.id
+}
+
+resource "gitlab_project_hook" "sandbox_test_ci" {
+  project = gitlab_project.sandbox_test.id
+  url     = "https://ci.example.com"
+}
+
+resource "gitlab_project_label" "sandbox_test" {
+  for_each = var.gitlab_project_label["my-group/sandbox/test"]
+  project  = gitlab_project.sandbox_test.id
+  name     = each.key
+}
+
+resource "gitlab_project" "unresolved" {
+  name         = "Unresolved"
+  path         = "unresolved"
+  namespace_id = local.namespace
+}
+`,
+	})
+
+	paths, err := ResourcePaths(&gitlab.Resources{}, dir)
+	if err != nil {
+		t.Fatalf("ResourcePaths error: %v", err)
+	}
+
+	want := map[string]string{
+		"gitlab_group.my_group":               "my-group",
+		"gitlab_group.sandbox":                "my-group/sandbox",
+		"gitlab_project.sandbox_test":         "my-group/sandbox/test",
+		"gitlab_project_hook.sandbox_test_ci": "my-group/sandbox/test",
+		"gitlab_project_label.sandbox_test":   "my-group/sandbox/test",
+	}
+	if len(paths) != len(want) {
+		t.Errorf("got %d paths, want %d: %v", len(paths), len(want), paths)
+	}
+	for addr, p := range want {
+		if paths[addr] != p {
+			t.Errorf("paths[%s] = %q, want %q", addr, paths[addr], p)
+		}
+	}
+}
+
+var testIgnoreFilter = ignore.Filter{Rules: ignore.Rules{
+	{Path: "my-group/sandbox"},
+	{Type: "gitlab_project", Attributes: []string{"description"}},
+	{Type: "gitlab_project_variable", Attributes: []string{"description"}},
+}}
+
+var testIgnorePaths = map[string]string{
+	"gitlab_project.api":          "my-group/api",
+	"gitlab_project.sandbox_test": "my-group/sandbox/test",
+	"gitlab_project_label.api":    "my-group/api",
+}
+
+func TestIgnoreImportCommands(t *testing.T) {
+	cmds := []ImportCommand{
+		{Address: "gitlab_project.api", ID: "1"},
+		{Address: "gitlab_project.sandbox_test", ID: "2"},
+		{Address: `gitlab_project_label.api["bug"]`, ID: "1:bug"},
+	}
+
+	got := IgnoreImportCommands(cmds, testIgnoreFilter, testIgnorePaths)
+	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "1:bug" {
+		t.Errorf("got %v, want commands for api and its label", got)
+	}
+}
+
+func TestIgnoreChanges(t *testing.T) {
+	changes := []BlockChange{
+		{Address: "gitlab_project.api", Kind: ChangeChanged, Attributes: []AttributeChange{
+			{Path: "description", Kind: ChangeChanged, Old: `"a"`, New: `"b"`},
+			{Path: "visibility_level", Kind: ChangeChanged, Old: `"private"`, New: `"internal"`},
+		}},
+		{Address: "gitlab_project.docs", Kind: ChangeChanged, Attributes: []AttributeChange{
+			{Path: "description", Kind: ChangeAdded, New: `"docs"`},
+		}},
+		{Address: "gitlab_project.sandbox_test", Kind: ChangeAdded},
+		{Address: "import.gitlab_project.sandbox_test", Kind: ChangeAdded},
+		{Address: "var.gitlab_project_variable", Kind: ChangeChanged, Attributes: []AttributeChange{
+			{Path: `default["my-group/api"]["TOKEN:*"].description`, Kind: ChangeChanged, Old: `""`, New: `"x"`},
+			{Path: `default["my-group/api"]["TOKEN:*"].masked`, Kind: ChangeChanged, Old: "false", New: "true"},
+			{Path: `default["my-group/sandbox/test"]["KEY:*"]`, Kind: ChangeAdded, New: "{}"},
+		}},
+		{Address: "data.gitlab_user.main", Kind: ChangeAdded},
+	}
+
+	got := IgnoreChanges(changes, testIgnoreFilter, testIgnorePaths)
+
+	want := map[string]int{
+		"gitlab_project.api":          1,
+		"var.gitlab_project_variable": 1,
+		"data.gitlab_user.main":       0,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(got), len(want), got)
+	}
+	for _, c := range got {
+		n, ok := want[c.Address]
+		if !ok || len(c.Attributes) != n {
+			t.Errorf("unexpected change %+v", c)
+		}
+	}
+	if got[0].Attributes[0].Path != "visibility_level" {
+		t.Errorf("api attributes = %+v, want only visibility_level", got[0].Attributes)
+	}
+}
+
+func TestIgnoreOrphans(t *testing.T) {
+	orphans := []Orphan{
+		{Address: "gitlab_project.sandbox_test"},
+		{Address: "gitlab_project.api"},
+		{Address: `var.gitlab_project_label["my-group/sandbox/old"]`},
+		{Address: `var.gitlab_project_label["my-group/old"]`},
+	}
+
+	got := IgnoreOrphans(orphans, testIgnoreFilter, testIgnorePaths)
+	if len(got) != 2 || got[0].Address != "gitlab_project.api" || got[1].Address != `var.gitlab_project_label["my-group/old"]` {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestPathSegments(t *testing.T) {
+	tests := map[string][]string{
+		"description":                           {"description"},
+		"push_rules[0].deny_delete_tag":         {"push_rules", "0", "deny_delete_tag"},
+		`default["my-group/api"]["A:*"].masked`: {"default", "my-group/api", "A:*", "masked"},
+		`gitlab_project_label["a\"b"]`:          {"gitlab_project_label", `a"b`},
+	}
+	for in, want := range tests {
+		got := pathSegments(in)
+		if len(got) != len(want) {
+			t.Errorf("pathSegments(%q) = %q, want %q", in, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("pathSegments(%q) = %q, want %q", in, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestKeepIgnoredAttributes(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+	writeTestFiles(t, existingDir, map[string]string{
+		"main.tf": `resource "gitlab_project" "api" {
+  name        = "API"
+  path        = "api"
+  description = "Written by hand"
+}
+`,
+	})
+	writeTestFiles(t, generatedDir, map[string]string{
+		"my_group.tf": `resource "gitlab_project" "api" {
+  name        = "API"
+  path        = "api"
+  description = "Edited in the UI"
+}
+
+resource "gitlab_project" "docs" {
+  name        = "Docs"
+  path        = "docs"
+  description = "Not declared yet"
+}
+
+resource "gitlab_project" "web" {
+  name        = "Web"
+  path        = "web"
+  description = "Kept"
+}
+
+variable "gitlab_project_variable" {
+  default = {
+    "my-group/api" = {
+      description = "kept in maps"
+    }
+  }
+}
+`,
+	})
+
+	filter := ignore.Filter{Rules: ignore.Rules{{Type: "gitlab_project", Path: "my-group/[ad]*", Attributes: []string{"description"}}}}
+	paths := map[string]string{"gitlab_project.api": "my-group/api", "gitlab_project.docs": "my-group/docs", "gitlab_project.web": "my-group/web"}
+	if err := KeepIgnored(generatedDir, existingDir, &gitlab.Resources{}, filter, paths); err != nil {
+		t.Fatalf("KeepIgnored error: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(generatedDir, "my_group.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `resource "gitlab_project" "api" {
+  name        = "API"
+  path        = "api"
+  description = "Written by hand"
+}
+
+resource "gitlab_project" "docs" {
+  name = "Docs"
+  path = "docs"
+}
+
+resource "gitlab_project" "web" {
+  name        = "Web"
+  path        = "web"
+  description = "Kept"
+}
+
+variable "gitlab_project_variable" {
+  default = {
+    "my-group/api" = {
+      description = "kept in maps"
+    }
+  }
+}
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestKeepIgnoredUnselected(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+	writeTestFiles(t, existingDir, map[string]string{
+		"approval_rules.tf": `data "gitlab_user" "approvers" {
+  for_each = toset(["alice", "carol"])
+  username = each.key
+}
+
+resource "gitlab_project_approval_rule" "team_a_api_security" {
+  project            = "my-group/team-a/api"
+  name               = "Security"
+  approvals_required = 1
+  user_ids           = [data.gitlab_user.approvers["alice"].id]
+}
+
+resource "gitlab_project_approval_rule" "team_b_web_security" {
+  project            = "my-group/team-b/web"
+  name               = "Security"
+  approvals_required = 1
+  user_ids           = [data.gitlab_user.approvers["carol"].id]
+}
+`,
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+
+resource "gitlab_group" "team_a" {
+  name      = "Team A"
+  path      = "team-a"
+  parent_id = gitlab_group.my_group.id
+}
+`,
+		"group_membership.tf": `variable "gitlab_group_membership" {
+  default = {
+    "my-group" = {
+      "root" = "owner"
+    }
+    "my-group/team-a" = {
+      "alice" = "developer"
+    }
+    "my-group/team-b" = {
+      "carol" = "developer"
+    }
+  }
+}
+`,
+	})
+	writeTestFiles(t, generatedDir, map[string]string{
+		"approval_rules.tf": `data "gitlab_user" "approvers" {
+  for_each = toset(["bob"])
+  username = each.key
+}
+
+resource "gitlab_project_approval_rule" "team_a_api_security" {
+  project            = "my-group/team-a/api"
+  name               = "Security"
+  approvals_required = 1
+  user_ids           = [data.gitlab_user.approvers["bob"].id]
+}
+`,
+		"my_group.tf": `resource "gitlab_group" "team_a" {
+  name      = "Team A"
+  path      = "team-a"
+  parent_id = 1
+}
+`,
+		"group_membership.tf": `variable "gitlab_group_membership" {
+  default = {
+    "my-group/team-a" = {
+      "bob" = "developer"
+    }
+  }
+}
+`,
+	})
+
+	filter := ignore.Filter{Include: []string{"my-group/team-a"}}
+	resources := &gitlab.Resources{Groups: []*gl.Group{{ID: 2, FullPath: "my-group/team-a", ParentID: 1}}}
+	paths := map[string]string{
+		"gitlab_group.my_group":                            "my-group",
+		"gitlab_group.team_a":                              "my-group/team-a",
+		"gitlab_project_approval_rule.team_a_api_security": "my-group/team-a/api",
+		"gitlab_project_approval_rule.team_b_web_security": "my-group/team-b/web",
+	}
+	if err := KeepIgnored(generatedDir, existingDir, resources, filter, paths); err != nil {
+		t.Fatalf("KeepIgnored error: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"approval_rules.tf": `data "gitlab_user" "approvers" {
+  for_each = toset(["bob", "carol"])
+  username = each.key
+}
+
+resource "gitlab_project_approval_rule" "team_a_api_security" {
+  project            = "my-group/team-a/api"
+  name               = "Security"
+  approvals_required = 1
+  user_ids           = [data.gitlab_user.approvers["bob"].id]
+}
+
+resource "gitlab_project_approval_rule" "team_b_web_security" {
+  project            = "my-group/team-b/web"
+  name               = "Security"
+  approvals_required = 1
+  user_ids           = [data.gitlab_user.approvers["carol"].id]
+}
+`,
+		"my_group.tf": `resource "gitlab_group" "team_a" {
+  name      = "Team A"
+  path      = "team-a"
+  parent_id = gitlab_group.my_group.id
+}
+
+resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+`,
+		"group_membership.tf": `variable "gitlab_group_membership" {
+  default = {
+    "my-group" = {
+      "root" = "owner"
+    }
+    "my-group/team-a" = {
+      "bob" = "developer"
+    }
+    "my-group/team-b" = {
+      "carol" = "developer"
+    }
+  }
+}
+`,
+	} {
+		got, err := os.ReadFile(filepath.Join(generatedDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", name, got, want)
+		}
+	}
+}
@@ -3,8 +3,14 @@ package terraform
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
 	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
 	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
 	gl "gitlab.com/gitlab-org/api/client-go"
@@ -14,12 +20,22 @@ import (
 type ImportCommand struct {
 	Address string
 	ID      string
+	// Key is the for_each key of the instance Address points to, empty for
+	// resources without for_each.
+	Key string
+}
+
+// instanceImport returns the import command of the for_each instance key of
+// resource.
+func instanceImport(resource, key, id string) ImportCommand {
+	return ImportCommand{Address: fmt.Sprintf("%s[%q]", resource, key), ID: id, Key: key}
 }
 
 // GenerateImportCommands returns import commands for resources that exist in
 // the API response but not in the existing terraform files.
 func GenerateImportCommands(resources *gitlab.Resources, existingResources map[string]bool, mainGroup string, skipSet skip.Set) []ImportCommand {
 	var cmds []ImportCommand
+	humanMembers, serviceAccountMembers := splitGroupMembers(resources.GroupMembers, resources.ServiceAccounts)
 
 	for _, g := range resources.Groups {
 		if g == nil {
@@ -57,10 +73,37 @@ func GenerateImportCommands(resources *gitlab.Resources, existingResources map[s
 			name := normalizeToTerraformName(g.Path)
 			key := "gitlab_group_membership." + name
 			if !existingResources[key] {
-				for _, m := range resources.GroupMembers[g.ID] {
+				for _, m := range humanMembers[g.ID] {
+					cmds = append(cmds, instanceImport(key, m.Username, fmt.Sprintf("%d:%d", g.ID, m.ID)))
+				}
+			}
+
+			saName := serviceAccountMembershipResourceName(g)
+			if !existingResources["gitlab_group_membership."+saName] {
+				for _, m := range serviceAccountMembers[g.ID] {
+					cmds = append(cmds, instanceImport("gitlab_group_membership."+saName, m.Username, fmt.Sprintf("%d:%d", g.ID, m.ID)))
+				}
+			}
+
+			if !existingResources["gitlab_group_share_group."+name] {
+				for _, sg := range resources.GroupShares[g.ID] {
+					cmds = append(cmds, instanceImport("gitlab_group_share_group."+name, sg.GroupFullPath, fmt.Sprintf("%d:%d", g.ID, sg.GroupID)))
+				}
+			}
+		}
+	}
+
+	if !skipSet.Has("service_accounts") {
+		for _, g := range resources.Groups {
+			if g == nil {
+				continue
+			}
+			for _, sa := range resources.ServiceAccounts[g.ID] {
+				key := "gitlab_group_service_account." + serviceAccountResourceName(g, sa)
+				if !existingResources[key] {
 					cmds = append(cmds, ImportCommand{
-						Address: fmt.Sprintf("gitlab_group_membership.%s[\"%s\"]", name, m.Username),
-						ID:      fmt.Sprintf("%d:%d", g.ID, m.ID),
+						Address: key,
+						ID:      fmt.Sprintf("%d:%d", g.ID, sa.ID),
 					})
 				}
 			}
@@ -76,10 +119,22 @@ func GenerateImportCommands(resources *gitlab.Resources, existingResources map[s
 			key := "gitlab_project_share_group." + name
 			if !existingResources[key] {
 				for _, sg := range p.SharedWithGroups {
-					cmds = append(cmds, ImportCommand{
-						Address: fmt.Sprintf("gitlab_project_share_group.%s[\"%s\"]", name, sg.GroupFullPath),
-						ID:      fmt.Sprintf("%d:%d", p.ID, sg.GroupID),
-					})
+					cmds = append(cmds, instanceImport(key, sg.GroupFullPath, fmt.Sprintf("%d:%d", p.ID, sg.GroupID)))
+				}
+			}
+		}
+	}
+
+	if !skipSet.Has("memberships") {
+		for _, p := range resources.Projects {
+			if p == nil {
+				continue
+			}
+			name := projectResourceName(p)
+			key := "gitlab_project_membership." + name
+			if !existingResources[key] {
+				for _, m := range resources.ProjectMembers[p.ID] {
+					cmds = append(cmds, instanceImport(key, m.Username, fmt.Sprintf("%d:%d", p.ID, m.ID)))
 				}
 			}
 		}
@@ -94,10 +149,7 @@ func GenerateImportCommands(resources *gitlab.Resources, existingResources map[s
 			key := "gitlab_group_label." + name
 			if !existingResources[key] {
 				for _, l := range resources.GroupLabels[g.ID] {
-					cmds = append(cmds, ImportCommand{
-						Address: fmt.Sprintf("gitlab_group_label.%s[\"%s\"]", name, l.Name),
-						ID:      fmt.Sprintf("%d:%d", g.ID, l.ID),
-					})
+					cmds = append(cmds, instanceImport(key, l.Name, fmt.Sprintf("%d:%d", g.ID, l.ID)))
 				}
 			}
 		}
@@ -112,10 +164,37 @@ func GenerateImportCommands(resources *gitlab.Resources, existingResources map[s
 			key := "gitlab_project_label." + name
 			if !existingResources[key] {
 				for _, l := range resources.ProjectLabels[p.ID] {
-					cmds = append(cmds, ImportCommand{
-						Address: fmt.Sprintf("gitlab_project_label.%s[\"%s\"]", name, l.Name),
-						ID:      fmt.Sprintf("%d:%d", p.ID, l.ID),
-					})
+					cmds = append(cmds, instanceImport(key, l.Name, fmt.Sprintf("%d:%d", p.ID, l.ID)))
+				}
+			}
+		}
+	}
+
+	if !skipSet.Has("variables") {
+		for _, g := range resources.Groups {
+			if g == nil {
+				continue
+			}
+			name := normalizeToTerraformName(g.Path)
+			key := "gitlab_group_variable." + name
+			if !existingResources[key] {
+				for _, v := range resources.GroupVariables[g.ID] {
+					varKey := ciVariableKey(v.Key, v.EnvironmentScope)
+					cmds = append(cmds, instanceImport(key, varKey, fmt.Sprintf("%d:%s", g.ID, varKey)))
+				}
+			}
+		}
+
+		for _, p := range resources.Projects {
+			if p == nil {
+				continue
+			}
+			name := projectResourceName(p)
+			key := "gitlab_project_variable." + name
+			if !existingResources[key] {
+				for _, v := range resources.ProjectVariables[p.ID] {
+					varKey := ciVariableKey(v.Key, v.EnvironmentScope)
+					cmds = append(cmds, instanceImport(key, varKey, fmt.Sprintf("%d:%s", p.ID, varKey)))
 				}
 			}
 		}
@@ -183,9 +262,157 @@ func GenerateImportCommands(resources *gitlab.Resources, existingResources map[s
 		}
 	}
 
+	if !skipSet.Has("branch_protection") {
+		for _, p := range resources.Projects {
+			if p == nil {
+				continue
+			}
+			branchNames := branchProtectionNames(p, resources.ProtectedBranches[p.ID])
+			for _, b := range resources.ProtectedBranches[p.ID] {
+				if b == nil {
+					continue
+				}
+				key := "gitlab_branch_protection." + branchNames[b.Name]
+				if !existingResources[key] {
+					cmds = append(cmds, ImportCommand{
+						Address: key,
+						ID:      fmt.Sprintf("%d:%s", p.ID, b.Name),
+					})
+				}
+			}
+			tagNames := tagProtectionNames(p, resources.ProtectedTags[p.ID])
+			for _, t := range resources.ProtectedTags[p.ID] {
+				if t == nil {
+					continue
+				}
+				key := "gitlab_tag_protection." + tagNames[t.Name]
+				if !existingResources[key] {
+					cmds = append(cmds, ImportCommand{
+						Address: key,
+						ID:      fmt.Sprintf("%d:%s", p.ID, t.Name),
+					})
+				}
+			}
+		}
+	}
+
+	if !skipSet.Has("approval_rules") {
+		for _, p := range resources.Projects {
+			if p == nil {
+				continue
+			}
+			ruleNames := approvalRuleNames(p, resources.ApprovalRules[p.ID])
+			for _, r := range manageableApprovalRules(resources.ApprovalRules[p.ID]) {
+				key := "gitlab_project_approval_rule." + ruleNames[r.ID]
+				if !existingResources[key] {
+					cmds = append(cmds, ImportCommand{
+						Address: key,
+						ID:      fmt.Sprintf("%d:%d", p.ID, r.ID),
+					})
+				}
+			}
+		}
+	}
+
+	if !skipSet.Has("mr_approvals") {
+		for _, p := range resources.Projects {
+			if p == nil || isDefaultMRApprovals(resources.MRApprovals[p.ID]) {
+				continue
+			}
+			key := "gitlab_project_level_mr_approvals." + mrApprovalsResourceName(p)
+			if !existingResources[key] {
+				cmds = append(cmds, ImportCommand{
+					Address: key,
+					ID:      fmt.Sprintf("%d", p.ID),
+				})
+			}
+		}
+	}
+
 	return cmds
 }
 
+// WriteImportBlocks writes an import block for each command, so imports run
+// as part of terraform plan and apply instead of as separate commands.
+func WriteImportBlocks(w io.Writer, cmds []ImportCommand) error {
+	f := hclwrite.NewEmptyFile()
+	rootBody := f.Body()
+
+	for i, cmd := range cmds {
+		resource, _, _ := strings.Cut(cmd.Address, "[")
+		resourceType, name, _ := strings.Cut(resource, ".")
+		if !hclsyntax.ValidIdentifier(resourceType) || !hclsyntax.ValidIdentifier(name) {
+			return fmt.Errorf("invalid import address %s", cmd.Address)
+		}
+		to := hcl.Traversal{
+			hcl.TraverseRoot{Name: resourceType},
+			hcl.TraverseAttr{Name: name},
+		}
+		if cmd.Key != "" {
+			to = append(to, hcl.TraverseIndex{Key: cty.StringVal(cmd.Key)})
+		}
+		if i > 0 {
+			rootBody.AppendNewline()
+		}
+		body := rootBody.AppendNewBlock("import", nil).Body()
+		body.SetAttributeTraversal("to", to)
+		body.SetAttributeValue("id", cty.StringVal(cmd.ID))
+	}
+
+	_, err := w.Write(f.Bytes())
+	return err
+}
+
+// SkipDeclaredMembers drops the import commands of group members that are
+// declared by a hand-written gitlab_group_membership of their own, going by
+// the identities ParseExistingResources returns. Generated memberships cover
+// all members of a group through for_each, so GenerateImportCommands cannot
+// tell these apart by address.
+func SkipDeclaredMembers(cmds []ImportCommand, identities map[string]string, resources *gitlab.Resources) []ImportCommand {
+	declared := make(map[string]bool)
+	for _, id := range identities {
+		if strings.HasPrefix(id, "gitlab_group_membership:") {
+			declared[id] = true
+		}
+	}
+	if len(declared) == 0 {
+		return cmds
+	}
+
+	groupPaths, _ := fullPaths(resources)
+	var result []ImportCommand
+	for _, cmd := range cmds {
+		if strings.HasPrefix(cmd.Address, "gitlab_group_membership.") {
+			groupID, userID, _ := strings.Cut(cmd.ID, ":")
+			gid, gerr := strconv.ParseInt(groupID, 10, 64)
+			uid, uerr := strconv.ParseInt(userID, 10, 64)
+			if gerr == nil && uerr == nil && declared[groupMemberIdentity(groupPaths[gid], uid)] {
+				continue
+			}
+		}
+		result = append(result, cmd)
+	}
+	return result
+}
+
+// RenameImportCommands rewrites the resource part of each address to the
+// existing address it was matched to by MatchExistingResources, so objects
+// declared under a different name are imported into that declaration.
+func RenameImportCommands(cmds []ImportCommand, matches map[string]string) []ImportCommand {
+	result := make([]ImportCommand, len(cmds))
+	for i, cmd := range cmds {
+		resource, key, hasKey := strings.Cut(cmd.Address, "[")
+		if existing, ok := matches[resource]; ok {
+			cmd.Address = existing
+			if hasKey {
+				cmd.Address += "[" + key
+			}
+		}
+		result[i] = cmd
+	}
+	return result
+}
+
 // PrintImportCommands writes terraform import commands to w.
 func PrintImportCommands(w io.Writer, cmds []ImportCommand) error {
 	for _, cmd := range cmds {
@@ -5,6 +5,9 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+
 	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
 	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
 	gl "gitlab.com/gitlab-org/api/client-go"
@@ -516,3 +519,417 @@ func TestGenerateImportCommandsSkipPipelineSchedules(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateImportCommandsNewVariables(t *testing.T) {
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{
+			{ID: 10, Path: "my-group", FullPath: "my-group"},
+		},
+		Projects: []*gl.Project{
+			{
+				ID:        1,
+				Path:      "my-project",
+				Namespace: &gl.ProjectNamespace{FullPath: "my-group"},
+			},
+		},
+		GroupVariables: map[int64][]*gl.GroupVariable{
+			10: {{Key: "API_URL", EnvironmentScope: "*"}},
+		},
+		ProjectVariables: map[int64][]*gl.ProjectVariable{
+			1: {
+				{Key: "TOKEN", EnvironmentScope: "production"},
+				{Key: "TOKEN", EnvironmentScope: "staging"},
+			},
+		},
+	}
+
+	existing := map[string]bool{
+		"gitlab_group.my_group":              true,
+		"gitlab_project.my_group_my_project": true,
+	}
+
+	cmds := GenerateImportCommands(resources, existing, "my-group", nil)
+
+	want := []ImportCommand{
+		{Address: `gitlab_group_variable.my_group["API_URL:*"]`, ID: "10:API_URL:*", Key: "API_URL:*"},
+		{Address: `gitlab_project_variable.my_group_my_project["TOKEN:production"]`, ID: "1:TOKEN:production", Key: "TOKEN:production"},
+		{Address: `gitlab_project_variable.my_group_my_project["TOKEN:staging"]`, ID: "1:TOKEN:staging", Key: "TOKEN:staging"},
+	}
+	if len(cmds) != len(want) {
+		t.Fatalf("expected %d commands, got %d", len(want), len(cmds))
+	}
+	for i, w := range want {
+		if cmds[i] != w {
+			t.Errorf("cmds[%d] = %+v, want %+v", i, cmds[i], w)
+		}
+	}
+}
+
+func TestGenerateImportCommandsSkipVariables(t *testing.T) {
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{
+			{ID: 10, Path: "grp", FullPath: "grp"},
+		},
+		GroupVariables: map[int64][]*gl.GroupVariable{
+			10: {{Key: "API_URL", EnvironmentScope: "*"}},
+		},
+	}
+
+	skipSet := skip.Set{"variables": true}
+	cmds := GenerateImportCommands(resources, nil, "grp", skipSet)
+
+	for _, cmd := range cmds {
+		if strings.Contains(cmd.Address, "_variable.") {
+			t.Errorf("should not generate variable import when skipped: %s", cmd.Address)
+		}
+	}
+}
+
+func TestGenerateImportCommandsNewBranchProtection(t *testing.T) {
+	resources := &gitlab.Resources{
+		Projects: []*gl.Project{
+			{
+				ID:        1,
+				Path:      "my-project",
+				Namespace: &gl.ProjectNamespace{FullPath: "parent"},
+			},
+		},
+		ProtectedBranches: map[int64][]*gl.ProtectedBranch{
+			1: {{ID: 5, Name: "release/*"}},
+		},
+		ProtectedTags: map[int64][]*gl.ProtectedTag{
+			1: {{Name: "v*"}},
+		},
+	}
+
+	existing := map[string]bool{
+		"gitlab_project.parent_my_project": true,
+	}
+
+	cmds := GenerateImportCommands(resources, existing, "parent", nil)
+
+	want := []ImportCommand{
+		{Address: "gitlab_branch_protection.parent_my_project_release_wildcard", ID: "1:release/*"},
+		{Address: "gitlab_tag_protection.parent_my_project_vwildcard", ID: "1:v*"},
+	}
+	if len(cmds) != len(want) {
+		t.Fatalf("expected %d commands, got %d", len(want), len(cmds))
+	}
+	for i, w := range want {
+		if cmds[i] != w {
+			t.Errorf("cmds[%d] = %+v, want %+v", i, cmds[i], w)
+		}
+	}
+}
+
+func TestGenerateImportCommandsSkipBranchProtection(t *testing.T) {
+	resources := &gitlab.Resources{
+		Projects: []*gl.Project{
+			{
+				ID:        1,
+				Path:      "proj",
+				Namespace: &gl.ProjectNamespace{FullPath: "grp"},
+			},
+		},
+		ProtectedBranches: map[int64][]*gl.ProtectedBranch{
+			1: {{ID: 5, Name: "main"}},
+		},
+	}
+
+	skipSet := skip.Set{"branch_protection": true}
+	cmds := GenerateImportCommands(resources, nil, "grp", skipSet)
+
+	for _, cmd := range cmds {
+		if strings.Contains(cmd.Address, "_protection.") {
+			t.Errorf("should not generate protection import when skipped: %s", cmd.Address)
+		}
+	}
+}
+
+func TestGenerateImportCommandsNewApprovals(t *testing.T) {
+	resources := &gitlab.Resources{
+		Projects: []*gl.Project{
+			{
+				ID:        1,
+				Path:      "my-project",
+				Namespace: &gl.ProjectNamespace{FullPath: "parent"},
+			},
+		},
+		ApprovalRules: map[int64][]*gl.ProjectApprovalRule{
+			1: {
+				{ID: 5, Name: "Security"},
+				{ID: 6, Name: "Code Owners", RuleType: "code_owner"},
+				{ID: 7, Name: "security"},
+			},
+		},
+		MRApprovals: map[int64]*gl.ProjectApprovals{
+			1: {ResetApprovalsOnPush: false},
+		},
+	}
+
+	existing := map[string]bool{
+		"gitlab_project.parent_my_project": true,
+	}
+
+	cmds := GenerateImportCommands(resources, existing, "parent", nil)
+
+	want := []ImportCommand{
+		{Address: "gitlab_project_approval_rule.parent_my_project_security", ID: "1:5"},
+		{Address: "gitlab_project_approval_rule.parent_my_project_security_2", ID: "1:7"},
+		{Address: "gitlab_project_level_mr_approvals.parent_my_project", ID: "1"},
+	}
+	if len(cmds) != len(want) {
+		t.Fatalf("expected %d commands, got %d", len(want), len(cmds))
+	}
+	for i, w := range want {
+		if cmds[i] != w {
+			t.Errorf("cmds[%d] = %+v, want %+v", i, cmds[i], w)
+		}
+	}
+}
+
+func TestGenerateImportCommandsServiceAccounts(t *testing.T) {
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{
+			{ID: 10, Path: "my-group", FullPath: "my-group"},
+		},
+		GroupMembers: map[int64][]*gl.GroupMember{
+			10: {
+				{ID: 100, Username: "alice"},
+				{ID: 200, Username: "service_account_group_10_ci"},
+			},
+		},
+		ServiceAccounts: map[int64][]*gl.GroupServiceAccount{
+			10: {{ID: 200, UserName: "service_account_group_10_ci"}},
+		},
+	}
+
+	existing := map[string]bool{
+		"gitlab_group.my_group": true,
+	}
+
+	cmds := GenerateImportCommands(resources, existing, "my-group", nil)
+
+	want := []ImportCommand{
+		{Address: `gitlab_group_membership.my_group["alice"]`, ID: "10:100", Key: "alice"},
+		{Address: `gitlab_group_membership.my_group_service_accounts["service_account_group_10_ci"]`, ID: "10:200", Key: "service_account_group_10_ci"},
+		{Address: "gitlab_group_service_account.my_group_service_account_group_10_ci", ID: "10:200"},
+	}
+	if len(cmds) != len(want) {
+		t.Fatalf("expected %d commands, got %d: %+v", len(want), len(cmds), cmds)
+	}
+	for i, w := range want {
+		if cmds[i] != w {
+			t.Errorf("cmds[%d] = %+v, want %+v", i, cmds[i], w)
+		}
+	}
+}
+
+func TestGenerateImportCommandsNewProjectMembership(t *testing.T) {
+	resources := &gitlab.Resources{
+		Projects: []*gl.Project{
+			{
+				ID:        1,
+				Path:      "my-project",
+				Namespace: &gl.ProjectNamespace{FullPath: "parent"},
+			},
+		},
+		ProjectMembers: map[int64][]*gl.ProjectMember{
+			1: {{ID: 100, Username: "alice"}},
+		},
+	}
+
+	existing := map[string]bool{
+		"gitlab_project.parent_my_project": true,
+	}
+
+	cmds := GenerateImportCommands(resources, existing, "parent", nil)
+
+	if len(cmds) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(cmds))
+	}
+	if cmds[0].Address != `gitlab_project_membership.parent_my_project["alice"]` {
+		t.Errorf("address = %q, want %q", cmds[0].Address, `gitlab_project_membership.parent_my_project["alice"]`)
+	}
+	if cmds[0].ID != "1:100" {
+		t.Errorf("id = %q, want %q", cmds[0].ID, "1:100")
+	}
+
+	skipSet := skip.Set{"memberships": true}
+	if cmds := GenerateImportCommands(resources, existing, "parent", skipSet); len(cmds) != 0 {
+		t.Errorf("expected 0 commands when memberships skipped, got %d", len(cmds))
+	}
+}
+
+func TestGenerateImportCommandsNewGroupShareGroup(t *testing.T) {
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{
+			{ID: 1, Path: "parent", FullPath: "parent"},
+		},
+		GroupShares: gitlab.GroupShares{
+			1: {{GroupID: 20, GroupFullPath: "other/team"}},
+		},
+	}
+
+	existing := map[string]bool{
+		"gitlab_group.parent":            true,
+		"gitlab_group_membership.parent": true,
+	}
+
+	cmds := GenerateImportCommands(resources, existing, "parent", nil)
+
+	if len(cmds) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(cmds))
+	}
+	if cmds[0].Address != `gitlab_group_share_group.parent["other/team"]` {
+		t.Errorf("address = %q, want %q", cmds[0].Address, `gitlab_group_share_group.parent["other/team"]`)
+	}
+	if cmds[0].ID != "1:20" {
+		t.Errorf("id = %q, want %q", cmds[0].ID, "1:20")
+	}
+
+	skipSet := skip.Set{"memberships": true}
+	if cmds := GenerateImportCommands(resources, existing, "parent", skipSet); len(cmds) != 0 {
+		t.Errorf("expected 0 commands when memberships skipped, got %d", len(cmds))
+	}
+
+	existing["gitlab_group_share_group.parent"] = true
+	if cmds := GenerateImportCommands(resources, existing, "parent", nil); len(cmds) != 0 {
+		t.Errorf("expected 0 commands when share resource exists, got %d", len(cmds))
+	}
+}
+
+func TestSkipDeclaredMembers(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"main.tf": `resource "gitlab_group" "team" {
+  path = "my-group"
+}
+
+resource "gitlab_group_membership" "alice" {
+  group_id     = gitlab_group.team.id
+  user_id      = 100
+  access_level = "developer"
+}
+
+resource "gitlab_group_membership" "bob" {
+  group_id     = 10
+  user_id      = data.gitlab_user.bob.id
+  access_level = "developer"
+}
+`,
+	})
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{{ID: 10, Path: "my-group", FullPath: "my-group"}},
+		GroupMembers: map[int64][]*gl.GroupMember{
+			10: {
+				{ID: 100, Username: "alice", AccessLevel: gl.DeveloperPermissions},
+				{ID: 200, Username: "bob", AccessLevel: gl.DeveloperPermissions},
+			},
+		},
+	}
+
+	identities, err := ParseExistingResources(dir, resources)
+	if err != nil {
+		t.Fatalf("ParseExistingResources error: %v", err)
+	}
+	cmds := GenerateImportCommands(resources, map[string]bool{"gitlab_group.my_group": true}, "my-group", nil)
+	got := SkipDeclaredMembers(cmds, identities, resources)
+
+	// bob's user ID is only known to terraform, so he is still imported.
+	want := []ImportCommand{
+		{Address: `gitlab_group_membership.my_group["bob"]`, ID: "10:200", Key: "bob"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cmds[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRenameImportCommands(t *testing.T) {
+	cmds := []ImportCommand{
+		{Address: "gitlab_project.backend_api", ID: "42"},
+		{Address: `gitlab_project_label.backend_api["bug"]`, ID: "42:7", Key: "bug"},
+		{Address: "gitlab_project.backend_web", ID: "43"},
+	}
+	matches := map[string]string{
+		"gitlab_project.backend_api":       "gitlab_project.api",
+		"gitlab_project_label.backend_api": "gitlab_project_label.api",
+	}
+
+	got := RenameImportCommands(cmds, matches)
+	want := []ImportCommand{
+		{Address: "gitlab_project.api", ID: "42"},
+		{Address: `gitlab_project_label.api["bug"]`, ID: "42:7", Key: "bug"},
+		{Address: "gitlab_project.backend_web", ID: "43"},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cmds[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteImportBlocks(t *testing.T) {
+	cmds := []ImportCommand{
+		{Address: "gitlab_project.parent_my_project", ID: "1"},
+		{Address: `gitlab_group_membership.parent["alice"]`, ID: "1:100", Key: "alice"},
+		{Address: `gitlab_project_variable.parent_my_project["TOKEN:*"]`, ID: "1:TOKEN:*", Key: "TOKEN:*"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteImportBlocks(&buf, cmds); err != nil {
+		t.Fatalf("WriteImportBlocks error: %v", err)
+	}
+
+	compareGolden(t, "import_blocks.tf", buf.String())
+}
+
+func TestWriteImportBlocksSpecialKeys(t *testing.T) {
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{{ID: 10, Path: "my-group", FullPath: "my-group"}},
+		GroupLabels: map[int64][]*gl.GroupLabel{
+			10: {
+				{ID: 1, Name: `say "hi"`},
+				{ID: 2, Name: `back\slash`},
+				{ID: 3, Name: "${template}"},
+			},
+		},
+	}
+	cmds := GenerateImportCommands(resources, map[string]bool{"gitlab_group.my_group": true}, "my-group", nil)
+
+	var buf bytes.Buffer
+	if err := WriteImportBlocks(&buf, cmds); err != nil {
+		t.Fatalf("WriteImportBlocks error: %v", err)
+	}
+
+	file, diags := hclsyntax.ParseConfig(buf.Bytes(), "imports.tf", hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		t.Fatalf("parsing import blocks: %s\n%s", diags.Error(), buf.String())
+	}
+	blocks := file.Body.(*hclsyntax.Body).Blocks
+	if len(blocks) != len(cmds) {
+		t.Fatalf("got %d import blocks, want %d", len(blocks), len(cmds))
+	}
+	for i, block := range blocks {
+		to, diags := hcl.AbsTraversalForExpr(block.Body.Attributes["to"].Expr)
+		if diags.HasErrors() {
+			t.Fatalf("import block %d: %s", i, diags.Error())
+		}
+		index, ok := to[len(to)-1].(hcl.TraverseIndex)
+		if !ok || index.Key.AsString() != cmds[i].Key {
+			t.Errorf("import block %d targets %#v, want key %q", i, to[len(to)-1], cmds[i].Key)
+		}
+	}
+}
+
+func TestWriteImportBlocksInvalidAddress(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteImportBlocks(&buf, []ImportCommand{{Address: "not valid[", ID: "1"}}); err == nil {
+		t.Fatal("expected error for invalid address")
+	}
+}
@@ -0,0 +1,367 @@
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
+)
+
+// orphanResourceTypes maps the resource types checked for orphans to the
+// skip key that disables them. gitlab_project_level_mr_approvals is left out
+// because it is only generated for non-default settings, so a missing block
+// does not mean the project is gone.
+var orphanResourceTypes = map[string]string{
+	"gitlab_group":                      "",
+	"gitlab_project":                    "",
+	"gitlab_group_membership":           "memberships",
+	"gitlab_group_share_group":          "memberships",
+	"gitlab_project_membership":         "memberships",
+	"gitlab_project_share_group":        "memberships",
+	"gitlab_group_label":                "labels",
+	"gitlab_project_label":              "labels",
+	"gitlab_group_variable":             "variables",
+	"gitlab_project_variable":           "variables",
+	"gitlab_group_hook":                 "hooks",
+	"gitlab_project_hook":               "hooks",
+	"gitlab_pipeline_schedule":          "schedules",
+	"gitlab_pipeline_schedule_variable": "schedules",
+	"gitlab_branch_protection":          "branch_protection",
+	"gitlab_tag_protection":             "branch_protection",
+	"gitlab_project_approval_rule":      "approval_rules",
+	"gitlab_group_service_account":      "service_accounts",
+}
+
+// Orphan is a resource, or an entry of a for_each variable map, that is
+// declared in terraform but whose GitLab object no longer exists.
+type Orphan struct {
+	Address string
+	File    string
+	Line    int
+}
+
+// IsResource reports whether the orphan is a whole resource rather than a
+// variable map entry.
+func (o Orphan) IsResource() bool {
+	return !strings.HasPrefix(o.Address, "var.")
+}
+
+// FindOrphans compares the terraform files in existingDir with the files
+// generated from the GitLab API in generatedDir. Resources of managed types
+// that were neither generated nor matched to a generated resource by
+// MatchExistingResources are orphans if the GitLab object they manage can be
+// identified from the code and no generated resource manages it. Resources
+// whose object depends on data sources, variables or locals may still exist
+// and are left out. Top-level keys of generated for_each variables that are
+// no longer a group or project path in GitLab are orphans too, as are entries
+// below a still existing path that were not generated anymore, e.g. a removed
+// member of a group.
+func FindOrphans(resources *gitlab.Resources, existingDir, generatedDir string, matches map[string]string, skipSet skip.Set) ([]Orphan, error) {
+	existingResources, existingVars, err := parseDeclarations(existingDir)
+	if err != nil {
+		return nil, err
+	}
+	generatedResources, generatedVars, err := parseDeclarations(generatedDir)
+	if err != nil {
+		return nil, err
+	}
+	existingIDs, err := ParseExistingResources(existingDir, resources)
+	if err != nil {
+		return nil, err
+	}
+	live, err := liveIdentities(resources, generatedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]bool, len(matches))
+	for _, addr := range matches {
+		matched[addr] = true
+	}
+
+	var orphans []Orphan
+	for addr, res := range existingResources {
+		resourceType, _, _ := strings.Cut(addr, ".")
+		skipKey, ok := orphanResourceTypes[resourceType]
+		if !ok || (skipKey != "" && skipSet.Has(skipKey)) {
+			continue
+		}
+		if _, ok := generatedResources[addr]; ok || matched[addr] {
+			continue
+		}
+		if id := existingIDs[addr]; id != "" && !live[id] {
+			orphans = append(orphans, Orphan{Address: addr, File: res.file, Line: res.line})
+		}
+	}
+
+	paths := make(map[string]bool)
+	for _, g := range resources.Groups {
+		if g != nil {
+			paths[g.FullPath] = true
+		}
+	}
+	for _, p := range resources.Projects {
+		if p != nil {
+			paths[projectFullPath(p)] = true
+		}
+	}
+
+	// Entries of iterated maps are GitLab objects themselves, e.g. the
+	// members of a group, so they are compared with the generated entries.
+	iterated := make(map[string]bool)
+	for _, res := range generatedResources {
+		if name, _, ok := strings.Cut(strings.TrimPrefix(res.forEachEntry, "var."), "["); ok {
+			iterated[name] = true
+		}
+	}
+
+	for name, generated := range generatedVars {
+		existing, ok := existingVars[name]
+		if !ok {
+			continue
+		}
+		for _, key := range existing.keys {
+			if !paths[key.name] {
+				orphans = append(orphans, Orphan{
+					Address: fmt.Sprintf("var.%s[%q]", name, key.name),
+					File:    existing.file,
+					Line:    key.line,
+				})
+				continue
+			}
+			genKey, ok := generated.key(key.name)
+			if !iterated[name] || !ok {
+				continue
+			}
+			for _, entry := range key.entries {
+				if _, ok := genKey.entry(entry.name); !ok {
+					orphans = append(orphans, Orphan{
+						Address: fmt.Sprintf("var.%s[%q][%q]", name, key.name, entry.name),
+						File:    existing.file,
+						Line:    entry.line,
+					})
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(orphans, func(a, b Orphan) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Address, b.Address)
+	})
+	return orphans, nil
+}
+
+// liveIdentities returns the identities of the GitLab objects managed by the
+// resources generated in generatedDir, plus those of the group members,
+// which are generated as for_each instances.
+func liveIdentities(resources *gitlab.Resources, generatedDir string) (map[string]bool, error) {
+	blocks, err := parseResourceBlocks(generatedDir)
+	if err != nil {
+		return nil, err
+	}
+	groupPaths, projectPaths := fullPaths(resources)
+	ids := newIdentityResolver(blocks, groupPaths, projectPaths)
+	live := make(map[string]bool, len(blocks))
+	for addr := range blocks {
+		if id := ids.identity(addr); id != "" {
+			live[id] = true
+		}
+	}
+	for _, g := range resources.Groups {
+		if g == nil {
+			continue
+		}
+		for _, m := range resources.GroupMembers[g.ID] {
+			if m != nil {
+				live[groupMemberIdentity(g.FullPath, m.ID)] = true
+			}
+		}
+	}
+	return live, nil
+}
+
+// declaredResource is a resource block found by parseDeclarations.
+type declaredResource struct {
+	file string
+	line int
+	// forEachEntry is the variable map entry the resource iterates, e.g.
+	// `var.gitlab_group_membership["my-group"]`, if any.
+	forEachEntry string
+}
+
+// variableKeys holds the top-level keys of a variable's default map.
+type variableKeys struct {
+	file string
+	keys []variableKey
+}
+
+// key returns the top-level key called name.
+func (v variableKeys) key(name string) (variableKey, bool) {
+	for _, k := range v.keys {
+		if k.name == name {
+			return k, true
+		}
+	}
+	return variableKey{}, false
+}
+
+type variableKey struct {
+	name string
+	line int
+	// entries are the keys of the map the key holds, if any.
+	entries []variableKey
+}
+
+// entry returns the entry called name.
+func (k variableKey) entry(name string) (variableKey, bool) {
+	return variableKeys{keys: k.entries}.key(name)
+}
+
+// parseDeclarations returns the resources and variable default map keys
+// declared in the .tf files of dir, along with the file declaring them.
+func parseDeclarations(dir string) (map[string]declaredResource, map[string]variableKeys, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing tf files: %w", err)
+	}
+
+	resources := make(map[string]declaredResource)
+	vars := make(map[string]variableKeys)
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		f, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+		if diags.HasErrors() {
+			return nil, nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
+		}
+
+		file := filepath.Base(path)
+		for _, block := range f.Body.(*hclsyntax.Body).Blocks {
+			switch {
+			case block.Type == "resource" && len(block.Labels) == 2:
+				attr, forEach := block.Body.Attributes["for_each"]
+				res := declaredResource{
+					file: file,
+					line: block.DefRange().Start.Line,
+				}
+				if forEach {
+					res.forEachEntry = variableEntry(attr.Expr)
+				}
+				resources[block.Labels[0]+"."+block.Labels[1]] = res
+			case block.Type == "variable" && len(block.Labels) == 1:
+				attr, ok := block.Body.Attributes["default"]
+				if !ok {
+					continue
+				}
+				obj, ok := attr.Expr.(*hclsyntax.ObjectConsExpr)
+				if !ok {
+					continue
+				}
+				vars[block.Labels[0]] = variableKeys{file: file, keys: objectKeys(obj, true)}
+			}
+		}
+	}
+	return resources, vars, nil
+}
+
+// objectKeys returns the string keys of obj, with the keys of their values
+// if nested is set and the value is an object too.
+func objectKeys(obj *hclsyntax.ObjectConsExpr, nested bool) []variableKey {
+	var keys []variableKey
+	for _, item := range obj.Items {
+		name, ok := objectKeyString(item.KeyExpr)
+		if !ok {
+			continue
+		}
+		key := variableKey{name: name, line: item.KeyExpr.Range().Start.Line}
+		if value, ok := item.ValueExpr.(*hclsyntax.ObjectConsExpr); ok && nested {
+			key.entries = objectKeys(value, false)
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// variableEntry returns expr as `var.<name>["<key>"]` if it is such a
+// reference, or "" otherwise.
+func variableEntry(expr hclsyntax.Expression) string {
+	traversal, ok := expr.(*hclsyntax.ScopeTraversalExpr)
+	if !ok || len(traversal.Traversal) != 3 || traversal.Traversal.RootName() != "var" {
+		return ""
+	}
+	name, ok := traversal.Traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return ""
+	}
+	index, ok := traversal.Traversal[2].(hcl.TraverseIndex)
+	if !ok || !index.Key.Type().Equals(cty.String) || !index.Key.IsKnown() || index.Key.IsNull() {
+		return ""
+	}
+	return fmt.Sprintf("var.%s[%q]", name.Name, index.Key.AsString())
+}
+
+func objectKeyString(expr hclsyntax.Expression) (string, bool) {
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || !v.IsKnown() || v.IsNull() || !v.Type().Equals(cty.String) {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+// PrintOrphans writes one line per orphan.
+func PrintOrphans(w io.Writer, orphans []Orphan) error {
+	for _, o := range orphans {
+		if _, err := fmt.Fprintf(w, "- %s (%s)\n", o.Address, o.File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRemovedBlocks writes a removed block for every orphaned resource so
+// terraform forgets it without trying to destroy the already deleted object.
+// Variable map entries are skipped, they are removed by editing the map, and
+// removed blocks cannot address single for_each instances.
+func WriteRemovedBlocks(orphans []Orphan, w io.Writer) error {
+	f := hclwrite.NewEmptyFile()
+	rootBody := f.Body()
+
+	first := true
+	for _, o := range orphans {
+		if !o.IsResource() {
+			continue
+		}
+		if !first {
+			rootBody.AppendNewline()
+		}
+		first = false
+
+		resourceType, name, _ := strings.Cut(o.Address, ".")
+		body := rootBody.AppendNewBlock("removed", nil).Body()
+		body.SetAttributeTraversal("from", hcl.Traversal{
+			hcl.TraverseRoot{Name: resourceType},
+			hcl.TraverseAttr{Name: name},
+		})
+		body.AppendNewline()
+		lifecycle := body.AppendNewBlock("lifecycle", nil).Body()
+		lifecycle.SetAttributeValue("destroy", cty.False)
+	}
+
+	_, err := w.Write(f.Bytes())
+	return err
+}
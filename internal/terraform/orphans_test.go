@@ -0,0 +1,259 @@
+package terraform
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/skip"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestFindOrphans(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+
+	writeTestFiles(t, existingDir, map[string]string{
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+
+resource "gitlab_project" "my_group_deleted" {
+  name         = "Deleted"
+  path         = "deleted"
+  namespace_id = gitlab_group.my_group.id
+}
+
+resource "gitlab_project_hook" "my_group_deleted_0" {
+  project = gitlab_project.my_group_deleted.id
+  url     = "https://example.com"
+}
+`,
+		"main.tf": `resource "gitlab_deploy_key" "manual" {
+  title = "manual"
+}
+
+resource "gitlab_project_label" "old" {
+  for_each = {}
+}
+`,
+		"group_membership.tf": `variable "gitlab_group_membership" {
+  default = {
+    "my-group" = {
+      "alice" = "developer"
+      "bob"   = "developer"
+    }
+    "my-group/removed" = {}
+  }
+}
+
+variable "gitlab_group_membership_expires_at" {
+  default = {
+    "my-group" = {
+      "alice" = "2030-01-01"
+    }
+  }
+}
+
+variable "unrelated" {
+  default = {
+    "foo" = "bar"
+  }
+}
+`,
+	})
+	writeTestFiles(t, generatedDir, map[string]string{
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+}
+`,
+		"group_membership.tf": `variable "gitlab_group_membership" {
+  default = {
+    "my-group" = {
+      "alice" = "developer"
+    }
+  }
+}
+
+variable "gitlab_group_membership_expires_at" {
+  default = {
+    "my-group" = {}
+  }
+}
+
+resource "gitlab_group_membership" "my_group" {
+  for_each = var.gitlab_group_membership["my-group"]
+}
+`,
+	})
+
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{{ID: 1, Path: "my-group", FullPath: "my-group"}},
+	}
+
+	orphans, err := FindOrphans(resources, existingDir, generatedDir, nil, skip.Set{"labels": true})
+	if err != nil {
+		t.Fatalf("FindOrphans error: %v", err)
+	}
+
+	want := []Orphan{
+		{Address: `var.gitlab_group_membership["my-group"]["bob"]`, File: "group_membership.tf", Line: 5},
+		{Address: `var.gitlab_group_membership["my-group/removed"]`, File: "group_membership.tf", Line: 7},
+		{Address: "gitlab_project.my_group_deleted", File: "my_group.tf", Line: 6},
+		{Address: "gitlab_project_hook.my_group_deleted_0", File: "my_group.tf", Line: 12},
+	}
+	if len(orphans) != len(want) {
+		t.Fatalf("got %d orphans, want %d: %+v", len(orphans), len(want), orphans)
+	}
+	for i := range want {
+		if orphans[i] != want[i] {
+			t.Errorf("orphans[%d] = %+v, want %+v", i, orphans[i], want[i])
+		}
+	}
+}
+
+func TestFindOrphansUnresolvedIdentity(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+
+	// Neither the project the hook belongs to nor the namespace of the
+	// project is known without evaluating data sources and variables.
+	writeTestFiles(t, existingDir, map[string]string{
+		"main.tf": `data "gitlab_project" "api" {
+  path_with_namespace = "my-group/api"
+}
+
+resource "gitlab_project_hook" "api" {
+  project = data.gitlab_project.api.id
+  url     = "https://example.com/hook"
+}
+
+resource "gitlab_project" "web" {
+  path         = "web"
+  namespace_id = var.namespace_id
+}
+`,
+	})
+	writeTestFiles(t, generatedDir, map[string]string{
+		"my_group.tf": `resource "gitlab_project" "my_group_api" {
+  path         = "api"
+  namespace_id = 1
+}
+
+resource "gitlab_project_hook" "my_group_api_0" {
+  project = gitlab_project.my_group_api.id
+  url     = "https://example.com/other"
+}
+`,
+	})
+
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{{ID: 1, Path: "my-group", FullPath: "my-group"}},
+	}
+
+	orphans, err := FindOrphans(resources, existingDir, generatedDir, nil, nil)
+	if err != nil {
+		t.Fatalf("FindOrphans error: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("expected no orphans, got %+v", orphans)
+	}
+}
+
+func TestFindOrphansSingleGroupMembership(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+
+	writeTestFiles(t, existingDir, map[string]string{
+		"main.tf": `resource "gitlab_group_membership" "alice" {
+  group_id     = 1
+  user_id      = 5
+  access_level = "developer"
+}
+
+resource "gitlab_group_membership" "bob" {
+  group_id     = 1
+  user_id      = 6
+  access_level = "developer"
+}
+`,
+	})
+	writeTestFiles(t, generatedDir, map[string]string{
+		"group_membership.tf": `resource "gitlab_group_membership" "my_group" {
+  for_each = var.gitlab_group_membership["my-group"]
+  group_id = gitlab_group.my_group.id
+}
+`,
+	})
+
+	resources := &gitlab.Resources{
+		Groups:       []*gl.Group{{ID: 1, Path: "my-group", FullPath: "my-group"}},
+		GroupMembers: map[int64][]*gl.GroupMember{1: {{ID: 5, Username: "alice"}}},
+	}
+
+	orphans, err := FindOrphans(resources, existingDir, generatedDir, nil, nil)
+	if err != nil {
+		t.Fatalf("FindOrphans error: %v", err)
+	}
+	want := []Orphan{{Address: "gitlab_group_membership.bob", File: "main.tf", Line: 7}}
+	if len(orphans) != len(want) || orphans[0] != want[0] {
+		t.Errorf("got %+v, want %+v", orphans, want)
+	}
+}
+
+func TestFindOrphansNone(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+}
+`,
+	})
+
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{{ID: 1, Path: "my-group", FullPath: "my-group"}},
+	}
+
+	orphans, err := FindOrphans(resources, dir, dir, nil, nil)
+	if err != nil {
+		t.Fatalf("FindOrphans error: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("expected no orphans, got %+v", orphans)
+	}
+}
+
+func TestWriteRemovedBlocks(t *testing.T) {
+	orphans := []Orphan{
+		{Address: `var.gitlab_group_membership["my-group/removed"]`, File: "group_membership.tf"},
+		{Address: "gitlab_project.my_group_deleted", File: "my_group.tf"},
+		{Address: "gitlab_project_hook.my_group_deleted_0", File: "my_group.tf"},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRemovedBlocks(orphans, &buf); err != nil {
+		t.Fatalf("WriteRemovedBlocks error: %v", err)
+	}
+
+	compareGolden(t, "removed_blocks.tf", buf.String())
+}
+
+func TestPrintOrphans(t *testing.T) {
+	orphans := []Orphan{
+		{Address: `var.gitlab_group_membership["my-group/removed"]`, File: "group_membership.tf"},
+		{Address: "gitlab_project.my_group_deleted", File: "my_group.tf"},
+	}
+
+	var buf bytes.Buffer
+	if err := PrintOrphans(&buf, orphans); err != nil {
+		t.Fatalf("PrintOrphans error: %v", err)
+	}
+
+	want := `- var.gitlab_group_membership["my-group/removed"] (group_membership.tf)
+- gitlab_project.my_group_deleted (my_group.tf)
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -1,41 +1,47 @@
 package terraform
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
-
-	"github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
 )
 
-// ParseExistingResources reads all .tf files in dir and returns a set of
-// "type.name" strings for every resource block found.
-func ParseExistingResources(dir string) (map[string]bool, error) {
-	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+// ParseExistingResources reads all .tf files in dir and returns every
+// "type.name" resource block mapped to the identity of the GitLab object it
+// manages, e.g. a project's namespace and path, or to "" if that cannot be
+// determined statically. IDs written in the files are resolved against
+// resources.
+func ParseExistingResources(dir string, resources *gitlab.Resources) (map[string]string, error) {
+	blocks, err := parseResourceBlocks(dir)
 	if err != nil {
-		return nil, fmt.Errorf("listing tf files: %w", err)
+		return nil, err
 	}
-
-	resources := make(map[string]bool)
-	for _, path := range files {
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return nil, fmt.Errorf("reading %s: %w", path, err)
-		}
-		f, diags := hclwrite.ParseConfig(data, path, hcl.Pos{Line: 1, Column: 1})
-		if diags.HasErrors() {
-			return nil, fmt.Errorf("parsing %s: %s", path, diags.Error())
-		}
-		for _, block := range f.Body().Blocks() {
-			if block.Type() == "resource" {
-				labels := block.Labels()
-				if len(labels) == 2 {
-					resources[labels[0]+"."+labels[1]] = true
-				}
-			}
-		}
+	groupPaths, projectPaths := fullPaths(resources)
+	ids := newIdentityResolver(blocks, groupPaths, projectPaths)
+	identities := make(map[string]string, len(blocks))
+	for _, addr := range sortedKeys(blocks) {
+		identities[addr] = ids.identity(addr)
 	}
+	return identities, nil
+}
+
+// Location is the file name and line a block is declared at.
+type Location struct {
+	File string
+	Line int
+	// ForEach is the variable map entry a for_each resource iterates, e.g.
+	// `var.gitlab_group_membership["my-group"]`.
+	ForEach string
+}
 
-	return resources, nil
+// ParseResourceLocations reads all .tf files in dir and returns where each
+// "type.name" resource is declared.
+func ParseResourceLocations(dir string) (map[string]Location, error) {
+	declared, _, err := parseDeclarations(dir)
+	if err != nil {
+		return nil, err
+	}
+	locations := make(map[string]Location, len(declared))
+	for addr, res := range declared {
+		locations[addr] = Location{File: res.file, Line: res.line, ForEach: res.forEachEntry}
+	}
+	return locations, nil
 }
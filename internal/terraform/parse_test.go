@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
 )
 
 func TestParseExistingResourcesFindsAllBlocks(t *testing.T) {
@@ -23,22 +25,22 @@ resource "gitlab_project" "my_group_my_project" {
 		t.Fatal(err)
 	}
 
-	got, err := ParseExistingResources(dir)
+	got, err := ParseExistingResources(dir, &gitlab.Resources{})
 	if err != nil {
 		t.Fatalf("ParseExistingResources error: %v", err)
 	}
 
-	want := map[string]bool{
-		"gitlab_group.my_group":              true,
-		"gitlab_project.my_group_my_project": true,
+	want := map[string]string{
+		"gitlab_group.my_group":              "gitlab_group:my-group",
+		"gitlab_project.my_group_my_project": "",
 	}
 
 	if len(got) != len(want) {
 		t.Fatalf("got %d resources, want %d", len(got), len(want))
 	}
-	for k := range want {
-		if !got[k] {
-			t.Errorf("missing resource %q", k)
+	for k, id := range want {
+		if got[k] != id {
+			t.Errorf("resource %q has identity %q, want %q", k, got[k], id)
 		}
 	}
 }
@@ -63,15 +65,15 @@ func TestParseExistingResourcesMultipleFiles(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := ParseExistingResources(dir)
+	got, err := ParseExistingResources(dir, &gitlab.Resources{})
 	if err != nil {
 		t.Fatalf("ParseExistingResources error: %v", err)
 	}
 
-	if !got["gitlab_group.alpha"] {
+	if _, ok := got["gitlab_group.alpha"]; !ok {
 		t.Error("missing gitlab_group.alpha")
 	}
-	if !got["gitlab_group_membership.alpha"] {
+	if _, ok := got["gitlab_group_membership.alpha"]; !ok {
 		t.Error("missing gitlab_group_membership.alpha")
 	}
 }
@@ -79,7 +81,7 @@ func TestParseExistingResourcesMultipleFiles(t *testing.T) {
 func TestParseExistingResourcesEmptyDir(t *testing.T) {
 	dir := t.TempDir()
 
-	got, err := ParseExistingResources(dir)
+	got, err := ParseExistingResources(dir, &gitlab.Resources{})
 	if err != nil {
 		t.Fatalf("ParseExistingResources error: %v", err)
 	}
@@ -110,7 +112,7 @@ resource "gitlab_group" "test" {
 		t.Fatal(err)
 	}
 
-	got, err := ParseExistingResources(dir)
+	got, err := ParseExistingResources(dir, &gitlab.Resources{})
 	if err != nil {
 		t.Fatalf("ParseExistingResources error: %v", err)
 	}
@@ -118,7 +120,7 @@ resource "gitlab_group" "test" {
 	if len(got) != 1 {
 		t.Fatalf("expected 1 resource, got %d", len(got))
 	}
-	if !got["gitlab_group.test"] {
+	if _, ok := got["gitlab_group.test"]; !ok {
 		t.Error("missing gitlab_group.test")
 	}
 }
@@ -0,0 +1,97 @@
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func WriteProjectUserMembershipVariable(projects []*gl.Project, projectMembers gitlab.ProjectMembers, w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("variable \"gitlab_project_user_membership\" {\n")
+	b.WriteString("  description = \"Assign gitlab users directly to projects.\"\n")
+	b.WriteString("  default = {\n")
+
+	var expiring []*gl.Project
+	for _, p := range projects {
+		if p == nil {
+			continue
+		}
+		path := projectFullPath(p)
+		members := projectMembers[p.ID]
+		if len(members) == 0 {
+			fmt.Fprintf(&b, "    \"%s\" = {}\n", path)
+			continue
+		}
+		fmt.Fprintf(&b, "    \"%s\" = {\n", path)
+		hasExpiry := false
+		for _, m := range members {
+			fmt.Fprintf(&b, "      \"%s\" = \"%s\"\n", m.Username, accessLevelToString(m.AccessLevel))
+			if m.ExpiresAt != nil {
+				hasExpiry = true
+			}
+		}
+		b.WriteString("    }\n")
+		if hasExpiry {
+			expiring = append(expiring, p)
+		}
+	}
+
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	b.WriteString("\n")
+
+	b.WriteString("variable \"gitlab_project_user_membership_expires_at\" {\n")
+	b.WriteString("  description = \"Expiry dates of direct project memberships.\"\n")
+	b.WriteString("  default = {\n")
+	for _, p := range expiring {
+		fmt.Fprintf(&b, "    \"%s\" = {\n", projectFullPath(p))
+		for _, m := range projectMembers[p.ID] {
+			if m.ExpiresAt != nil {
+				fmt.Fprintf(&b, "      \"%s\" = \"%s\"\n", m.Username, m.ExpiresAt.String())
+			}
+		}
+		b.WriteString("    }\n")
+	}
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+
+	_, err := w.Write(hclwrite.Format([]byte(b.String())))
+	return err
+}
+
+func WriteProjectUserMembershipHelpers(w io.Writer) error {
+	_, err := fmt.Fprint(w, `locals {
+  users_by_projects = toset(distinct(flatten([
+    for key, project in var.gitlab_project_user_membership : [
+      for user, access in project : user
+    ]
+  ])))
+}
+
+data "gitlab_user" "by_projects" {
+  for_each = local.users_by_projects
+  username = each.key
+}
+`)
+	return err
+}
+
+func WriteProjectMembershipResource(project *gl.Project, w io.Writer) error {
+	name := projectResourceName(project)
+	path := projectFullPath(project)
+	_, err := fmt.Fprintf(w, `resource "gitlab_project_membership" "%s" {
+  for_each     = var.gitlab_project_user_membership["%s"]
+  project      = gitlab_project.%s.id
+  user_id      = data.gitlab_user.by_projects[each.key].id
+  access_level = each.value
+  expires_at   = try(var.gitlab_project_user_membership_expires_at["%s"][each.key], null)
+}
+`, name, path, name, path)
+	return err
+}
@@ -0,0 +1,66 @@
+package terraform
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestWriteProjectUserMembershipVariable(t *testing.T) {
+	projects := []*gl.Project{
+		{
+			ID:                1,
+			Path:              "project-a",
+			Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+			PathWithNamespace: "my-group/project-a",
+		},
+		{
+			ID:                2,
+			Path:              "project-b",
+			Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+			PathWithNamespace: "my-group/project-b",
+		},
+	}
+	expiry := gl.ISOTime(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC))
+	members := gitlab.ProjectMembers{
+		1: {
+			{ID: 42, Username: "jdoe", AccessLevel: gl.DeveloperPermissions},
+			{ID: 43, Username: "contractor", AccessLevel: gl.ReporterPermissions, ExpiresAt: &expiry},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectUserMembershipVariable(projects, members, &buf); err != nil {
+		t.Fatalf("WriteProjectUserMembershipVariable error: %v", err)
+	}
+
+	compareGolden(t, "project_user_membership_variable.tf", buf.String())
+}
+
+func TestWriteProjectUserMembershipHelpers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteProjectUserMembershipHelpers(&buf); err != nil {
+		t.Fatalf("WriteProjectUserMembershipHelpers error: %v", err)
+	}
+
+	compareGolden(t, "project_user_membership_helpers.tf", buf.String())
+}
+
+func TestWriteProjectMembershipResource(t *testing.T) {
+	project := &gl.Project{
+		ID:                1,
+		Path:              "project-a",
+		Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+		PathWithNamespace: "my-group/project-a",
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectMembershipResource(project, &buf); err != nil {
+		t.Fatalf("WriteProjectMembershipResource error: %v", err)
+	}
+
+	compareGolden(t, "project_membership_resource.tf", buf.String())
+}
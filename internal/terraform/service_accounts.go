@@ -0,0 +1,141 @@
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func serviceAccountResourceName(g *gl.Group, sa *gl.GroupServiceAccount) string {
+	return normalizeToTerraformName(g.Path) + "_" + normalizeName(sa.UserName)
+}
+
+func serviceAccountMembershipResourceName(g *gl.Group) string {
+	return normalizeToTerraformName(g.Path) + "_service_accounts"
+}
+
+// splitGroupMembers separates group members that are service accounts from
+// human members, so bots are not managed as regular users.
+func splitGroupMembers(members gitlab.GroupMembers, accounts gitlab.GroupServiceAccounts) (humans, bots gitlab.GroupMembers) {
+	if len(accounts) == 0 {
+		return members, nil
+	}
+
+	serviceAccountIDs := make(map[int64]bool)
+	for _, list := range accounts {
+		for _, sa := range list {
+			if sa != nil {
+				serviceAccountIDs[sa.ID] = true
+			}
+		}
+	}
+
+	humans = make(gitlab.GroupMembers, len(members))
+	bots = make(gitlab.GroupMembers)
+	for groupID, list := range members {
+		for _, m := range list {
+			if m == nil {
+				continue
+			}
+			if serviceAccountIDs[m.ID] {
+				bots[groupID] = append(bots[groupID], m)
+			} else {
+				humans[groupID] = append(humans[groupID], m)
+			}
+		}
+	}
+	return humans, bots
+}
+
+// WriteServiceAccounts writes gitlab_group_service_account resources for all
+// top-level groups followed by a local mapping usernames to their user IDs.
+func WriteServiceAccounts(groups []*gl.Group, accounts gitlab.GroupServiceAccounts, w io.Writer) error {
+	f := hclwrite.NewEmptyFile()
+	rootBody := f.Body()
+
+	var ids []hclwrite.ObjectAttrTokens
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, sa := range accounts[g.ID] {
+			name := serviceAccountResourceName(g, sa)
+			block := rootBody.AppendNewBlock("resource", []string{"gitlab_group_service_account", name})
+			body := block.Body()
+
+			body.SetAttributeTraversal("group", hcl.Traversal{
+				hcl.TraverseRoot{Name: "gitlab_group"},
+				hcl.TraverseAttr{Name: normalizeToTerraformName(g.Path)},
+				hcl.TraverseAttr{Name: "id"},
+			})
+			body.SetAttributeValue("name", cty.StringVal(sa.Name))
+			body.SetAttributeValue("username", cty.StringVal(sa.UserName))
+			if sa.Email != "" {
+				body.SetAttributeValue("email", cty.StringVal(sa.Email))
+			}
+			rootBody.AppendNewline()
+
+			ids = append(ids, hclwrite.ObjectAttrTokens{
+				Name: hclwrite.TokensForValue(cty.StringVal(sa.UserName)),
+				Value: hclwrite.TokensForTraversal(hcl.Traversal{
+					hcl.TraverseRoot{Name: "gitlab_group_service_account"},
+					hcl.TraverseAttr{Name: name},
+					hcl.TraverseAttr{Name: "service_account_id"},
+				}),
+			})
+		}
+	}
+
+	locals := rootBody.AppendNewBlock("locals", nil).Body()
+	locals.SetAttributeRaw("service_account_ids", hclwrite.TokensForObject(ids))
+
+	_, err := w.Write(f.Bytes())
+	return err
+}
+
+func WriteServiceAccountMembershipVariable(groups []*gl.Group, bots gitlab.GroupMembers, w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("variable \"gitlab_group_service_account_membership\" {\n")
+	b.WriteString("  description = \"Assign gitlab service accounts to groups.\"\n")
+	b.WriteString("  default = {\n")
+
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		members := bots[g.ID]
+		if len(members) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "    \"%s\" = {\n", g.FullPath)
+		for _, m := range members {
+			fmt.Fprintf(&b, "      \"%s\" = \"%s\"\n", m.Username, accessLevelToString(m.AccessLevel))
+		}
+		b.WriteString("    }\n")
+	}
+
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+
+	_, err := w.Write(hclwrite.Format([]byte(b.String())))
+	return err
+}
+
+func WriteServiceAccountMembershipResource(group *gl.Group, w io.Writer) error {
+	name := normalizeToTerraformName(group.Path)
+	_, err := fmt.Fprintf(w, `resource "gitlab_group_membership" "%s" {
+  for_each     = var.gitlab_group_service_account_membership["%s"]
+  group_id     = gitlab_group.%s.id
+  user_id      = local.service_account_ids[each.key]
+  access_level = each.value
+}
+`, serviceAccountMembershipResourceName(group), group.FullPath, name)
+	return err
+}
@@ -0,0 +1,98 @@
+package terraform
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestSplitGroupMembers(t *testing.T) {
+	members := gitlab.GroupMembers{
+		10: {
+			{ID: 1, Username: "alice"},
+			{ID: 2, Username: "service_account_group_10_ci"},
+		},
+		20: {
+			{ID: 2, Username: "service_account_group_10_ci"},
+		},
+	}
+	accounts := gitlab.GroupServiceAccounts{
+		10: {{ID: 2, UserName: "service_account_group_10_ci"}},
+	}
+
+	humans, bots := splitGroupMembers(members, accounts)
+
+	if len(humans[10]) != 1 || humans[10][0].Username != "alice" {
+		t.Errorf("humans[10] = %v, want [alice]", humans[10])
+	}
+	if len(humans[20]) != 0 {
+		t.Errorf("humans[20] = %v, want none", humans[20])
+	}
+	if len(bots[10]) != 1 || len(bots[20]) != 1 {
+		t.Errorf("bots = %v, want one service account in each group", bots)
+	}
+}
+
+func TestSplitGroupMembersWithoutServiceAccounts(t *testing.T) {
+	members := gitlab.GroupMembers{
+		10: {{ID: 1, Username: "alice"}},
+	}
+
+	humans, bots := splitGroupMembers(members, nil)
+
+	if len(humans[10]) != 1 {
+		t.Errorf("humans[10] = %v, want [alice]", humans[10])
+	}
+	if bots != nil {
+		t.Errorf("bots = %v, want nil", bots)
+	}
+}
+
+func TestWriteServiceAccounts(t *testing.T) {
+	groups := []*gl.Group{
+		{ID: 10, Path: "my-group", FullPath: "my-group"},
+	}
+	accounts := gitlab.GroupServiceAccounts{
+		10: {
+			{ID: 2, Name: "CI Bot", UserName: "service_account_group_10_ci", Email: "ci@example.com"},
+			{ID: 3, Name: "Renovate", UserName: "service_account_group_10_renovate"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteServiceAccounts(groups, accounts, &buf); err != nil {
+		t.Fatalf("WriteServiceAccounts error: %v", err)
+	}
+
+	compareGolden(t, "service_accounts.tf", buf.String())
+}
+
+func TestWriteServiceAccountMembershipVariable(t *testing.T) {
+	groups := []*gl.Group{
+		{ID: 10, Path: "my-group", FullPath: "my-group"},
+		{ID: 20, Path: "sub-group", FullPath: "my-group/sub-group"},
+	}
+	bots := gitlab.GroupMembers{
+		20: {{ID: 2, Username: "service_account_group_10_ci", AccessLevel: gl.MaintainerPermissions}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteServiceAccountMembershipVariable(groups, bots, &buf); err != nil {
+		t.Fatalf("WriteServiceAccountMembershipVariable error: %v", err)
+	}
+
+	compareGolden(t, "service_account_membership_variable.tf", buf.String())
+}
+
+func TestWriteServiceAccountMembershipResource(t *testing.T) {
+	group := &gl.Group{ID: 20, Path: "sub-group", FullPath: "my-group/sub-group"}
+
+	var buf bytes.Buffer
+	if err := WriteServiceAccountMembershipResource(group, &buf); err != nil {
+		t.Fatalf("WriteServiceAccountMembershipResource error: %v", err)
+	}
+
+	compareGolden(t, "service_account_membership_resource.tf", buf.String())
+}
@@ -0,0 +1,216 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
+)
+
+// sharedFiles are the files WriteAll writes for all namespaces, plus the
+// import and removed blocks written by scan. They are never stale.
+var sharedFiles = []string{
+	"group_membership.tf", "project_membership.tf", "group_share_group.tf", "service_accounts.tf",
+	"group_labels.tf", "project_labels.tf", "group_variables.tf", "project_variables.tf",
+	"pipeline_schedules.tf", "hooks.tf", "branch_protection.tf", "approval_rules.tf", "mr_approvals.tf",
+	"imports.tf", "removed.tf",
+}
+
+// namespaceResourceTypes are the resource types WriteAll writes to the file
+// of a namespace.
+var namespaceResourceTypes = []string{
+	"gitlab_group", "gitlab_group_membership", "gitlab_group_share_group", "gitlab_group_label", "gitlab_group_variable",
+	"gitlab_project", "gitlab_project_share_group", "gitlab_project_membership", "gitlab_project_label", "gitlab_project_variable",
+}
+
+// namespaceFileName matches the names NamespaceFileName returns.
+var namespaceFileName = regexp.MustCompile(`^[a-z0-9_]+\.tf$`)
+
+// StaleFile is an existing namespace file nothing is generated into anymore,
+// because its namespace was deleted, renamed or transferred.
+type StaleFile struct {
+	Name string
+	// MovedTo is the new generated file declaring the same group, e.g.
+	// after the main group changed; empty if the group is gone from its
+	// full path.
+	MovedTo string
+}
+
+// FindStaleFiles returns the files in existingDir that were generated for a
+// group that generatedDir no longer has a file for. Only files named after
+// their group that declare nothing but the resources WriteAll writes for it
+// are returned, so hand-written files are never deleted or moved. Files of
+// groups whose full path cannot be resolved, or that filter leaves out of
+// the scan, are not returned either, as their group was not fetched.
+func FindStaleFiles(resources *gitlab.Resources, existingDir, generatedDir string, filter ignore.Filter) ([]StaleFile, error) {
+	existing, err := filepath.Glob(filepath.Join(existingDir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("listing existing files: %w", err)
+	}
+	generated, err := filepath.Glob(filepath.Join(generatedDir, "*.tf"))
+	if err != nil {
+		return nil, fmt.Errorf("listing generated files: %w", err)
+	}
+
+	generatedNames := make(map[string]bool, len(generated))
+	for _, path := range generated {
+		generatedNames[filepath.Base(path)] = true
+	}
+	existingNames := make(map[string]bool, len(existing))
+	for _, path := range existing {
+		existingNames[filepath.Base(path)] = true
+	}
+
+	existingGroups, err := groupFullPaths(resources, existingDir)
+	if err != nil {
+		return nil, err
+	}
+	generatedGroups, err := groupFullPaths(resources, generatedDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []StaleFile
+	staleByPath := make(map[string][]int)
+	for _, path := range existing {
+		name := filepath.Base(path)
+		if generatedNames[name] || !namespaceFileName.MatchString(name) || slices.Contains(sharedFiles, name) {
+			continue
+		}
+		group, ok, err := namespaceFileGroup(path)
+		if err != nil {
+			return nil, err
+		}
+		fullPath := existingGroups[group]
+		if !ok || fullPath == "" || filter.Ignored("gitlab_group", fullPath) {
+			continue
+		}
+		staleByPath[fullPath] = append(staleByPath[fullPath], len(stale))
+		stale = append(stale, StaleFile{Name: name})
+	}
+
+	// A stale file moved if exactly one new file declares the same group,
+	// e.g. after the main group changed.
+	newByPath := make(map[string][]string)
+	for _, path := range generated {
+		name := filepath.Base(path)
+		if existingNames[name] || slices.Contains(sharedFiles, name) {
+			continue
+		}
+		group, ok, err := namespaceFileGroup(path)
+		if err != nil {
+			return nil, err
+		}
+		if fullPath := generatedGroups[group]; ok && fullPath != "" {
+			newByPath[fullPath] = append(newByPath[fullPath], name)
+		}
+	}
+	for fullPath, indexes := range staleByPath {
+		if len(indexes) == 1 && len(newByPath[fullPath]) == 1 {
+			stale[indexes[0]].MovedTo = newByPath[fullPath][0]
+		}
+	}
+
+	slices.SortFunc(stale, func(a, b StaleFile) int { return strings.Compare(a.Name, b.Name) })
+	return stale, nil
+}
+
+// groupFullPaths maps the gitlab_group resources declared in dir to their
+// full path, following parent_id references. Groups whose full path cannot
+// be determined statically, e.g. below a parent_id of a group that was not
+// fetched, are left out.
+func groupFullPaths(resources *gitlab.Resources, dir string) (map[string]string, error) {
+	blocks, err := parseResourceBlocks(dir)
+	if err != nil {
+		return nil, err
+	}
+	groupPaths, projectPaths := fullPaths(resources)
+	ids := newIdentityResolver(blocks, groupPaths, projectPaths)
+
+	// Paths are resolved up to a declared top-level group or a fetched one.
+	known := make(map[string]bool)
+	for _, p := range groupPaths {
+		known[p] = true
+	}
+	for addr, block := range blocks {
+		if _, ok := block.attrs["parent_id"]; block.resourceType == "gitlab_group" && !ok {
+			known[strings.TrimPrefix(ids.identity(addr), "gitlab_group:")] = true
+		}
+	}
+
+	paths := make(map[string]string)
+	for addr, block := range blocks {
+		if block.resourceType != "gitlab_group" {
+			continue
+		}
+		p, ok := strings.CutPrefix(ids.identity(addr), "gitlab_group:")
+		if !ok {
+			continue
+		}
+		for ancestor := p; ancestor != ""; ancestor = path.Dir(ancestor) {
+			if known[ancestor] {
+				paths[addr] = p
+				break
+			}
+			if !strings.Contains(ancestor, "/") {
+				break
+			}
+		}
+	}
+	return paths, nil
+}
+
+// namespaceFileGroup returns the address of the group a namespace file was
+// generated for. ok is false unless the file declares exactly one group,
+// named and filed as WriteAll does, and nothing else than the resources
+// WriteAll writes next to it.
+func namespaceFileGroup(path string) (group string, ok bool, err error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	f, diags := hclsyntax.ParseConfig(src, path, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		// Not ours to judge, let terraform complain about it.
+		return "", false, nil
+	}
+
+	var groups []string
+	for _, block := range f.Body.(*hclsyntax.Body).Blocks {
+		if block.Type != "resource" || len(block.Labels) != 2 || !slices.Contains(namespaceResourceTypes, block.Labels[0]) {
+			return "", false, nil
+		}
+		if block.Labels[0] != "gitlab_group" {
+			continue
+		}
+		attr, ok := block.Body.Attributes["path"]
+		if !ok {
+			return "", false, nil
+		}
+		v, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || !v.Type().Equals(cty.String) || v.IsNull() {
+			return "", false, nil
+		}
+		name := normalizeToTerraformName(v.AsString())
+		file := filepath.Base(path)
+		if block.Labels[1] != name || (file != name+".tf" && !strings.HasSuffix(file, "_"+name+".tf")) {
+			return "", false, nil
+		}
+		groups = append(groups, "gitlab_group."+name)
+	}
+
+	if len(groups) != 1 {
+		return "", false, nil
+	}
+	return groups[0], true, nil
+}
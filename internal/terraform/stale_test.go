@@ -0,0 +1,158 @@
+package terraform
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/ignore"
+)
+
+func TestFindStaleFiles(t *testing.T) {
+	existingDir := t.TempDir()
+	generatedDir := t.TempDir()
+
+	writeTestFiles(t, existingDir, map[string]string{
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+`,
+		"team_a.tf": `resource "gitlab_group" "team_a" {
+  name      = "Team A"
+  path      = "team-a"
+  parent_id = gitlab_group.my_group.id
+}
+`,
+		// Deleted subgroup
+		"old_team.tf": `resource "gitlab_group" "old_team" {
+  name      = "Old Team"
+  path      = "old-team"
+  parent_id = gitlab_group.my_group.id
+}
+
+resource "gitlab_group_membership" "old_team" {
+  for_each     = var.gitlab_group_membership["my-group/old-team"]
+  group_id     = gitlab_group.old_team.id
+  user_id      = data.gitlab_user.main[each.key].id
+  access_level = each.value
+}
+
+resource "gitlab_project" "api" {
+  name         = "api"
+  path         = "api"
+  namespace_id = gitlab_group.old_team.id
+}
+`,
+		// Transferred from team-a to team-b: a different group path now
+		"team_a_backend.tf": `resource "gitlab_group" "backend" {
+  name      = "Backend"
+  path      = "backend"
+  parent_id = gitlab_group.team_a.id
+}
+`,
+		// Same group, written to a file of another name before
+		"old_name_infra.tf": `resource "gitlab_group" "infra" {
+  name      = "Infra"
+  path      = "infra"
+  parent_id = gitlab_group.my_group.id
+}
+`,
+		// Excluded from the scan, so not generated
+		"sandbox.tf": `resource "gitlab_group" "sandbox" {
+  name      = "Sandbox"
+  path      = "sandbox"
+  parent_id = gitlab_group.my_group.id
+}
+`,
+		// Parent group unknown
+		"lost.tf": `resource "gitlab_group" "lost" {
+  name      = "Lost"
+  path      = "lost"
+  parent_id = 99
+}
+`,
+		// Hand-written: other blocks, or named unlike its group
+		"providers.tf": `provider "gitlab" {}
+`,
+		"teams.tf": `resource "gitlab_group" "team_c" {
+  name = "Team C"
+  path = "team-c"
+}
+`,
+		"extra.tf": `resource "gitlab_group" "extra" {
+  name = "Extra"
+  path = "extra"
+}
+
+locals {
+  owners = []
+}
+`,
+		// Shared files are never stale
+		"hooks.tf": `resource "gitlab_project_hook" "api" {}
+`,
+	})
+	writeTestFiles(t, generatedDir, map[string]string{
+		"my_group.tf": `resource "gitlab_group" "my_group" {
+  name = "My Group"
+  path = "my-group"
+}
+`,
+		"team_a.tf": `resource "gitlab_group" "team_a" {
+  name      = "Team A"
+  path      = "team-a"
+  parent_id = gitlab_group.my_group.id
+}
+`,
+		"team_b_backend.tf": `resource "gitlab_group" "backend" {
+  name      = "Backend"
+  path      = "backend"
+  parent_id = gitlab_group.team_b.id
+}
+`,
+		"team_b.tf": `resource "gitlab_group" "team_b" {
+  name      = "Team B"
+  path      = "team-b"
+  parent_id = gitlab_group.my_group.id
+}
+`,
+		"infra.tf": `resource "gitlab_group" "infra" {
+  name      = "Infra"
+  path      = "infra"
+  parent_id = gitlab_group.my_group.id
+}
+`,
+		"team_c.tf": `resource "gitlab_group" "team_c" {
+  name = "Team C"
+  path = "team-c"
+}
+`,
+	})
+
+	filter := ignore.Filter{Exclude: []string{"my-group/sandbox"}}
+	got, err := FindStaleFiles(&gitlab.Resources{}, existingDir, generatedDir, filter)
+	if err != nil {
+		t.Fatalf("FindStaleFiles() error: %v", err)
+	}
+	want := []StaleFile{
+		{Name: "old_name_infra.tf", MovedTo: "infra.tf"},
+		{Name: "old_team.tf"},
+		{Name: "team_a_backend.tf"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindStaleFiles() = %+v, want %+v", got, want)
+	}
+
+	// Groups above the included ones are not fetched, so their files are
+	// not stale either.
+	filter = ignore.Filter{Include: []string{"my-group/team-a"}}
+	got, err = FindStaleFiles(&gitlab.Resources{}, existingDir, generatedDir, filter)
+	if err != nil {
+		t.Fatalf("FindStaleFiles() error: %v", err)
+	}
+	want = []StaleFile{{Name: "team_a_backend.tf"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindStaleFiles() with include = %+v, want %+v", got, want)
+	}
+}
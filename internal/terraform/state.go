@@ -0,0 +1,221 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+)
+
+// State holds the IDs of the gitlab_* resources recorded in terraform state.
+type State struct {
+	ids map[string]bool
+	// instances keeps the attributes of the resources whose state id can
+	// differ from the import ID.
+	instances []stateInstance
+}
+
+type stateInstance struct {
+	resourceType string
+	attrs        map[string]any
+}
+
+// stateIDSpec tells how the import ID of a resource type is rebuilt from its
+// state attributes: the group or project attribute, which may hold a full
+// path instead of an ID, followed by the object's own attributes.
+type stateIDSpec struct {
+	owner string
+	kind  refKind
+	parts []string
+}
+
+// stateIDSpecs covers the types whose state id is not the import ID
+// GenerateImportCommands writes, e.g. labels, which are identified by name in
+// state, or any type whose group or project argument is a full path.
+var stateIDSpecs = map[string]stateIDSpec{
+	"gitlab_group_membership":           {"group_id", groupRef, []string{"user_id"}},
+	"gitlab_group_share_group":          {"group_id", groupRef, []string{"share_group_id"}},
+	"gitlab_group_service_account":      {"group", groupRef, []string{"service_account_id"}},
+	"gitlab_group_label":                {"group", groupRef, []string{"label_id"}},
+	"gitlab_group_variable":             {"group", groupRef, []string{"key", "environment_scope"}},
+	"gitlab_group_hook":                 {"group", groupRef, []string{"hook_id"}},
+	"gitlab_project_share_group":        {"project", projectRef, []string{"group_id"}},
+	"gitlab_project_membership":         {"project", projectRef, []string{"user_id"}},
+	"gitlab_project_label":              {"project", projectRef, []string{"label_id"}},
+	"gitlab_project_variable":           {"project", projectRef, []string{"key", "environment_scope"}},
+	"gitlab_project_hook":               {"project", projectRef, []string{"hook_id"}},
+	"gitlab_pipeline_schedule":          {"project", projectRef, []string{"pipeline_schedule_id"}},
+	"gitlab_pipeline_schedule_variable": {"project", projectRef, []string{"pipeline_schedule_id", "key"}},
+	"gitlab_branch_protection":          {"project", projectRef, []string{"branch"}},
+	"gitlab_tag_protection":             {"project", projectRef, []string{"tag"}},
+	"gitlab_project_approval_rule":      {"project", projectRef, []string{"rule_id"}},
+	"gitlab_project_level_mr_approvals": {"project", projectRef, nil},
+}
+
+// stateFile covers both a raw terraform.tfstate file and the output of
+// `terraform show -json`.
+type stateFile struct {
+	Resources []struct {
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Instances []struct {
+			Attributes map[string]any `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+	Values *struct {
+		RootModule stateModule `json:"root_module"`
+	} `json:"values"`
+}
+
+type stateModule struct {
+	Resources []struct {
+		Mode   string         `json:"mode"`
+		Type   string         `json:"type"`
+		Values map[string]any `json:"values"`
+	} `json:"resources"`
+	ChildModules []stateModule `json:"child_modules"`
+}
+
+// LoadState reads a terraform.tfstate file or `terraform show -json` output.
+func LoadState(r io.Reader) (*State, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var f stateFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("decoding state: %w", err)
+	}
+
+	s := &State{ids: make(map[string]bool)}
+	for _, res := range f.Resources {
+		if res.Mode != "managed" {
+			continue
+		}
+		for _, inst := range res.Instances {
+			s.add(res.Type, inst.Attributes)
+		}
+	}
+	if f.Values != nil {
+		s.addModule(f.Values.RootModule)
+	}
+	return s, nil
+}
+
+func (s *State) addModule(m stateModule) {
+	for _, res := range m.Resources {
+		if res.Mode == "managed" {
+			s.add(res.Type, res.Values)
+		}
+	}
+	for _, child := range m.ChildModules {
+		s.addModule(child)
+	}
+}
+
+func (s *State) add(resourceType string, attrs map[string]any) {
+	if !strings.HasPrefix(resourceType, "gitlab_") {
+		return
+	}
+	if _, ok := stateIDSpecs[resourceType]; ok {
+		s.instances = append(s.instances, stateInstance{resourceType: resourceType, attrs: attrs})
+	}
+	if id, ok := stateValue(attrs["id"]); ok {
+		s.ids[resourceType+"/"+id] = true
+	}
+}
+
+// stateValue returns a string, number or bool attribute as string.
+func stateValue(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// importID rebuilds the import ID of inst, resolving full paths with the
+// IDs of the fetched groups and projects. ok is false if an attribute is
+// missing or a path is unknown.
+func (inst stateInstance) importID(groupIDs, projectIDs map[string]int64) (string, bool) {
+	spec := stateIDSpecs[inst.resourceType]
+	owner, ok := stateValue(inst.attrs[spec.owner])
+	if !ok || owner == "" {
+		return "", false
+	}
+	if _, err := strconv.ParseInt(owner, 10, 64); err != nil {
+		ids := groupIDs
+		if spec.kind == projectRef {
+			ids = projectIDs
+		}
+		id, ok := ids[owner]
+		if !ok {
+			return "", false
+		}
+		owner = strconv.FormatInt(id, 10)
+	}
+
+	parts := []string{owner}
+	for _, name := range spec.parts {
+		v, ok := stateValue(inst.attrs[name])
+		if !ok {
+			return "", false
+		}
+		if name == "environment_scope" && v == "" {
+			v = "*"
+		}
+		parts = append(parts, v)
+	}
+	return strings.Join(parts, ":"), true
+}
+
+// Has reports whether a resource of the given type and ID is in state.
+func (s *State) Has(resourceType, id string) bool {
+	return s.ids[resourceType+"/"+id]
+}
+
+// Len returns the number of gitlab_* resource instances in state.
+func (s *State) Len() int {
+	return len(s.ids)
+}
+
+// FilterImportCommands drops commands for objects that are already in state.
+// Objects are matched by state id, or by the import ID rebuilt from their
+// state attributes where the provider records another id, with the group
+// and project paths of resources resolved to their IDs.
+func (s *State) FilterImportCommands(cmds []ImportCommand, resources *gitlab.Resources) []ImportCommand {
+	groupIDs := make(map[string]int64)
+	for _, g := range resources.Groups {
+		if g != nil {
+			groupIDs[g.FullPath] = g.ID
+		}
+	}
+	projectIDs := make(map[string]int64)
+	for _, p := range resources.Projects {
+		if p != nil {
+			projectIDs[projectFullPath(p)] = p.ID
+		}
+	}
+	imported := make(map[string]bool, len(s.instances))
+	for _, inst := range s.instances {
+		if id, ok := inst.importID(groupIDs, projectIDs); ok {
+			imported[inst.resourceType+"/"+id] = true
+		}
+	}
+
+	var result []ImportCommand
+	for _, cmd := range cmds {
+		resourceType, _, _ := strings.Cut(cmd.Address, ".")
+		if !s.Has(resourceType, cmd.ID) && !imported[resourceType+"/"+cmd.ID] {
+			result = append(result, cmd)
+		}
+	}
+	return result
+}
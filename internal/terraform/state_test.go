@@ -0,0 +1,200 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestLoadStateRawFile(t *testing.T) {
+	state, err := LoadState(strings.NewReader(`{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "gitlab_project",
+      "name": "api",
+      "instances": [{"attributes": {"id": "42", "path": "api"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "gitlab_group_membership",
+      "name": "my_group",
+      "instances": [
+        {"index_key": "alice", "attributes": {"id": "1:100"}},
+        {"index_key": "bob", "attributes": {"id": "1:101"}}
+      ]
+    },
+    {
+      "mode": "data",
+      "type": "gitlab_group",
+      "name": "lookup",
+      "instances": [{"attributes": {"id": "7"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "other",
+      "instances": [{"attributes": {"id": "9"}}]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("LoadState error: %v", err)
+	}
+
+	if state.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", state.Len())
+	}
+	for _, tt := range []struct {
+		resourceType, id string
+		want             bool
+	}{
+		{"gitlab_project", "42", true},
+		{"gitlab_group_membership", "1:100", true},
+		{"gitlab_group_membership", "1:101", true},
+		{"gitlab_group", "7", false},
+		{"null_resource", "9", false},
+		{"gitlab_project", "43", false},
+	} {
+		if got := state.Has(tt.resourceType, tt.id); got != tt.want {
+			t.Errorf("Has(%q, %q) = %v, want %v", tt.resourceType, tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestLoadStateShowJSON(t *testing.T) {
+	state, err := LoadState(strings.NewReader(`{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {"address": "gitlab_group.my_group", "mode": "managed", "type": "gitlab_group", "values": {"id": 1}}
+      ],
+      "child_modules": [
+        {
+          "resources": [
+            {"address": "module.x.gitlab_project_hook.ci", "mode": "managed", "type": "gitlab_project_hook", "values": {"id": "42:5"}}
+          ]
+        }
+      ]
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatalf("LoadState error: %v", err)
+	}
+
+	if !state.Has("gitlab_group", "1") {
+		t.Error("expected numeric id to be loaded")
+	}
+	if !state.Has("gitlab_project_hook", "42:5") {
+		t.Error("expected resource from child module to be loaded")
+	}
+}
+
+func TestLoadStateInvalid(t *testing.T) {
+	if _, err := LoadState(strings.NewReader("not json")); err == nil {
+		t.Fatal("expected error for invalid state")
+	}
+}
+
+func TestStateFilterImportCommands(t *testing.T) {
+	state, err := LoadState(strings.NewReader(`{"resources": [
+  {"mode": "managed", "type": "gitlab_project", "instances": [{"attributes": {"id": "42"}}]},
+  {"mode": "managed", "type": "gitlab_group_membership", "instances": [{"attributes": {"id": "1:100"}}]}
+]}`))
+	if err != nil {
+		t.Fatalf("LoadState error: %v", err)
+	}
+
+	cmds := []ImportCommand{
+		{Address: "gitlab_project.backend_api", ID: "42"},
+		{Address: "gitlab_project.backend_web", ID: "43"},
+		{Address: `gitlab_group_membership.my_group["alice"]`, ID: "1:100"},
+		{Address: `gitlab_group_membership.my_group["bob"]`, ID: "1:101"},
+	}
+
+	got := state.FilterImportCommands(cmds, &gitlab.Resources{})
+	want := []ImportCommand{
+		{Address: "gitlab_project.backend_web", ID: "43"},
+		{Address: `gitlab_group_membership.my_group["bob"]`, ID: "1:101"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d commands, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cmds[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStateFilterImportCommandsByAttributes(t *testing.T) {
+	// Attributes as recorded by the gitlabhq/gitlab provider, with group and
+	// project arguments written as full paths.
+	state, err := LoadState(strings.NewReader(`{"resources": [
+  {"mode": "managed", "type": "gitlab_project_label", "instances": [
+    {"index_key": "bug", "attributes": {"id": "bug", "project": "my-group/api", "name": "bug", "label_id": 7, "color": "#ff0000"}}
+  ]},
+  {"mode": "managed", "type": "gitlab_group_label", "instances": [
+    {"index_key": "team", "attributes": {"id": "my-group:team", "group": "my-group", "name": "team", "label_id": 8}}
+  ]},
+  {"mode": "managed", "type": "gitlab_project_variable", "instances": [
+    {"index_key": "API_URL:*", "attributes": {"id": "my-group/api:API_URL:*", "project": "my-group/api", "key": "API_URL", "environment_scope": "*"}}
+  ]},
+  {"mode": "managed", "type": "gitlab_group_membership", "instances": [
+    {"index_key": "alice", "attributes": {"id": "my-group:100", "group_id": "my-group", "user_id": 100, "access_level": "developer"}}
+  ]},
+  {"mode": "managed", "type": "gitlab_project_membership", "instances": [
+    {"index_key": "bob", "attributes": {"id": "42:101", "project": "42", "user_id": 101}}
+  ]},
+  {"mode": "managed", "type": "gitlab_branch_protection", "instances": [
+    {"attributes": {"id": "my-group/api:main", "project": "my-group/api", "branch": "main", "branch_protection_id": 3}}
+  ]},
+  {"mode": "managed", "type": "gitlab_project_hook", "instances": [
+    {"attributes": {"id": "my-group/unknown:5", "project": "my-group/unknown", "hook_id": 5}}
+  ]}
+]}`))
+	if err != nil {
+		t.Fatalf("LoadState error: %v", err)
+	}
+
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{{ID: 1, Path: "my-group", FullPath: "my-group"}},
+		Projects: []*gl.Project{{
+			ID:                42,
+			Path:              "api",
+			PathWithNamespace: "my-group/api",
+			Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+		}},
+	}
+	cmds := []ImportCommand{
+		{Address: `gitlab_project_label.my_group_api["bug"]`, ID: "42:7"},
+		{Address: `gitlab_project_label.my_group_api["feature"]`, ID: "42:9"},
+		{Address: `gitlab_group_label.my_group["team"]`, ID: "1:8"},
+		{Address: `gitlab_project_variable.my_group_api["API_URL:*"]`, ID: "42:API_URL:*"},
+		{Address: `gitlab_project_variable.my_group_api["API_URL:prod"]`, ID: "42:API_URL:prod"},
+		{Address: `gitlab_group_membership.my_group["alice"]`, ID: "1:100"},
+		{Address: `gitlab_project_membership.my_group_api["bob"]`, ID: "42:101"},
+		{Address: "gitlab_branch_protection.my_group_api_main", ID: "42:main"},
+		{Address: "gitlab_project_hook.my_group_api_0", ID: "42:5"},
+	}
+
+	got := state.FilterImportCommands(cmds, resources)
+	want := []ImportCommand{
+		{Address: `gitlab_project_label.my_group_api["feature"]`, ID: "42:9"},
+		{Address: `gitlab_project_variable.my_group_api["API_URL:prod"]`, ID: "42:API_URL:prod"},
+		{Address: "gitlab_project_hook.my_group_api_0", ID: "42:5"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d commands, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cmds[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,9 @@
+data "gitlab_user" "approvers" {
+  for_each = toset(["alice", "bob"])
+  username = each.key
+}
+
+data "gitlab_group" "approvers" {
+  for_each  = toset(["my-group/reviewers"])
+  full_path = each.key
+}
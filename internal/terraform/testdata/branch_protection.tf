@@ -0,0 +1,32 @@
+resource "gitlab_branch_protection" "my_group_my_project_main" {
+  project                      = gitlab_project.my_group_my_project.id
+  branch                       = "main"
+  push_access_level            = "no one"
+  merge_access_level           = "developer"
+  unprotect_access_level       = "maintainer"
+  code_owner_approval_required = true
+
+  allowed_to_push {
+    user_id = 42
+  }
+
+  allowed_to_push {
+    group_id = gitlab_group.my_group.id
+  }
+
+  allowed_to_unprotect {
+    group_id = 99
+  }
+}
+
+resource "gitlab_branch_protection" "my_group_my_project_release_wildcard" {
+  project            = gitlab_project.my_group_my_project.id
+  branch             = "release/*"
+  push_access_level  = "maintainer"
+  merge_access_level = "maintainer"
+  allow_force_push   = true
+
+  allowed_to_push {
+    deploy_key_id = 7
+  }
+}
@@ -0,0 +1,12 @@
+locals {
+  groups_by_groups = toset(distinct(flatten([
+    for key, group in var.gitlab_group_share_group : [
+      for share, access in group : share
+    ]
+  ])))
+}
+
+data "gitlab_group" "by_groups" {
+  for_each  = local.groups_by_groups
+  full_path = each.key
+}
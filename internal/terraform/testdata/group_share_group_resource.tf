@@ -0,0 +1,7 @@
+resource "gitlab_group_share_group" "my_group" {
+  for_each       = var.gitlab_group_share_group["my-group"]
+  group_id       = gitlab_group.my_group.id
+  share_group_id = data.gitlab_group.by_groups[each.key].id
+  group_access   = each.value
+  expires_at     = try(var.gitlab_group_share_group_expires_at["my-group"][each.key], null)
+}
@@ -0,0 +1,19 @@
+variable "gitlab_group_share_group" {
+  description = "Share groups with other groups."
+  default = {
+    "my-group" = {
+      "other-group"       = "developer"
+      "partners/auditors" = "reporter"
+    }
+    "my-group/sub-group" = {}
+  }
+}
+
+variable "gitlab_group_share_group_expires_at" {
+  description = "Expiry dates of group shares."
+  default = {
+    "my-group" = {
+      "partners/auditors" = "2026-12-31"
+    }
+  }
+}
@@ -0,0 +1,12 @@
+resource "gitlab_group_variable" "my_group" {
+  for_each          = var.gitlab_group_variable["my-group"]
+  group             = gitlab_group.my_group.id
+  key               = each.value.key
+  value             = each.value.value != null ? each.value.value : var.gitlab_group_variable_secrets["my-group"][each.key]
+  variable_type     = each.value.variable_type
+  protected         = each.value.protected
+  masked            = each.value.masked
+  raw               = each.value.raw
+  environment_scope = each.value.environment_scope
+  description       = each.value.description
+}
@@ -0,0 +1,34 @@
+variable "gitlab_group_variable" {
+  description = "CI/CD variables for gitlab groups."
+  default = {
+    "my-group" = {
+      "API_URL:*" = {
+        key               = "API_URL"
+        value             = "https://api.example.com"
+        variable_type     = "env_var"
+        protected         = false
+        masked            = false
+        raw               = false
+        environment_scope = "*"
+        description       = ""
+      }
+      "DEPLOY_TOKEN:production" = {
+        key               = "DEPLOY_TOKEN"
+        value             = null
+        variable_type     = "env_var"
+        protected         = false
+        masked            = true
+        raw               = false
+        environment_scope = "production"
+        description       = "Deploy token"
+      }
+    }
+  }
+}
+
+variable "gitlab_group_variable_secrets" {
+  description = "Values of masked or protected gitlab group variables, keyed like gitlab_group_variable."
+  type        = map(map(string))
+  sensitive   = true
+  default     = {}
+}
@@ -0,0 +1,14 @@
+import {
+  to = gitlab_project.parent_my_project
+  id = "1"
+}
+
+import {
+  to = gitlab_group_membership.parent["alice"]
+  id = "1:100"
+}
+
+import {
+  to = gitlab_project_variable.parent_my_project["TOKEN:*"]
+  id = "1:TOKEN:*"
+}
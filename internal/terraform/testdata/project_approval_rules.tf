@@ -0,0 +1,16 @@
+resource "gitlab_project_approval_rule" "my_group_my_project_security" {
+  project              = gitlab_project.my_group_my_project.id
+  name                 = "Security"
+  approvals_required   = 2
+  user_ids             = [data.gitlab_user.approvers["alice"].id]
+  group_ids            = [data.gitlab_group.approvers["my-group/reviewers"].id]
+  protected_branch_ids = [gitlab_branch_protection.my_group_my_project_main.branch_protection_id]
+}
+
+resource "gitlab_project_approval_rule" "my_group_my_project_all_members" {
+  project                           = gitlab_project.my_group_my_project.id
+  name                              = "All Members"
+  approvals_required                = 1
+  rule_type                         = "any_approver"
+  applies_to_all_protected_branches = true
+}
@@ -0,0 +1,7 @@
+resource "gitlab_project_membership" "my_group_project_a" {
+  for_each     = var.gitlab_project_user_membership["my-group/project-a"]
+  project      = gitlab_project.my_group_project_a.id
+  user_id      = data.gitlab_user.by_projects[each.key].id
+  access_level = each.value
+  expires_at   = try(var.gitlab_project_user_membership_expires_at["my-group/project-a"][each.key], null)
+}
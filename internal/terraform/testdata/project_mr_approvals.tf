@@ -0,0 +1,9 @@
+resource "gitlab_project_level_mr_approvals" "my_group_my_project" {
+  project                                        = gitlab_project.my_group_my_project.id
+  reset_approvals_on_push                        = true
+  disable_overriding_approvers_per_merge_request = false
+  merge_requests_author_approval                 = false
+  merge_requests_disable_committers_approval     = true
+  require_password_to_approve                    = false
+  selective_code_owner_removals                  = false
+}
@@ -0,0 +1,12 @@
+locals {
+  users_by_projects = toset(distinct(flatten([
+    for key, project in var.gitlab_project_user_membership : [
+      for user, access in project : user
+    ]
+  ])))
+}
+
+data "gitlab_user" "by_projects" {
+  for_each = local.users_by_projects
+  username = each.key
+}
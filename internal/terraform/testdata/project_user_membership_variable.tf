@@ -0,0 +1,19 @@
+variable "gitlab_project_user_membership" {
+  description = "Assign gitlab users directly to projects."
+  default = {
+    "my-group/project-a" = {
+      "jdoe"       = "developer"
+      "contractor" = "reporter"
+    }
+    "my-group/project-b" = {}
+  }
+}
+
+variable "gitlab_project_user_membership_expires_at" {
+  description = "Expiry dates of direct project memberships."
+  default = {
+    "my-group/project-a" = {
+      "contractor" = "2026-12-31"
+    }
+  }
+}
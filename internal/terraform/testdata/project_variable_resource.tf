@@ -0,0 +1,12 @@
+resource "gitlab_project_variable" "my_group_my_project" {
+  for_each          = var.gitlab_project_variable["my-group/my-project"]
+  project           = gitlab_project.my_group_my_project.id
+  key               = each.value.key
+  value             = each.value.value != null ? each.value.value : var.gitlab_project_variable_secrets["my-group/my-project"][each.key]
+  variable_type     = each.value.variable_type
+  protected         = each.value.protected
+  masked            = each.value.masked
+  raw               = each.value.raw
+  environment_scope = each.value.environment_scope
+  description       = each.value.description
+}
@@ -0,0 +1,44 @@
+variable "gitlab_project_variable" {
+  description = "CI/CD variables for gitlab projects."
+  default = {
+    "my-group/my-project" = {
+      "GREETING:*" = {
+        key               = "GREETING"
+        value             = "hello $${name}"
+        variable_type     = "env_var"
+        protected         = false
+        masked            = false
+        raw               = true
+        environment_scope = "*"
+        description       = ""
+      }
+      "GREETING:staging" = {
+        key               = "GREETING"
+        value             = "hi \"there\""
+        variable_type     = "env_var"
+        protected         = false
+        masked            = false
+        raw               = false
+        environment_scope = "staging"
+        description       = ""
+      }
+      "KUBECONFIG:*" = {
+        key               = "KUBECONFIG"
+        value             = null
+        variable_type     = "file"
+        protected         = true
+        masked            = false
+        raw               = false
+        environment_scope = "*"
+        description       = ""
+      }
+    }
+  }
+}
+
+variable "gitlab_project_variable_secrets" {
+  description = "Values of masked or protected gitlab project variables, keyed like gitlab_project_variable."
+  type        = map(map(string))
+  sensitive   = true
+  default     = {}
+}
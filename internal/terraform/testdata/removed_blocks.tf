@@ -0,0 +1,15 @@
+removed {
+  from = gitlab_project.my_group_deleted
+
+  lifecycle {
+    destroy = false
+  }
+}
+
+removed {
+  from = gitlab_project_hook.my_group_deleted_0
+
+  lifecycle {
+    destroy = false
+  }
+}
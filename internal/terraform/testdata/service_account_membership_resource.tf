@@ -0,0 +1,6 @@
+resource "gitlab_group_membership" "sub_group_service_accounts" {
+  for_each     = var.gitlab_group_service_account_membership["my-group/sub-group"]
+  group_id     = gitlab_group.sub_group.id
+  user_id      = local.service_account_ids[each.key]
+  access_level = each.value
+}
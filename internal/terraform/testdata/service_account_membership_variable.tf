@@ -0,0 +1,8 @@
+variable "gitlab_group_service_account_membership" {
+  description = "Assign gitlab service accounts to groups."
+  default = {
+    "my-group/sub-group" = {
+      "service_account_group_10_ci" = "maintainer"
+    }
+  }
+}
@@ -0,0 +1,19 @@
+resource "gitlab_group_service_account" "my_group_service_account_group_10_ci" {
+  group    = gitlab_group.my_group.id
+  name     = "CI Bot"
+  username = "service_account_group_10_ci"
+  email    = "ci@example.com"
+}
+
+resource "gitlab_group_service_account" "my_group_service_account_group_10_renovate" {
+  group    = gitlab_group.my_group.id
+  name     = "Renovate"
+  username = "service_account_group_10_renovate"
+}
+
+locals {
+  service_account_ids = {
+    "service_account_group_10_ci"       = gitlab_group_service_account.my_group_service_account_group_10_ci.service_account_id
+    "service_account_group_10_renovate" = gitlab_group_service_account.my_group_service_account_group_10_renovate.service_account_id
+  }
+}
@@ -0,0 +1,9 @@
+resource "gitlab_tag_protection" "my_group_my_project_vwildcard" {
+  project             = gitlab_project.my_group_my_project.id
+  tag                 = "v*"
+  create_access_level = "maintainer"
+
+  allowed_to_create {
+    user_id = 42
+  }
+}
@@ -0,0 +1,214 @@
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ciVariable holds the fields shared by project and group CI/CD variables.
+type ciVariable struct {
+	Key              string
+	Value            string
+	VariableType     gl.VariableTypeValue
+	Protected        bool
+	Masked           bool
+	Hidden           bool
+	Raw              bool
+	EnvironmentScope string
+	Description      string
+}
+
+func fromGroupVariable(v *gl.GroupVariable) ciVariable {
+	return ciVariable{
+		Key:              v.Key,
+		Value:            v.Value,
+		VariableType:     v.VariableType,
+		Protected:        v.Protected,
+		Masked:           v.Masked,
+		Hidden:           v.Hidden,
+		Raw:              v.Raw,
+		EnvironmentScope: v.EnvironmentScope,
+		Description:      v.Description,
+	}
+}
+
+func fromProjectVariable(v *gl.ProjectVariable) ciVariable {
+	return ciVariable{
+		Key:              v.Key,
+		Value:            v.Value,
+		VariableType:     v.VariableType,
+		Protected:        v.Protected,
+		Masked:           v.Masked,
+		Hidden:           v.Hidden,
+		Raw:              v.Raw,
+		EnvironmentScope: v.EnvironmentScope,
+		Description:      v.Description,
+	}
+}
+
+// sensitive reports whether the value must not be written in clear text.
+func (v ciVariable) sensitive() bool {
+	return v.Masked || v.Protected || v.Hidden
+}
+
+func (v ciVariable) scope() string {
+	if v.EnvironmentScope == "" {
+		return "*"
+	}
+	return v.EnvironmentScope
+}
+
+// ciVariableKey returns the for_each key of a variable. The same key may
+// exist once per environment scope, so the scope is part of the map key.
+func ciVariableKey(key, scope string) string {
+	if scope == "" {
+		scope = "*"
+	}
+	return key + ":" + scope
+}
+
+func WriteGroupVariableVariable(groups []*gl.Group, groupVariables gitlab.GroupVariables, w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("variable \"gitlab_group_variable\" {\n")
+	b.WriteString("  description = \"CI/CD variables for gitlab groups.\"\n")
+	b.WriteString("  default = {\n")
+
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		variables := groupVariables[g.ID]
+		if len(variables) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "    \"%s\" = {\n", g.FullPath)
+		for _, v := range variables {
+			writeCIVariableEntry(&b, fromGroupVariable(v))
+		}
+		b.WriteString("    }\n")
+	}
+
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	b.WriteString("\n")
+	writeCIVariableSecrets(&b, "gitlab_group_variable_secrets", "Values of masked or protected gitlab group variables, keyed like gitlab_group_variable.")
+
+	_, err := w.Write(hclwrite.Format([]byte(b.String())))
+	return err
+}
+
+func WriteGroupVariableResource(group *gl.Group, w io.Writer) error {
+	name := normalizeToTerraformName(group.Path)
+	_, err := fmt.Fprintf(w, `resource "gitlab_group_variable" "%s" {
+  for_each          = var.gitlab_group_variable["%s"]
+  group             = gitlab_group.%s.id
+  key               = each.value.key
+  value             = each.value.value != null ? each.value.value : var.gitlab_group_variable_secrets["%s"][each.key]
+  variable_type     = each.value.variable_type
+  protected         = each.value.protected
+  masked            = each.value.masked
+  raw               = each.value.raw
+  environment_scope = each.value.environment_scope
+  description       = each.value.description
+}
+`, name, group.FullPath, name, group.FullPath)
+	return err
+}
+
+func WriteProjectVariableVariable(projects []*gl.Project, projectVariables gitlab.ProjectVariables, w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("variable \"gitlab_project_variable\" {\n")
+	b.WriteString("  description = \"CI/CD variables for gitlab projects.\"\n")
+	b.WriteString("  default = {\n")
+
+	for _, p := range projects {
+		if p == nil {
+			continue
+		}
+		variables := projectVariables[p.ID]
+		if len(variables) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "    \"%s\" = {\n", projectFullPath(p))
+		for _, v := range variables {
+			writeCIVariableEntry(&b, fromProjectVariable(v))
+		}
+		b.WriteString("    }\n")
+	}
+
+	b.WriteString("  }\n")
+	b.WriteString("}\n")
+	b.WriteString("\n")
+	writeCIVariableSecrets(&b, "gitlab_project_variable_secrets", "Values of masked or protected gitlab project variables, keyed like gitlab_project_variable.")
+
+	_, err := w.Write(hclwrite.Format([]byte(b.String())))
+	return err
+}
+
+func WriteProjectVariableResource(project *gl.Project, w io.Writer) error {
+	name := projectResourceName(project)
+	path := projectFullPath(project)
+	_, err := fmt.Fprintf(w, `resource "gitlab_project_variable" "%s" {
+  for_each          = var.gitlab_project_variable["%s"]
+  project           = gitlab_project.%s.id
+  key               = each.value.key
+  value             = each.value.value != null ? each.value.value : var.gitlab_project_variable_secrets["%s"][each.key]
+  variable_type     = each.value.variable_type
+  protected         = each.value.protected
+  masked            = each.value.masked
+  raw               = each.value.raw
+  environment_scope = each.value.environment_scope
+  description       = each.value.description
+}
+`, name, path, name, path)
+	return err
+}
+
+// writeCIVariableEntry writes one variable map entry. Masked, protected and
+// hidden values are written as null so the resource falls back to the
+// sensitive secrets variable.
+func writeCIVariableEntry(b *strings.Builder, v ciVariable) {
+	fmt.Fprintf(b, "      \"%s\" = {\n", ciVariableKey(v.Key, v.EnvironmentScope))
+	fmt.Fprintf(b, "        key = %s\n", quoteHCL(v.Key))
+	if v.sensitive() {
+		b.WriteString("        value = null\n")
+	} else {
+		fmt.Fprintf(b, "        value = %s\n", quoteHCL(v.Value))
+	}
+	fmt.Fprintf(b, "        variable_type = \"%s\"\n", variableType(v.VariableType))
+	fmt.Fprintf(b, "        protected = %t\n", v.Protected)
+	fmt.Fprintf(b, "        masked = %t\n", v.Masked || v.Hidden)
+	fmt.Fprintf(b, "        raw = %t\n", v.Raw)
+	fmt.Fprintf(b, "        environment_scope = %s\n", quoteHCL(v.scope()))
+	fmt.Fprintf(b, "        description = %s\n", quoteHCL(v.Description))
+	b.WriteString("      }\n")
+}
+
+func writeCIVariableSecrets(b *strings.Builder, name, description string) {
+	fmt.Fprintf(b, "variable \"%s\" {\n", name)
+	fmt.Fprintf(b, "  description = \"%s\"\n", description)
+	b.WriteString("  type = map(map(string))\n")
+	b.WriteString("  sensitive = true\n")
+	b.WriteString("  default = {}\n")
+	b.WriteString("}\n")
+}
+
+func variableType(t gl.VariableTypeValue) string {
+	if t == "" {
+		return string(gl.EnvVariableType)
+	}
+	return string(t)
+}
+
+// quoteHCL returns s as a quoted HCL string literal, escaping quotes and
+// template sequences.
+func quoteHCL(s string) string {
+	return string(hclwrite.TokensForValue(cty.StringVal(s)).Bytes())
+}
@@ -0,0 +1,106 @@
+package terraform
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/xMoelletschi/terraform-gitlab-drift/internal/gitlab"
+	gl "gitlab.com/gitlab-org/api/client-go"
+)
+
+func TestWriteGroupVariableVariable(t *testing.T) {
+	groups := []*gl.Group{
+		{ID: 10, Path: "my-group", FullPath: "my-group"},
+		{ID: 20, Path: "sub-group", FullPath: "my-group/sub-group"},
+	}
+	variables := gitlab.GroupVariables{
+		10: {
+			{Key: "API_URL", Value: "https://api.example.com", VariableType: gl.EnvVariableType, EnvironmentScope: "*"},
+			{Key: "DEPLOY_TOKEN", Value: "s3cr3t", VariableType: gl.EnvVariableType, Masked: true, EnvironmentScope: "production", Description: "Deploy token"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGroupVariableVariable(groups, variables, &buf); err != nil {
+		t.Fatalf("WriteGroupVariableVariable error: %v", err)
+	}
+
+	compareGolden(t, "group_variable_variable.tf", buf.String())
+}
+
+func TestWriteGroupVariableResource(t *testing.T) {
+	group := &gl.Group{ID: 10, Path: "my-group", FullPath: "my-group"}
+
+	var buf bytes.Buffer
+	if err := WriteGroupVariableResource(group, &buf); err != nil {
+		t.Fatalf("WriteGroupVariableResource error: %v", err)
+	}
+
+	compareGolden(t, "group_variable_resource.tf", buf.String())
+}
+
+func TestWriteProjectVariableVariable(t *testing.T) {
+	projects := []*gl.Project{
+		{
+			ID:                1,
+			Path:              "my-project",
+			Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+			PathWithNamespace: "my-group/my-project",
+		},
+	}
+	variables := gitlab.ProjectVariables{
+		1: {
+			{Key: "GREETING", Value: "hello ${name}", VariableType: gl.EnvVariableType, Raw: true, EnvironmentScope: "*"},
+			{Key: "GREETING", Value: "hi \"there\"", VariableType: gl.EnvVariableType, EnvironmentScope: "staging"},
+			{Key: "KUBECONFIG", Value: "apiVersion: v1", VariableType: gl.FileVariableType, Protected: true, EnvironmentScope: "*"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectVariableVariable(projects, variables, &buf); err != nil {
+		t.Fatalf("WriteProjectVariableVariable error: %v", err)
+	}
+
+	compareGolden(t, "project_variable_variable.tf", buf.String())
+}
+
+func TestWriteProjectVariableVariableNeverWritesSecrets(t *testing.T) {
+	projects := []*gl.Project{
+		{ID: 1, Path: "my-project", PathWithNamespace: "my-group/my-project"},
+	}
+	variables := gitlab.ProjectVariables{
+		1: {
+			{Key: "MASKED", Value: "masked-value", Masked: true},
+			{Key: "PROTECTED", Value: "protected-value", Protected: true},
+			{Key: "HIDDEN", Value: "hidden-value", Hidden: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectVariableVariable(projects, variables, &buf); err != nil {
+		t.Fatalf("WriteProjectVariableVariable error: %v", err)
+	}
+
+	for _, secret := range []string{"masked-value", "protected-value", "hidden-value"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("output contains secret value %q", secret)
+		}
+	}
+}
+
+func TestWriteProjectVariableResource(t *testing.T) {
+	project := &gl.Project{
+		ID:                1,
+		Path:              "my-project",
+		Namespace:         &gl.ProjectNamespace{FullPath: "my-group"},
+		PathWithNamespace: "my-group/my-project",
+	}
+
+	var buf bytes.Buffer
+	if err := WriteProjectVariableResource(project, &buf); err != nil {
+		t.Fatalf("WriteProjectVariableResource error: %v", err)
+	}
+
+	compareGolden(t, "project_variable_resource.tf", buf.String())
+}
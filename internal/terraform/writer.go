@@ -21,6 +21,7 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 	var errs []error
 
 	groupRefs := buildGroupRefMap(resources.Groups)
+	humanMembers, serviceAccountMembers := splitGroupMembers(resources.GroupMembers, resources.ServiceAccounts)
 
 	groupsByPath := make(map[string]*gl.Group)
 	for _, g := range resources.Groups {
@@ -55,7 +56,7 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 	// Write group_membership.tf with variable + user data source
 	if !skipSet.Has("memberships") {
 		if err := writeFile(filepath.Join(dir, "group_membership.tf"), func(w io.Writer) error {
-			if err := WriteGroupMembershipVariable(resources.Groups, resources.GroupMembers, w); err != nil {
+			if err := WriteGroupMembershipVariable(resources.Groups, humanMembers, w); err != nil {
 				return err
 			}
 			if _, err := w.Write([]byte("\n")); err != nil {
@@ -67,7 +68,7 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 		}
 	}
 
-	// Write project_membership.tf with variable + helpers only
+	// Write project_membership.tf with share group and user variables + helpers only
 	if !skipSet.Has("memberships") {
 		if err := writeFile(filepath.Join(dir, "project_membership.tf"), func(w io.Writer) error {
 			if err := WriteProjectMembershipVariable(resources.Projects, w); err != nil {
@@ -76,12 +77,57 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 			if _, err := w.Write([]byte("\n")); err != nil {
 				return err
 			}
-			return WriteProjectMembershipHelpers(w)
+			if err := WriteProjectMembershipHelpers(w); err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return err
+			}
+			if err := WriteProjectUserMembershipVariable(resources.Projects, resources.ProjectMembers, w); err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return err
+			}
+			return WriteProjectUserMembershipHelpers(w)
 		}); err != nil {
 			errs = append(errs, fmt.Errorf("project_membership.tf: %w", err))
 		}
 	}
 
+	// Write group_share_group.tf with variables + helpers only
+	if !skipSet.Has("memberships") {
+		if err := writeFile(filepath.Join(dir, "group_share_group.tf"), func(w io.Writer) error {
+			if err := WriteGroupShareGroupVariable(resources.Groups, resources.GroupShares, w); err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return err
+			}
+			return WriteGroupShareGroupHelpers(w)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("group_share_group.tf: %w", err))
+		}
+	}
+
+	// Write service_accounts.tf with service accounts and their memberships
+	if !skipSet.Has("service_accounts") {
+		if err := writeFile(filepath.Join(dir, "service_accounts.tf"), func(w io.Writer) error {
+			if err := WriteServiceAccounts(resources.Groups, resources.ServiceAccounts, w); err != nil {
+				return err
+			}
+			if skipSet.Has("memberships") {
+				return nil
+			}
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return err
+			}
+			return WriteServiceAccountMembershipVariable(resources.Groups, serviceAccountMembers, w)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("service_accounts.tf: %w", err))
+		}
+	}
+
 	// Write group_labels.tf with variable
 	if !skipSet.Has("labels") {
 		if err := writeFile(filepath.Join(dir, "group_labels.tf"), func(w io.Writer) error {
@@ -100,6 +146,24 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 		}
 	}
 
+	// Write group_variables.tf with variable
+	if !skipSet.Has("variables") {
+		if err := writeFile(filepath.Join(dir, "group_variables.tf"), func(w io.Writer) error {
+			return WriteGroupVariableVariable(resources.Groups, resources.GroupVariables, w)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("group_variables.tf: %w", err))
+		}
+	}
+
+	// Write project_variables.tf with variable
+	if !skipSet.Has("variables") {
+		if err := writeFile(filepath.Join(dir, "project_variables.tf"), func(w io.Writer) error {
+			return WriteProjectVariableVariable(resources.Projects, resources.ProjectVariables, w)
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("project_variables.tf: %w", err))
+		}
+	}
+
 	// Write pipeline_schedules.tf with individual resource blocks
 	if !skipSet.Has("schedules") {
 		if err := writeFile(filepath.Join(dir, "pipeline_schedules.tf"), func(w io.Writer) error {
@@ -168,14 +232,109 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 		}
 	}
 
-	// Write one file per namespace: group → group membership resource → projects → project share group resources
-	for ns := range allNamespaces {
-		trimmedNs := strings.TrimPrefix(ns, mainGroup+"/")
-		if trimmedNs == mainGroup {
-			trimmedNs = ns
+	// Write branch_protection.tf with individual resource blocks
+	if !skipSet.Has("branch_protection") {
+		if err := writeFile(filepath.Join(dir, "branch_protection.tf"), func(w io.Writer) error {
+			first := true
+			for _, p := range resources.Projects {
+				if p == nil {
+					continue
+				}
+				if branches := resources.ProtectedBranches[p.ID]; len(branches) > 0 {
+					if !first {
+						if _, err := w.Write([]byte("\n")); err != nil {
+							return err
+						}
+					}
+					if err := WriteBranchProtections(p, branches, w, groupRefs); err != nil {
+						return err
+					}
+					first = false
+				}
+				if tags := resources.ProtectedTags[p.ID]; len(tags) > 0 {
+					if !first {
+						if _, err := w.Write([]byte("\n")); err != nil {
+							return err
+						}
+					}
+					if err := WriteTagProtections(p, tags, w, groupRefs); err != nil {
+						return err
+					}
+					first = false
+				}
+			}
+			return nil
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("branch_protection.tf: %w", err))
+		}
+	}
+
+	// Write approval_rules.tf with approver data sources and individual resource blocks
+	if !skipSet.Has("approval_rules") {
+		if err := writeFile(filepath.Join(dir, "approval_rules.tf"), func(w io.Writer) error {
+			users, groups := approverNames(resources.Projects, resources.ApprovalRules)
+			first := true
+			if len(users) > 0 || len(groups) > 0 {
+				if err := WriteApproverDataSources(users, groups, w); err != nil {
+					return err
+				}
+				first = false
+			}
+			for _, p := range resources.Projects {
+				if p == nil {
+					continue
+				}
+				if rules := manageableApprovalRules(resources.ApprovalRules[p.ID]); len(rules) > 0 {
+					if !first {
+						if _, err := w.Write([]byte("\n")); err != nil {
+							return err
+						}
+					}
+					var branchNames map[string]string
+					if !skipSet.Has("branch_protection") {
+						branchNames = branchProtectionNames(p, resources.ProtectedBranches[p.ID])
+					}
+					if err := WriteProjectApprovalRules(p, rules, w, branchNames); err != nil {
+						return err
+					}
+					first = false
+				}
+			}
+			return nil
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("approval_rules.tf: %w", err))
 		}
+	}
 
-		filename := normalizeToTerraformName(trimmedNs) + ".tf"
+	// Write mr_approvals.tf with one resource per project with non-default settings
+	if !skipSet.Has("mr_approvals") {
+		if err := writeFile(filepath.Join(dir, "mr_approvals.tf"), func(w io.Writer) error {
+			first := true
+			for _, p := range resources.Projects {
+				if p == nil {
+					continue
+				}
+				if a := resources.MRApprovals[p.ID]; !isDefaultMRApprovals(a) {
+					if !first {
+						if _, err := w.Write([]byte("\n")); err != nil {
+							return err
+						}
+					}
+					if err := WriteProjectMRApprovals(p, a, w); err != nil {
+						return err
+					}
+					first = false
+				}
+			}
+			return nil
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("mr_approvals.tf: %w", err))
+		}
+	}
+
+	// Write one file per namespace: group → group membership resource → projects → project share group resources
+	for ns := range allNamespaces {
+		filename := NamespaceFileName(ns, mainGroup)
 		if err := writeFile(filepath.Join(dir, filename), func(w io.Writer) error {
 			written := false
 
@@ -187,12 +346,25 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 					if err := WriteGroupMembershipResource(group, w); err != nil {
 						return err
 					}
+					if len(serviceAccountMembers[group.ID]) > 0 {
+						if err := WriteServiceAccountMembershipResource(group, w); err != nil {
+							return err
+						}
+					}
+					if err := WriteGroupShareGroupResource(group, w); err != nil {
+						return err
+					}
 				}
 				if !skipSet.Has("labels") && len(resources.GroupLabels[group.ID]) > 0 {
 					if err := WriteGroupLabelResource(group, w); err != nil {
 						return err
 					}
 				}
+				if !skipSet.Has("variables") && len(resources.GroupVariables[group.ID]) > 0 {
+					if err := WriteGroupVariableResource(group, w); err != nil {
+						return err
+					}
+				}
 				written = true
 			}
 
@@ -210,12 +382,20 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 						if err := WriteProjectShareGroupResource(p, w); err != nil {
 							return err
 						}
+						if err := WriteProjectMembershipResource(p, w); err != nil {
+							return err
+						}
 					}
 					if !skipSet.Has("labels") && len(resources.ProjectLabels[p.ID]) > 0 {
 						if err := WriteProjectLabelResource(p, w); err != nil {
 							return err
 						}
 					}
+					if !skipSet.Has("variables") && len(resources.ProjectVariables[p.ID]) > 0 {
+						if err := WriteProjectVariableResource(p, w); err != nil {
+							return err
+						}
+					}
 				}
 			}
 
@@ -228,6 +408,34 @@ func WriteAll(resources *gitlab.Resources, dir string, mainGroup string, skipSet
 	return errors.Join(errs...)
 }
 
+// NamespaceFileName returns the name of the file WriteAll writes the group
+// and projects of namespace ns to, e.g. "team_a.tf" for "my-group/team-a".
+func NamespaceFileName(ns, mainGroup string) string {
+	trimmedNs := strings.TrimPrefix(ns, mainGroup+"/")
+	if trimmedNs == mainGroup {
+		trimmedNs = ns
+	}
+	return normalizeToTerraformName(trimmedNs) + ".tf"
+}
+
+// NamespaceFiles maps the per-namespace files WriteAll writes for resources
+// to the full path of their namespace. All other generated files hold
+// resources of several namespaces.
+func NamespaceFiles(resources *gitlab.Resources, mainGroup string) map[string]string {
+	files := make(map[string]string)
+	for _, g := range resources.Groups {
+		if g != nil && g.FullPath != "" {
+			files[NamespaceFileName(g.FullPath, mainGroup)] = g.FullPath
+		}
+	}
+	for _, p := range resources.Projects {
+		if p != nil && p.Namespace != nil && p.Namespace.FullPath != "" {
+			files[NamespaceFileName(p.Namespace.FullPath, mainGroup)] = p.Namespace.FullPath
+		}
+	}
+	return files
+}
+
 func writeFile(path string, writeFn func(io.Writer) error) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -116,6 +116,15 @@ func TestWriteAllSplitsProjectsByNamespace(t *testing.T) {
 				},
 			},
 		},
+		ProjectMembers: map[int64][]*gl.ProjectMember{
+			2: {
+				{
+					ID:          101,
+					Username:    "jdoe",
+					AccessLevel: gl.MaintainerPermissions,
+				},
+			},
+		},
 	}
 
 	dir := t.TempDir()
@@ -154,10 +163,32 @@ func TestWriteAllSplitsProjectsByNamespace(t *testing.T) {
 	if !strings.Contains(pmContent, `data "gitlab_group" "by_projects"`) {
 		t.Error("project_membership.tf should contain data source for group lookup")
 	}
+	if !strings.Contains(pmContent, `variable "gitlab_project_user_membership"`) {
+		t.Error("project_membership.tf should contain gitlab_project_user_membership variable")
+	}
+	if !strings.Contains(pmContent, `"jdoe" = "maintainer"`) {
+		t.Error("project_membership.tf should contain direct project member entry")
+	}
 	if strings.Contains(pmContent, `resource`) {
 		t.Error("project_membership.tf should NOT contain resource blocks")
 	}
 
+	// group_share_group.tf: variables + helpers only, no resources
+	data, err = os.ReadFile(filepath.Join(dir, "group_share_group.tf"))
+	if err != nil {
+		t.Fatalf("reading group_share_group.tf: %v", err)
+	}
+	gsContent := string(data)
+	if !strings.Contains(gsContent, `variable "gitlab_group_share_group"`) {
+		t.Error("group_share_group.tf should contain gitlab_group_share_group variable")
+	}
+	if !strings.Contains(gsContent, `data "gitlab_group" "by_groups"`) {
+		t.Error("group_share_group.tf should contain data source for group lookup")
+	}
+	if strings.Contains(gsContent, `resource`) {
+		t.Error("group_share_group.tf should NOT contain resource blocks")
+	}
+
 	// One file per namespace.
 	if _, err := os.Stat(filepath.Join(dir, "xdeveloperic.tf")); err != nil {
 		t.Fatalf("expected xdeveloperic.tf to exist: %v", err)
@@ -178,12 +209,18 @@ func TestWriteAllSplitsProjectsByNamespace(t *testing.T) {
 	if !strings.Contains(content, `gitlab_group_membership" "xdeveloperic"`) {
 		t.Error("xdeveloperic.tf should contain group membership resource")
 	}
+	if !strings.Contains(content, `gitlab_group_share_group" "xdeveloperic"`) {
+		t.Error("xdeveloperic.tf should contain group share group resource")
+	}
 	if !strings.Contains(content, `"xdeveloperic_project_a"`) {
 		t.Error("xdeveloperic.tf should contain xdeveloperic_project_a")
 	}
 	if !strings.Contains(content, `gitlab_project_share_group" "xdeveloperic_project_a"`) {
 		t.Error("xdeveloperic.tf should contain project share group resource")
 	}
+	if !strings.Contains(content, `gitlab_project_membership" "xdeveloperic_project_b"`) {
+		t.Error("xdeveloperic.tf should contain project membership resource")
+	}
 
 	// Verify ordering: group → membership → project → share group
 	groupIdx := strings.Index(content, `gitlab_group" "xdeveloperic"`)
@@ -216,3 +253,30 @@ func TestWriteAllSplitsProjectsByNamespace(t *testing.T) {
 		t.Error("sub_group.tf should contain sub_group_project_c")
 	}
 }
+
+func TestNamespaceFiles(t *testing.T) {
+	resources := &gitlab.Resources{
+		Groups: []*gl.Group{
+			{ID: 10, FullPath: "my-group"},
+			{ID: 20, FullPath: "my-group/team-a"},
+		},
+		Projects: []*gl.Project{
+			{ID: 1, Namespace: &gl.ProjectNamespace{FullPath: "my-group/team-a/backend"}},
+		},
+	}
+
+	got := NamespaceFiles(resources, "my-group")
+	want := map[string]string{
+		"my_group.tf":       "my-group",
+		"team_a.tf":         "my-group/team-a",
+		"team_a_backend.tf": "my-group/team-a/backend",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("NamespaceFiles() = %v, want %v", got, want)
+	}
+	for file, ns := range want {
+		if got[file] != ns {
+			t.Errorf("NamespaceFiles()[%q] = %q, want %q", file, got[file], ns)
+		}
+	}
+}